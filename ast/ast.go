@@ -3,6 +3,8 @@ package ast
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() Position // position of the first character of the node
+	End() Position // position just after the last character of the node
 }
 
 type Statement interface {
@@ -27,6 +29,25 @@ func (p Program) TokenLiteral() string {
 	}
 }
 
+// Pos returns the start of the first statement, the position of an empty program is not valid
+func (p Program) Pos() Position {
+	if len(p.Statements) == 0 {
+		return Position{}
+	}
+	return p.Statements[0].Pos()
+}
+
+// End returns the end of the last statement
+func (p Program) End() Position {
+	if len(p.Statements) == 0 {
+		return Position{}
+	}
+	return p.Statements[len(p.Statements)-1].End()
+}
+
+// String returns the program as canonical PL/B source: labels in column 1, verbs indented,
+// operands separated by commas and prepositions, and literals quoted with their quotes doubled.
+// Comments are not kept. Parsing the result gives the same tree, apart from the positions of its tokens.
 func (p Program) String() string {
 	var out string
 	for _, s := range p.Statements {
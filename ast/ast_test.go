@@ -0,0 +1,309 @@
+package ast
+
+import (
+	"PLB-Interpreter/tokens"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// nodes has an instance of every node type, a new node type has to be added here and to Walk and Rewrite
+var nodes = map[string]Node{
+	"Program":                &Program{},
+	"Identifier":             &Identifier{},
+	"StringLiteral":          &StringLiteral{},
+	"NumberLiteral":          &NumberLiteral{},
+	"FlagExpression":         &FlagExpression{},
+	"EventExpression":        &EventExpression{},
+	"PrefixExpression":       &PrefixExpression{},
+	"InfixExpression":        &InfixExpression{},
+	"GroupedExpression":      &GroupedExpression{},
+	"IndexExpression":        &IndexExpression{},
+	"DerefExpression":        &DerefExpression{},
+	"BadExpression":          &BadExpression{},
+	"LabelStatement":         &LabelStatement{},
+	"VerbStatement":          &VerbStatement{},
+	"BlockStatement":         &BlockStatement{},
+	"IfStatement":            &IfStatement{},
+	"ElseIfClause":           &ElseIfClause{},
+	"LoopStatement":          &LoopStatement{},
+	"LoopConditionStatement": &LoopConditionStatement{},
+	"ForStatement":           &ForStatement{},
+	"SwitchStatement":        &SwitchStatement{},
+	"CaseClause":             &CaseClause{},
+	"CallStatement":          &CallStatement{},
+	"ListItem":               &ListItem{},
+	"ListStatement":          &ListStatement{},
+	"IncludeStatement":       &IncludeStatement{},
+	"BadStatement":           &BadStatement{},
+	"DataDeclaration":        &DataDeclaration{},
+	"FileDeclaration":        &FileDeclaration{},
+	"PointerDeclaration":     &PointerDeclaration{},
+	"RoutineStatement":       &RoutineStatement{},
+}
+
+// nodeTypes returns the names of the types of this package that implement Node, found in its source
+func nodeTypes(t *testing.T) []string {
+	t.Helper()
+	pkgs, err := parser.ParseDir(token.NewFileSet(), ".", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range pkgs["ast"].Files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Name.Name != "TokenLiteral" {
+				continue
+			}
+			recv := fn.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			names = append(names, recv.(*ast.Ident).Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+var (
+	expressionType = reflect.TypeOf((*Expression)(nil)).Elem()
+	statementType  = reflect.TypeOf((*Statement)(nil)).Elem()
+)
+
+// child returns a new leaf node that fits a field or slice element of type typ, ok is false if typ holds no node
+func child(typ reflect.Type) (reflect.Value, bool) {
+	switch {
+	case typ == expressionType:
+		return reflect.ValueOf(Expression(&BadExpression{})), true
+	case typ == statementType:
+		return reflect.ValueOf(Statement(&BadStatement{})), true
+	case typ.Kind() == reflect.Pointer && typ.Implements(nodeType):
+		return reflect.New(typ.Elem()), true
+	}
+	return reflect.Value{}, false
+}
+
+// populate sets every node field of node to a new leaf and returns the leaves in field order
+func populate(node Node) []Node {
+	var children []Node
+	v := reflect.ValueOf(node).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Slice {
+			if c, ok := child(field.Type().Elem()); ok {
+				field.Set(reflect.Append(reflect.MakeSlice(field.Type(), 0, 1), c))
+				children = append(children, c.Interface().(Node))
+			}
+			continue
+		}
+		if c, ok := child(field.Type()); ok {
+			field.Set(c)
+			children = append(children, c.Interface().(Node))
+		}
+	}
+	return children
+}
+
+func TestNodes_Complete(t *testing.T) {
+	for _, name := range nodeTypes(t) {
+		if _, ok := nodes[name]; !ok {
+			t.Errorf("%s implements Node but is missing from the nodes of this test, add it here and to Walk and Rewrite", name)
+		}
+		if _, ok := nodeKinds[name]; !ok {
+			t.Errorf("%s implements Node but is missing from nodeKinds, it cannot be decoded from JSON", name)
+		}
+	}
+}
+
+func TestWalk_VisitsEveryChild(t *testing.T) {
+	for name, node := range nodes {
+		t.Run(name, func(t *testing.T) {
+			children := populate(node)
+
+			visited := childrenOf(node)
+			if len(visited) != len(children) {
+				t.Fatalf("visited %d children, want %d", len(visited), len(children))
+			}
+			for i := range children {
+				if visited[i] != children[i] {
+					t.Errorf("child %d: visited %T, want %T", i, visited[i], children[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRewrite_ReplacesEveryChild(t *testing.T) {
+	for name, node := range nodes {
+		t.Run(name, func(t *testing.T) {
+			children := populate(node)
+			old := map[Node]bool{}
+			for _, c := range children {
+				old[c] = true
+			}
+
+			replaced := 0
+			Rewrite(node, func(n Node) Node {
+				if !old[n] {
+					return n
+				}
+				replaced++
+				return reflect.New(reflect.TypeOf(n).Elem()).Interface().(Node)
+			})
+			if replaced != len(children) {
+				t.Fatalf("replaced %d children, want %d", replaced, len(children))
+			}
+			for _, c := range childrenOf(node) {
+				if old[c] {
+					t.Errorf("%T child was not replaced", c)
+				}
+			}
+		})
+	}
+}
+
+// childrenOf returns the direct children of node as visited by Inspect
+func childrenOf(node Node) []Node {
+	var children []Node
+	Inspect(node, func(n Node) bool {
+		if n != nil && n != node {
+			children = append(children, n)
+		}
+		return n == node
+	})
+	return children
+}
+
+func TestLabelAndBlocks(t *testing.T) {
+	for name, node := range nodes {
+		stmt, ok := node.(Statement)
+		if !ok {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			populate(node)
+
+			var want *Identifier
+			if field := reflect.ValueOf(node).Elem().FieldByName("Label"); field.IsValid() {
+				want = field.Interface().(*Identifier)
+			}
+			if got := Label(stmt); got != want {
+				t.Errorf("Label() = %v, want %v", got, want)
+			}
+
+			// the blocks are the bodies reached without passing another statement, or the block itself
+			var blocks []*BlockStatement
+			Inspect(node, func(n Node) bool {
+				if block, ok := n.(*BlockStatement); ok {
+					blocks = append(blocks, block)
+					return false
+				}
+				_, nested := n.(Statement)
+				return n == node || !nested
+			})
+			var got []*BlockStatement
+			Blocks(stmt, func(block *BlockStatement) {
+				got = append(got, block)
+			})
+			if !reflect.DeepEqual(got, blocks) {
+				t.Errorf("Blocks() calls fn with %d blocks, want %d", len(got), len(blocks))
+			}
+		})
+	}
+}
+
+func TestRewrite_RemovesNil(t *testing.T) {
+	ident := func(name string) *Identifier { return &Identifier{Token: tokens.Token{Literal: name}, Value: name} }
+	prog := &Program{Statements: []Statement{
+		&LabelStatement{Name: ident("START")},
+		&VerbStatement{Verb: "MOVE", Label: ident("X"), Operands: []Expression{ident("A"), ident("B")}, Seps: []tokens.Token{{Literal: ","}}},
+		&VerbStatement{Verb: "STOP"},
+	}}
+
+	Rewrite(prog, func(n Node) Node {
+		switch n := n.(type) {
+		case *LabelStatement:
+			return nil
+		case *Identifier:
+			if n.Value == "X" {
+				return nil
+			}
+			if n.Value == "A" {
+				return &StringLiteral{Value: "A"}
+			}
+		}
+		return n
+	})
+
+	if got, want := prog.String(), " MOVE \"A\",B\n STOP\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRewrite_PanicsOnMisfit(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("got no panic replacing a label by a literal")
+		}
+	}()
+	Rewrite(&LabelStatement{Name: &Identifier{Value: "X"}}, func(n Node) Node {
+		if _, ok := n.(*Identifier); ok {
+			return &StringLiteral{Value: "X"}
+		}
+		return n
+	})
+}
+
+func TestJSON_RoundTripsEveryNode(t *testing.T) {
+	for name, node := range nodes {
+		t.Run(name, func(t *testing.T) {
+			populate(node)
+			data, err := (&jsonEncoder{lines: map[string]map[int]string{}}).node(node)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := (&jsonDecoder{}).node(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, node) {
+				t.Errorf("got %#v from %s, want %#v", got, data, node)
+			}
+		})
+	}
+}
+
+func TestJSON_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{"version": 2, "program": {"node": "Program"}}`, "unsupported AST JSON version 2"},
+		{`{"version": 1, "program": {"node": "Frobnicate"}}`, `unknown node kind "Frobnicate"`},
+		{`{"version": 1, "program": {"node": "Identifier"}}`, "want a Program"},
+		{`{"version": 1, "program": {"node": "Program", "statements": [{"node": "Identifier"}]}}`, "cannot be a ast.Statement"},
+		{`{"version": 1, "program": {"node": "Program", "statements": [{"node": "ListStatement", "items": [{"node": "ListItem", "kind": "frobnicate"}]}]}}`, `unknown list item kind "frobnicate"`},
+		{`{"version": 1, "program": {"node": "Program", "statements": [{"node": "ListStatement", "items": [{"node": "ListItem", "kind": "literal"}]}]}}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := DecodeJSON(strings.NewReader(tt.input))
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("got %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
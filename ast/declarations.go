@@ -0,0 +1,175 @@
+package ast
+
+import (
+	"PLB-Interpreter/tokens"
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// DataDeclaration is a data definition, e.g. VARONE DIM 10 or VARTWO INIT "SECOND VAR"
+type DataDeclaration struct {
+	Token    tokens.Token // the token of the defining verb
+	Name     *Identifier  // the declared data label, nil if the label is on a line of its own
+	Kind     string       // upper-cased defining verb, e.g. DIM, FORM or INIT
+	Operands []Expression // size or initial values
+}
+
+func (dd *DataDeclaration) statementNode()       {}
+func (dd *DataDeclaration) TokenLiteral() string { return dd.Token.Literal }
+func (dd *DataDeclaration) Pos() Position        { return labelledPos(dd.Name, dd.Token) }
+func (dd *DataDeclaration) End() Position        { return lastEnd(dd.Token, lastOf(dd.Operands)) }
+func (dd *DataDeclaration) String() string {
+	var out bytes.Buffer
+	writeLabel(&out, dd.Name)
+	out.WriteString(" " + dd.Kind)
+	for i, op := range dd.Operands {
+		if i == 0 {
+			out.WriteString(" ")
+		} else {
+			out.WriteString(",")
+		}
+		out.WriteString(op.String())
+	}
+	out.WriteString("\n")
+	return out.String()
+}
+
+// RoutineStatement is a ROUTINE or LROUTINE entry point with its formal parameters, e.g. SUB ROUTINE A,B.
+// The body holds every statement up to the next routine or the end of the file, including every RETURN
+// and the labelled statements between them. A routine that can run past its last statement is reported by lint.
+// Data declared in the body of an LROUTINE is local to it.
+type RoutineStatement struct {
+	Token  tokens.Token  // the ROUTINE or LROUTINE token
+	Name   *Identifier   // the label naming the routine
+	Kind   string        // ROUTINE or LROUTINE
+	Params []*Identifier // formal parameters in declaration order
+	Body   *BlockStatement
+}
+
+func (rs *RoutineStatement) statementNode()       {}
+func (rs *RoutineStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *RoutineStatement) Pos() Position        { return labelledPos(rs.Name, rs.Token) }
+
+// End returns the end of the last statement of the routine, or of its parameters if the body is empty
+func (rs *RoutineStatement) End() Position {
+	var body Node
+	if rs.Body != nil && len(rs.Body.Statements) > 0 {
+		body = rs.Body
+	}
+	return lastEnd(rs.Token, lastOf(rs.Params), body)
+}
+func (rs *RoutineStatement) String() string {
+	var out bytes.Buffer
+	writeLabel(&out, rs.Name)
+	out.WriteString(" " + rs.Kind)
+	for i, param := range rs.Params {
+		if i == 0 {
+			out.WriteString(" ")
+		} else {
+			out.WriteString(",")
+		}
+		out.WriteString(param.String())
+	}
+	out.WriteString("\n")
+	out.WriteString(rs.Body.String())
+	return out.String()
+}
+
+// IsLocal returns true if data declared in the body is local to the routine
+func (rs *RoutineStatement) IsLocal() bool {
+	return rs.Kind == "LROUTINE"
+}
+
+// FileDeclaration is a file variable definition with its attributes, e.g. CUSTOMERS IFILE KEYLEN=10,FIXED=128,DUP.
+// Attributes that are not given keep their default, which is the zero value of the field.
+type FileDeclaration struct {
+	Token        tokens.Token // the token of the defining verb
+	Name         *Identifier  // the declared file label, nil if the label is on a line of its own
+	Kind         string       // FILE, IFILE, AFILE or PFILE
+	Fixed        bool         // FIXED=, every record has exactly RecordLength bytes
+	RecordLength int          // maximum record length given by FIXED= or VAR=, 0 if not given
+	KeyLength    int          // KEYLEN= of an IFILE or AFILE, 0 if not given
+	Dup          bool         // DUP, an IFILE or AFILE allows duplicate keys; NODUP is the default
+	Binary       bool         // BINARY, records are not terminated by a line end; TEXT is the default
+	Compressed   bool         // COMP, records are space compressed; UNCOMP is the default
+	EndToken     tokens.Token // the last token of the declaration
+}
+
+func (fd *FileDeclaration) statementNode()       {}
+func (fd *FileDeclaration) TokenLiteral() string { return fd.Token.Literal }
+func (fd *FileDeclaration) Pos() Position        { return labelledPos(fd.Name, fd.Token) }
+func (fd *FileDeclaration) End() Position        { return endOr(fd.EndToken, fd.Token) }
+func (fd *FileDeclaration) String() string {
+	var out bytes.Buffer
+	writeLabel(&out, fd.Name)
+	out.WriteString(" " + fd.Kind)
+
+	var attrs []string
+	if fd.RecordLength > 0 {
+		if fd.Fixed {
+			attrs = append(attrs, "FIXED="+strconv.Itoa(fd.RecordLength))
+		} else {
+			attrs = append(attrs, "VAR="+strconv.Itoa(fd.RecordLength))
+		}
+	}
+	if fd.KeyLength > 0 {
+		attrs = append(attrs, "KEYLEN="+strconv.Itoa(fd.KeyLength))
+	}
+	if fd.Dup {
+		attrs = append(attrs, "DUP")
+	}
+	if fd.Binary {
+		attrs = append(attrs, "BINARY")
+	}
+	if fd.Compressed {
+		attrs = append(attrs, "COMP")
+	}
+	if len(attrs) > 0 {
+		out.WriteString(" " + strings.Join(attrs, ","))
+	}
+	out.WriteString("\n")
+	return out.String()
+}
+
+// IsIndexed returns true if the file is accessed by key, i.e. an IFILE or AFILE
+func (fd *FileDeclaration) IsIndexed() bool {
+	return fd.Kind == "IFILE" || fd.Kind == "AFILE"
+}
+
+// PointerDeclaration is a pointer variable definition, e.g. PTR DIM ^ or ANY VAR @.
+// A pointer is null until MOVEADR or MOVEPTR makes it refer to a variable.
+type PointerDeclaration struct {
+	Token    tokens.Token // the token of the defining verb
+	Name     *Identifier  // the declared pointer label, nil if the label is on a line of its own
+	Kind     string       // DIM or FORM for a pointer to that kind of variable, VAR for a pointer to any variable
+	EndToken tokens.Token // the ^ or @ token
+}
+
+func (pd *PointerDeclaration) statementNode()       {}
+func (pd *PointerDeclaration) TokenLiteral() string { return pd.Token.Literal }
+func (pd *PointerDeclaration) Pos() Position        { return labelledPos(pd.Name, pd.Token) }
+func (pd *PointerDeclaration) End() Position        { return endOr(pd.EndToken, pd.Token) }
+func (pd *PointerDeclaration) String() string {
+	var out bytes.Buffer
+	writeLabel(&out, pd.Name)
+	out.WriteString(" " + pd.Kind + " " + pd.Marker() + "\n")
+	return out.String()
+}
+
+// Marker returns the symbol declaring the pointer, @ for VAR and ^ otherwise
+func (pd *PointerDeclaration) Marker() string {
+	if pd.Kind == "VAR" {
+		return "@"
+	}
+	return "^"
+}
+
+// DeclaredName returns the name of a data, file or pointer declaration, or label if the declaration has none,
+// i.e. the label on the line before it that names it
+func DeclaredName(name, label *Identifier) *Identifier {
+	if name != nil {
+		return name
+	}
+	return label
+}
@@ -0,0 +1,192 @@
+package ast
+
+import (
+	"PLB-Interpreter/tokens"
+	"bytes"
+	"strings"
+)
+
+// Identifier is a reference to a label, e.g. a variable or an execution label
+type Identifier struct {
+	Token tokens.Token // the IDENT token
+	Value string
+}
+
+func (i *Identifier) expressionNode()      {}
+func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() Position        { return tokenPos(i.Token) }
+func (i *Identifier) End() Position        { return tokenEnd(i.Token) }
+func (i *Identifier) String() string       { return i.Value }
+
+// StringLiteral is a quoted literal, e.g. "HELLO"
+type StringLiteral struct {
+	Token tokens.Token // the LITERAL token
+	Value string       // the unescaped value without the surrounding quotes
+}
+
+func (sl *StringLiteral) expressionNode()      {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() Position        { return tokenPos(sl.Token) }
+func (sl *StringLiteral) End() Position        { return tokenEnd(sl.Token) }
+func (sl *StringLiteral) String() string       { return Quote(sl.Value) }
+
+// Quote returns value as a PL/B literal, a quote within the value is doubled, e.g. "SAY ""HI"""
+func Quote(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
+
+// NumberLiteral is a decimal, octal or hexadecimal constant, a DIGITS.DIGITS constant or a quoted numeric literal
+type NumberLiteral struct {
+	Token tokens.Token // the DNUM, ONUM, XNUM, NUMERICCONSTANT or NUMERICLITERAL token
+	Value string
+}
+
+func (nl *NumberLiteral) expressionNode()      {}
+func (nl *NumberLiteral) TokenLiteral() string { return nl.Token.Literal }
+func (nl *NumberLiteral) Pos() Position        { return tokenPos(nl.Token) }
+func (nl *NumberLiteral) End() Position        { return tokenEnd(nl.Token) }
+func (nl *NumberLiteral) String() string {
+	if nl.Token.Type == tokens.NUMERICLITERAL {
+		return `"` + nl.Value + `"`
+	}
+	return nl.Value
+}
+
+// FlagExpression is a reference to one of the condition flags set by the previous operation,
+// e.g. the EQUAL in "GOTO DONE IF EQUAL"
+type FlagExpression struct {
+	Token tokens.Token // the IDENT token of the flag
+	Flag  string       // upper-cased flag name, see ConditionFlags
+}
+
+func (fe *FlagExpression) expressionNode()      {}
+func (fe *FlagExpression) TokenLiteral() string { return fe.Token.Literal }
+func (fe *FlagExpression) Pos() Position        { return tokenPos(fe.Token) }
+func (fe *FlagExpression) End() Position        { return tokenEnd(fe.Token) }
+func (fe *FlagExpression) String() string       { return fe.Flag }
+
+// EventExpression is a runtime event a TRAP waits for, e.g. the RANGE in "TRAP BADPTR IF RANGE"
+type EventExpression struct {
+	Token tokens.Token // the IDENT token of the event
+	Event string       // upper-cased event name, see TrapEvents
+}
+
+func (ee *EventExpression) expressionNode()      {}
+func (ee *EventExpression) TokenLiteral() string { return ee.Token.Literal }
+func (ee *EventExpression) Pos() Position        { return tokenPos(ee.Token) }
+func (ee *EventExpression) End() Position        { return tokenEnd(ee.Token) }
+func (ee *EventExpression) String() string       { return ee.Event }
+
+// TrapEvents are the runtime events that can be trapped by TRAP
+var TrapEvents = map[string]bool{
+	"IO":     true, // a file operation failed
+	"RANGE":  true, // a reference outside of the data area, e.g. through a null pointer
+	"FORMAT": true, // a value that does not fit the format of its destination
+	"PARITY": true, // a damaged record
+	"CFAIL":  true, // a failed CHAIN
+	"INT":    true, // an interrupt by the operator
+}
+
+// ConditionFlags are the flags that can be tested by a conditional statement suffix
+var ConditionFlags = map[string]bool{
+	"OVER":  true,
+	"LESS":  true,
+	"EQUAL": true,
+	"ZERO":  true,
+	"EOS":   true,
+}
+
+// PrefixExpression is an operator applied to a single operand, e.g. NOT EQUAL or -1
+type PrefixExpression struct {
+	Token    tokens.Token // the operator token
+	Operator string
+	Right    Expression
+}
+
+func (pe *PrefixExpression) expressionNode()      {}
+func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() Position        { return tokenPos(pe.Token) }
+func (pe *PrefixExpression) End() Position        { return lastEnd(pe.Token, pe.Right) }
+func (pe *PrefixExpression) String() string {
+	if pe.Operator == "NOT" {
+		return pe.Operator + " " + pe.Right.String()
+	}
+	return pe.Operator + pe.Right.String()
+}
+
+// InfixExpression is a binary operation, e.g. VARTHREE <= 25
+type InfixExpression struct {
+	Token    tokens.Token // the operator token
+	Left     Expression
+	Operator string
+	Right    Expression
+}
+
+func (ie *InfixExpression) expressionNode()      {}
+func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() Position        { return ie.Left.Pos() }
+func (ie *InfixExpression) End() Position        { return lastEnd(ie.Token, ie.Right) }
+func (ie *InfixExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString(ie.Left.String())
+	out.WriteString(" " + ie.Operator + " ")
+	out.WriteString(ie.Right.String())
+	return out.String()
+}
+
+// GroupedExpression is an expression enclosed in parentheses.
+// It is kept as its own node so the source form can be reproduced.
+type GroupedExpression struct {
+	Token      tokens.Token // the ( token
+	Expression Expression
+	EndToken   tokens.Token // the ) token
+}
+
+func (ge *GroupedExpression) expressionNode()      {}
+func (ge *GroupedExpression) TokenLiteral() string { return ge.Token.Literal }
+func (ge *GroupedExpression) Pos() Position        { return tokenPos(ge.Token) }
+func (ge *GroupedExpression) End() Position        { return endOr(ge.EndToken, ge.Token, ge.Expression) }
+func (ge *GroupedExpression) String() string       { return "(" + ge.Expression.String() + ")" }
+
+// IndexExpression is an array reference, e.g. ARR(3)
+type IndexExpression struct {
+	Token    tokens.Token // the ( token
+	Left     Expression
+	Index    Expression
+	EndToken tokens.Token // the ) token
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() Position        { return ie.Left.Pos() }
+func (ie *IndexExpression) End() Position        { return endOr(ie.EndToken, ie.Token, ie.Index) }
+func (ie *IndexExpression) String() string {
+	return ie.Left.String() + "(" + ie.Index.String() + ")"
+}
+
+// DerefExpression is an operand referring to the variable a pointer points to.
+// PL/B has no dereference operator, the parser wraps every pointer used where a plain variable is expected.
+type DerefExpression struct {
+	Token   tokens.Token // the IDENT token of the pointer
+	Pointer *Identifier
+}
+
+func (de *DerefExpression) expressionNode()      {}
+func (de *DerefExpression) TokenLiteral() string { return de.Token.Literal }
+func (de *DerefExpression) Pos() Position        { return de.Pointer.Pos() }
+func (de *DerefExpression) End() Position        { return de.Pointer.End() }
+func (de *DerefExpression) String() string       { return de.Pointer.String() }
+
+// BadExpression stands in for an operand or condition that could not be parsed,
+// it spans the skipped tokens so the enclosing statement can be kept
+type BadExpression struct {
+	Token    tokens.Token // the first skipped token
+	EndToken tokens.Token // the last skipped token
+	Text     string       // the skipped source text
+}
+
+func (be *BadExpression) expressionNode()      {}
+func (be *BadExpression) TokenLiteral() string { return be.Token.Literal }
+func (be *BadExpression) Pos() Position        { return tokenPos(be.Token) }
+func (be *BadExpression) End() Position        { return tokenEnd(be.EndToken) }
+func (be *BadExpression) String() string       { return be.Text }
@@ -0,0 +1,273 @@
+package ast
+
+import (
+	"PLB-Interpreter/tokens"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+)
+
+// JSONVersion is the version of the JSON encoding written by EncodeJSON.
+// It changes whenever a node kind or field is renamed or removed, DecodeJSON only reads this version.
+const JSONVersion = 1
+
+// The JSON encoding of a program is a document
+//
+//	{"version": 1, "program": {...}, "lines": {"file": {"1": "source line", ...}}}
+//
+// Every node is an object with its "node" kind, the Go type name e.g. VerbStatement, its "pos" and "end",
+// and its fields named like the Go fields in lower camel case, e.g. "endToken".
+// Missing nodes, tokens and lists are null. A token is an object with its "type", "literal",
+// "file", "line" and "col"; the source line it was read from is stored once in "lines".
+// Positions are derived from the tokens and are not read back by DecodeJSON.
+type jsonDocument struct {
+	Version int                       `json:"version"`
+	Program json.RawMessage           `json:"program"`
+	Lines   map[string]map[int]string `json:"lines"`
+}
+
+type jsonToken struct {
+	Type    tokens.TokenType `json:"type"`
+	Literal string           `json:"literal"`
+	File    string           `json:"file"`
+	Line    int              `json:"line"`
+	Col     int              `json:"col"`
+	LineTxt *string          `json:"lineTxt,omitempty"` // only if the line is not in the lines of the document
+}
+
+// nodeKinds are the node types by kind, see jsonDocument
+var nodeKinds = map[string]reflect.Type{}
+
+func init() {
+	for _, node := range []Node{
+		&Program{},
+		&Identifier{}, &StringLiteral{}, &NumberLiteral{}, &FlagExpression{}, &EventExpression{},
+		&PrefixExpression{}, &InfixExpression{}, &GroupedExpression{}, &IndexExpression{},
+		&DerefExpression{}, &BadExpression{},
+		&LabelStatement{}, &VerbStatement{}, &BlockStatement{}, &IfStatement{}, &ElseIfClause{},
+		&LoopStatement{}, &LoopConditionStatement{}, &ForStatement{}, &SwitchStatement{}, &CaseClause{},
+		&CallStatement{}, &ListItem{}, &ListStatement{}, &IncludeStatement{}, &BadStatement{},
+		&DataDeclaration{}, &FileDeclaration{}, &PointerDeclaration{}, &RoutineStatement{},
+	} {
+		typ := reflect.TypeOf(node).Elem()
+		nodeKinds[typ.Name()] = typ
+	}
+}
+
+var (
+	nodeType  = reflect.TypeOf((*Node)(nil)).Elem()
+	tokenType = reflect.TypeOf(tokens.Token{})
+)
+
+// EncodeJSON writes prog to w as an indented JSON document
+func EncodeJSON(w io.Writer, prog *Program) error {
+	e := &jsonEncoder{lines: map[string]map[int]string{}}
+	program, err := e.node(prog)
+	if err != nil {
+		return err
+	}
+	doc, err := json.Marshal(jsonDocument{Version: JSONVersion, Program: program, Lines: e.lines})
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, doc, "", "  "); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+	_, err = out.WriteTo(w)
+	return err
+}
+
+// DecodeJSON reads a program written by EncodeJSON
+func DecodeJSON(r io.Reader) (*Program, error) {
+	var doc jsonDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != JSONVersion {
+		return nil, fmt.Errorf("unsupported AST JSON version %d, want %d", doc.Version, JSONVersion)
+	}
+	d := &jsonDecoder{lines: doc.Lines}
+	node, err := d.node(doc.Program)
+	if err != nil {
+		return nil, err
+	}
+	prog, ok := node.(*Program)
+	if !ok {
+		return nil, fmt.Errorf("the program is a %T, want a Program", node)
+	}
+	return prog, nil
+}
+
+type jsonEncoder struct {
+	lines map[string]map[int]string // source lines by file and line number
+}
+
+// node encodes a node as an object with its kind and positions first and then its fields in declaration order
+func (e *jsonEncoder) node(node Node) (json.RawMessage, error) {
+	v := reflect.ValueOf(node).Elem()
+	var out bytes.Buffer
+	fmt.Fprintf(&out, `{"node":%q`, v.Type().Name())
+	for _, pos := range []struct {
+		name string
+		pos  Position
+	}{{"pos", node.Pos()}, {"end", node.End()}} {
+		data, err := json.Marshal(pos.pos)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&out, `,%q:%s`, pos.name, data)
+	}
+	for i := 0; i < v.NumField(); i++ {
+		data, err := e.value(v.Field(i))
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&out, `,%q:%s`, fieldName(v.Type().Field(i).Name), data)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+func (e *jsonEncoder) value(v reflect.Value) (json.RawMessage, error) {
+	switch {
+	case v.Type() == tokenType:
+		return e.token(v.Interface().(tokens.Token))
+	case v.Type().Implements(nodeType):
+		if v.IsNil() {
+			return json.RawMessage("null"), nil
+		}
+		return e.node(v.Interface().(Node))
+	case v.Kind() == reflect.Slice:
+		if v.IsNil() {
+			return json.RawMessage("null"), nil
+		}
+		elems := make([]json.RawMessage, v.Len())
+		for i := range elems {
+			elem, err := e.value(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = elem
+		}
+		return json.Marshal(elems)
+	}
+	return json.Marshal(v.Interface())
+}
+
+// token encodes tok, keeping its source line in the lines of the document
+func (e *jsonEncoder) token(tok tokens.Token) (json.RawMessage, error) {
+	if tok == (tokens.Token{}) {
+		return json.RawMessage("null"), nil
+	}
+	jt := jsonToken{Type: tok.Type, Literal: tok.Literal, File: tok.FileName, Line: tok.Line, Col: tok.Col}
+	if tok.Line > 0 {
+		if e.lines[tok.FileName] == nil {
+			e.lines[tok.FileName] = map[int]string{}
+		}
+		if line, ok := e.lines[tok.FileName][tok.Line]; !ok {
+			e.lines[tok.FileName][tok.Line] = tok.LineTxt
+		} else if line != tok.LineTxt {
+			jt.LineTxt = &tok.LineTxt
+		}
+	} else if tok.LineTxt != "" {
+		jt.LineTxt = &tok.LineTxt
+	}
+	return json.Marshal(jt)
+}
+
+type jsonDecoder struct {
+	lines map[string]map[int]string
+}
+
+func (d *jsonDecoder) node(data json.RawMessage) (Node, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var kind string
+	if err := json.Unmarshal(fields["node"], &kind); err != nil {
+		return nil, fmt.Errorf("node without a kind: %s", data)
+	}
+	typ, ok := nodeKinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown node kind %q", kind)
+	}
+
+	v := reflect.New(typ)
+	for i := 0; i < typ.NumField(); i++ {
+		name := fieldName(typ.Field(i).Name)
+		value, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if err := d.value(value, v.Elem().Field(i)); err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", kind, name, err)
+		}
+	}
+	return v.Interface().(Node), nil
+}
+
+// value decodes data into v, which has to be settable
+func (d *jsonDecoder) value(data json.RawMessage, v reflect.Value) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+	switch {
+	case v.Type() == tokenType:
+		tok, err := d.token(data)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(tok))
+		return nil
+	case v.Type().Implements(nodeType):
+		node, err := d.node(data)
+		if err != nil {
+			return err
+		}
+		if !reflect.TypeOf(node).AssignableTo(v.Type()) {
+			return fmt.Errorf("a %T cannot be a %s", node, v.Type())
+		}
+		v.Set(reflect.ValueOf(node))
+		return nil
+	case v.Kind() == reflect.Slice:
+		var elems []json.RawMessage
+		if err := json.Unmarshal(data, &elems); err != nil {
+			return err
+		}
+		v.Set(reflect.MakeSlice(v.Type(), len(elems), len(elems)))
+		for i, elem := range elems {
+			if err := d.value(elem, v.Index(i)); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		return nil
+	}
+	return json.Unmarshal(data, v.Addr().Interface())
+}
+
+func (d *jsonDecoder) token(data json.RawMessage) (tokens.Token, error) {
+	var jt jsonToken
+	if err := json.Unmarshal(data, &jt); err != nil {
+		return tokens.Token{}, err
+	}
+	tok := tokens.Token{Type: jt.Type, Literal: jt.Literal, FileName: jt.File, Line: jt.Line, Col: jt.Col}
+	if jt.LineTxt != nil {
+		tok.LineTxt = *jt.LineTxt
+	} else if tok.Line > 0 {
+		tok.LineTxt = d.lines[tok.FileName][tok.Line]
+	}
+	return tok, nil
+}
+
+// fieldName returns the JSON name of a Go field, e.g. endToken for EndToken
+func fieldName(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
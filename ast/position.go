@@ -0,0 +1,103 @@
+package ast
+
+import (
+	"PLB-Interpreter/tokens"
+	"fmt"
+	"strings"
+)
+
+// Position is a location in a source file, Line and Col are 1-based like those of tokens.Token
+type Position struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Col  int    `json:"col"`
+}
+
+// IsValid returns true if the position refers to a line, positions of missing tokens are not valid
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s %d:%d", p.File, p.Line, p.Col)
+}
+
+// Before returns true if p is on an earlier line than q, or on the same line in an earlier column.
+// The file names are not compared.
+func (p Position) Before(q Position) bool {
+	return p.Line < q.Line || p.Line == q.Line && p.Col < q.Col
+}
+
+// tokenPos returns the position of the first character of tok
+func tokenPos(tok tokens.Token) Position {
+	return Position{File: tok.FileName, Line: tok.Line, Col: tok.Col}
+}
+
+// tokenEnd returns the position just after the last character of tok.
+// The literal of a quoted token has its quotes removed and doubled quotes unescaped,
+// so its width is measured in the source line instead.
+func tokenEnd(tok tokens.Token) Position {
+	end := tokenPos(tok)
+	if tok.Line == 0 {
+		return end
+	}
+	line := strings.TrimRight(tok.LineTxt, "\r\n")
+	if start := tok.Col - 1; start >= 0 && start < len(line) && line[start] == '"' {
+		end.Col += quotedWidth(line[start:])
+	} else {
+		end.Col += len(tok.Literal)
+	}
+	return end
+}
+
+// quotedWidth returns the width of the quoted literal text starts with, including both quotes.
+// An unterminated literal extends to the end of the line.
+func quotedWidth(text string) int {
+	for i := 1; i < len(text); i++ {
+		if text[i] != '"' {
+			continue
+		}
+		if i+1 < len(text) && text[i+1] == '"' {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(text)
+}
+
+// labelledPos returns the position of a statement starting with an optional label in front of its verb
+func labelledPos(label *Identifier, verb tokens.Token) Position {
+	if label != nil {
+		return label.Pos()
+	}
+	return tokenPos(verb)
+}
+
+// lastEnd returns the latest end of tok and of the nodes that are present
+func lastEnd(tok tokens.Token, nodes ...Node) Position {
+	end := tokenEnd(tok)
+	for _, node := range nodes {
+		if isNil(node) {
+			continue
+		}
+		if e := node.End(); e.IsValid() && end.Before(e) {
+			end = e
+		}
+	}
+	return end
+}
+
+// endOr returns the end of tok if it was found, e.g. the ENDIF of an IF, otherwise the end of the last node present
+func endOr(tok, first tokens.Token, nodes ...Node) Position {
+	if tok.Line > 0 {
+		return tokenEnd(tok)
+	}
+	return lastEnd(first, nodes...)
+}
+
+// lastOf returns the last node of list, or nil if it is empty
+func lastOf[T Node](list []T) Node {
+	if len(list) == 0 {
+		return nil
+	}
+	return list[len(list)-1]
+}
@@ -0,0 +1,182 @@
+package ast
+
+import "fmt"
+
+// RewriteFunc returns the replacement of node, which may be node itself.
+// Returning nil removes the node from a list, e.g. the statements of a block, or clears the field holding it.
+// Separators kept beside a list, like VerbStatement.Seps, are not adjusted.
+type RewriteFunc func(node Node) Node
+
+// Rewrite transforms the tree rooted at node bottom-up: the children of a node are rewritten first,
+// then f is called with the node and its result takes the place of the node in its parent.
+// Rewrite returns the replacement of node itself.
+//
+// A replacement has to fit the place of the node it replaces, e.g. a Statement for a statement
+// or an *Identifier for a label; Rewrite panics otherwise.
+func Rewrite(node Node, f RewriteFunc) Node {
+	switch n := node.(type) {
+	case Program:
+		n.Statements = rewriteList(n.Statements, f)
+		return f(n)
+	case *Program:
+		n.Statements = rewriteList(n.Statements, f)
+
+	// expressions
+	case *Identifier, *StringLiteral, *NumberLiteral, *FlagExpression, *EventExpression, *BadExpression:
+		// leaves
+	case *PrefixExpression:
+		n.Right = rewriteExpression(n.Right, f)
+	case *InfixExpression:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Right = rewriteExpression(n.Right, f)
+	case *GroupedExpression:
+		n.Expression = rewriteExpression(n.Expression, f)
+	case *IndexExpression:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Index = rewriteExpression(n.Index, f)
+	case *DerefExpression:
+		n.Pointer = rewriteIdentifier(n.Pointer, f)
+
+	// statements
+	case *BadStatement:
+		// leaf
+	case *LabelStatement:
+		n.Name = rewriteIdentifier(n.Name, f)
+	case *VerbStatement:
+		n.Label = rewriteIdentifier(n.Label, f)
+		n.Operands = rewriteList(n.Operands, f)
+		n.Condition = rewriteExpression(n.Condition, f)
+	case *BlockStatement:
+		n.Statements = rewriteList(n.Statements, f)
+	case *IfStatement:
+		n.Label = rewriteIdentifier(n.Label, f)
+		n.Condition = rewriteExpression(n.Condition, f)
+		n.Consequence = rewriteBlock(n.Consequence, f)
+		n.ElseIfs = rewriteList(n.ElseIfs, f)
+		n.Alternative = rewriteBlock(n.Alternative, f)
+	case *ElseIfClause:
+		n.Condition = rewriteExpression(n.Condition, f)
+		n.Body = rewriteBlock(n.Body, f)
+	case *LoopStatement:
+		n.Label = rewriteIdentifier(n.Label, f)
+		n.Body = rewriteBlock(n.Body, f)
+	case *LoopConditionStatement:
+		n.Label = rewriteIdentifier(n.Label, f)
+		n.Condition = rewriteExpression(n.Condition, f)
+	case *ForStatement:
+		n.Label = rewriteIdentifier(n.Label, f)
+		n.Variable = rewriteExpression(n.Variable, f)
+		n.From = rewriteExpression(n.From, f)
+		n.To = rewriteExpression(n.To, f)
+		n.By = rewriteExpression(n.By, f)
+		n.Body = rewriteBlock(n.Body, f)
+	case *SwitchStatement:
+		n.Label = rewriteIdentifier(n.Label, f)
+		n.Subject = rewriteExpression(n.Subject, f)
+		n.Cases = rewriteList(n.Cases, f)
+		n.Default = rewriteBlock(n.Default, f)
+	case *CaseClause:
+		n.Values = rewriteList(n.Values, f)
+		n.Body = rewriteBlock(n.Body, f)
+	case *CallStatement:
+		n.Label = rewriteIdentifier(n.Label, f)
+		n.Target = rewriteIdentifier(n.Target, f)
+		n.Args = rewriteList(n.Args, f)
+		n.Condition = rewriteExpression(n.Condition, f)
+	case *ListStatement:
+		n.Label = rewriteIdentifier(n.Label, f)
+		n.Items = rewriteList(n.Items, f)
+	case *ListItem:
+		n.Value = rewriteExpression(n.Value, f)
+		n.Args = rewriteList(n.Args, f)
+	case *IncludeStatement:
+		n.Label = rewriteIdentifier(n.Label, f)
+		n.Body = rewriteBlock(n.Body, f)
+
+	// declarations
+	case *DataDeclaration:
+		n.Name = rewriteIdentifier(n.Name, f)
+		n.Operands = rewriteList(n.Operands, f)
+	case *FileDeclaration:
+		n.Name = rewriteIdentifier(n.Name, f)
+	case *PointerDeclaration:
+		n.Name = rewriteIdentifier(n.Name, f)
+	case *RoutineStatement:
+		n.Name = rewriteIdentifier(n.Name, f)
+		n.Params = rewriteList(n.Params, f)
+		n.Body = rewriteBlock(n.Body, f)
+
+	default:
+		panic(fmt.Sprintf("ast.Rewrite: unexpected node type %T", n))
+	}
+	return f(node)
+}
+
+// rewriteAs rewrites node and checks that its replacement is a T, a nil replacement gives the zero T
+func rewriteAs[T Node](node T, f RewriteFunc) T {
+	var zero T
+	repl := Rewrite(node, f)
+	if repl == nil {
+		return zero
+	}
+	t, ok := repl.(T)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: cannot replace %T with %T", node, repl))
+	}
+	return t
+}
+
+func rewriteIdentifier(ident *Identifier, f RewriteFunc) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	return rewriteAs(ident, f)
+}
+
+func rewriteBlock(block *BlockStatement, f RewriteFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	return rewriteAs(block, f)
+}
+
+func rewriteExpression(expr Expression, f RewriteFunc) Expression {
+	if expr == nil {
+		return nil
+	}
+	return rewriteAs(expr, f)
+}
+
+// rewriteList rewrites every element of list in place, elements replaced by nil are removed
+func rewriteList[T Node](list []T, f RewriteFunc) []T {
+	kept := list[:0]
+	for _, node := range list {
+		if isNil(node) {
+			continue
+		}
+		if repl := rewriteAs(node, f); !isNil(repl) {
+			kept = append(kept, repl)
+		}
+	}
+	return kept
+}
+
+// isNil reports whether node is nil or a nil pointer of a node type
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	switch n := node.(type) {
+	case *Identifier:
+		return n == nil
+	case *BlockStatement:
+		return n == nil
+	case *ElseIfClause:
+		return n == nil
+	case *CaseClause:
+		return n == nil
+	case *ListItem:
+		return n == nil
+	}
+	return false
+}
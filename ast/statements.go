@@ -0,0 +1,466 @@
+package ast
+
+import (
+	"PLB-Interpreter/tokens"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// LabelStatement is an execution label on a line of its own, e.g. TOP
+type LabelStatement struct {
+	Token tokens.Token // the IDENT token of the label
+	Name  *Identifier
+}
+
+func (ls *LabelStatement) statementNode()       {}
+func (ls *LabelStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LabelStatement) Pos() Position        { return tokenPos(ls.Token) }
+func (ls *LabelStatement) End() Position        { return tokenEnd(ls.Token) }
+func (ls *LabelStatement) String() string       { return ls.Name.String() + "\n" }
+
+// VerbStatement is a single verb with its operands, e.g. MOVE "HELLO" TO VARONE
+type VerbStatement struct {
+	Token     tokens.Token   // the verb token
+	Label     *Identifier    // optional label in front of the verb
+	Verb      string         // upper-cased verb name
+	Operands  []Expression   // operands in source order
+	Seps      []tokens.Token // separators between the operands (COMMA or PREPOSITION), len(Operands)-1 entries
+	Condition Expression     // optional trailing IF condition, nil if the statement is unconditional
+}
+
+func (vs *VerbStatement) statementNode()       {}
+func (vs *VerbStatement) TokenLiteral() string { return vs.Token.Literal }
+func (vs *VerbStatement) Pos() Position        { return labelledPos(vs.Label, vs.Token) }
+func (vs *VerbStatement) End() Position        { return lastEnd(vs.Token, lastOf(vs.Operands), vs.Condition) }
+func (vs *VerbStatement) String() string {
+	var out bytes.Buffer
+	writeLabel(&out, vs.Label)
+	out.WriteString(" " + vs.Verb)
+	for i, op := range vs.Operands {
+		if i == 0 {
+			out.WriteString(" ")
+		} else if i-1 < len(vs.Seps) && vs.Seps[i-1].Type == tokens.PREPOSITION {
+			out.WriteString(" " + vs.Seps[i-1].Literal + " ")
+		} else if i-1 < len(vs.Seps) && vs.Seps[i-1].Type == tokens.SEMICOLON {
+			out.WriteString(";")
+		} else {
+			// operands of a tree built without separators are separated by commas
+			out.WriteString(",")
+		}
+		out.WriteString(op.String())
+	}
+	if vs.Condition != nil {
+		out.WriteString(" IF " + vs.Condition.String())
+	}
+	out.WriteString("\n")
+	return out.String()
+}
+
+// BlockStatement is a sequence of statements nested inside a structured statement, e.g. the body of a LOOP
+type BlockStatement struct {
+	Token      tokens.Token // the token of the verb opening the block
+	Statements []Statement
+}
+
+func (bs *BlockStatement) statementNode()       {}
+func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+
+// Pos returns the start of the first statement of the block, a block without statements is placed at its opening verb
+func (bs *BlockStatement) Pos() Position {
+	if len(bs.Statements) == 0 {
+		return tokenPos(bs.Token)
+	}
+	return bs.Statements[0].Pos()
+}
+
+// End returns the end of the last statement of the block, or the end of the opening verb if there is none
+func (bs *BlockStatement) End() Position {
+	if len(bs.Statements) == 0 {
+		return tokenEnd(bs.Token)
+	}
+	return bs.Statements[len(bs.Statements)-1].End()
+}
+func (bs *BlockStatement) String() string {
+	var out bytes.Buffer
+	for _, s := range bs.Statements {
+		out.WriteString(s.String())
+	}
+	return out.String()
+}
+
+// IfStatement is an IF ... ELSEIF ... ELSE ... ENDIF block
+type IfStatement struct {
+	Token       tokens.Token    // the IF token
+	Label       *Identifier     // optional label in front of the IF
+	Condition   Expression      // condition flag or parenthesised expression
+	Consequence *BlockStatement // statements executed if Condition holds
+	ElseIfs     []*ElseIfClause // ELSEIF clauses in source order
+	Alternative *BlockStatement // statements of the ELSE clause, nil if there is none
+	EndToken    tokens.Token    // the ENDIF token
+}
+
+func (is *IfStatement) statementNode()       {}
+func (is *IfStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *IfStatement) Pos() Position        { return labelledPos(is.Label, is.Token) }
+func (is *IfStatement) End() Position {
+	return endOr(is.EndToken, is.Token, is.Condition, is.Consequence, lastOf(is.ElseIfs), is.Alternative)
+}
+func (is *IfStatement) String() string {
+	var out bytes.Buffer
+	writeLabel(&out, is.Label)
+	out.WriteString(" IF " + is.Condition.String() + "\n")
+	out.WriteString(is.Consequence.String())
+	for _, clause := range is.ElseIfs {
+		out.WriteString(clause.String())
+	}
+	if is.Alternative != nil {
+		out.WriteString(" ELSE\n")
+		out.WriteString(is.Alternative.String())
+	}
+	out.WriteString(" ENDIF\n")
+	return out.String()
+}
+
+// ElseIfClause is a single ELSEIF branch of an IfStatement
+type ElseIfClause struct {
+	Token     tokens.Token // the ELSEIF token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ec *ElseIfClause) TokenLiteral() string { return ec.Token.Literal }
+func (ec *ElseIfClause) Pos() Position        { return tokenPos(ec.Token) }
+func (ec *ElseIfClause) End() Position        { return lastEnd(ec.Token, ec.Condition, ec.Body) }
+func (ec *ElseIfClause) String() string {
+	return " ELSEIF " + ec.Condition.String() + "\n" + ec.Body.String()
+}
+
+// LoopStatement is a LOOP ... REPEAT block, left by WHILE, UNTIL or BREAK inside the body
+type LoopStatement struct {
+	Token    tokens.Token // the LOOP token
+	Label    *Identifier  // optional label in front of the LOOP
+	Body     *BlockStatement
+	EndToken tokens.Token // the REPEAT token
+}
+
+func (ls *LoopStatement) statementNode()       {}
+func (ls *LoopStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LoopStatement) Pos() Position        { return labelledPos(ls.Label, ls.Token) }
+func (ls *LoopStatement) End() Position        { return endOr(ls.EndToken, ls.Token, ls.Body) }
+func (ls *LoopStatement) String() string {
+	var out bytes.Buffer
+	writeLabel(&out, ls.Label)
+	out.WriteString(" LOOP\n")
+	out.WriteString(ls.Body.String())
+	out.WriteString(" REPEAT\n")
+	return out.String()
+}
+
+// LoopConditionStatement is a WHILE or UNTIL test inside a loop body
+type LoopConditionStatement struct {
+	Token     tokens.Token // the WHILE or UNTIL token
+	Label     *Identifier  // optional label in front of the verb
+	Verb      string       // WHILE or UNTIL
+	Condition Expression
+}
+
+func (lc *LoopConditionStatement) statementNode()       {}
+func (lc *LoopConditionStatement) TokenLiteral() string { return lc.Token.Literal }
+func (lc *LoopConditionStatement) Pos() Position        { return labelledPos(lc.Label, lc.Token) }
+func (lc *LoopConditionStatement) End() Position        { return lastEnd(lc.Token, lc.Condition) }
+func (lc *LoopConditionStatement) String() string {
+	var out bytes.Buffer
+	writeLabel(&out, lc.Label)
+	out.WriteString(" " + lc.Verb + " " + lc.Condition.String() + "\n")
+	return out.String()
+}
+
+// ForStatement is a FOR ... FROM ... TO ... BY ... REPEAT block
+type ForStatement struct {
+	Token    tokens.Token // the FOR token
+	Label    *Identifier  // optional label in front of the FOR
+	Variable Expression   // the loop counter
+	From     Expression
+	To       Expression
+	By       Expression // step, nil if omitted
+	Body     *BlockStatement
+	EndToken tokens.Token // the REPEAT token
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() Position        { return labelledPos(fs.Label, fs.Token) }
+func (fs *ForStatement) End() Position {
+	return endOr(fs.EndToken, fs.Token, fs.Variable, fs.From, fs.To, fs.By, fs.Body)
+}
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+	writeLabel(&out, fs.Label)
+	out.WriteString(" FOR " + fs.Variable.String())
+	if fs.From != nil {
+		out.WriteString(" FROM " + fs.From.String())
+	}
+	if fs.To != nil {
+		out.WriteString(" TO " + fs.To.String())
+	}
+	if fs.By != nil {
+		out.WriteString(" BY " + fs.By.String())
+	}
+	out.WriteString("\n")
+	out.WriteString(fs.Body.String())
+	out.WriteString(" REPEAT\n")
+	return out.String()
+}
+
+// SwitchStatement is a SWITCH ... CASE ... DEFAULT ... ENDSWITCH block
+type SwitchStatement struct {
+	Token    tokens.Token  // the SWITCH token
+	Label    *Identifier   // optional label in front of the SWITCH
+	Subject  Expression    // the value compared against each CASE
+	Cases    []*CaseClause // CASE clauses in source order
+	Default  *BlockStatement
+	EndToken tokens.Token // the ENDSWITCH token
+}
+
+func (ss *SwitchStatement) statementNode()       {}
+func (ss *SwitchStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *SwitchStatement) Pos() Position        { return labelledPos(ss.Label, ss.Token) }
+func (ss *SwitchStatement) End() Position {
+	return endOr(ss.EndToken, ss.Token, ss.Subject, lastOf(ss.Cases), ss.Default)
+}
+func (ss *SwitchStatement) String() string {
+	var out bytes.Buffer
+	writeLabel(&out, ss.Label)
+	out.WriteString(" SWITCH " + ss.Subject.String() + "\n")
+	for _, c := range ss.Cases {
+		out.WriteString(c.String())
+	}
+	if ss.Default != nil {
+		out.WriteString(" DEFAULT\n")
+		out.WriteString(ss.Default.String())
+	}
+	out.WriteString(" ENDSWITCH\n")
+	return out.String()
+}
+
+// CaseClause is a single CASE branch of a SwitchStatement
+type CaseClause struct {
+	Token  tokens.Token // the CASE token
+	Values []Expression
+	Body   *BlockStatement
+}
+
+func (cc *CaseClause) TokenLiteral() string { return cc.Token.Literal }
+func (cc *CaseClause) Pos() Position        { return tokenPos(cc.Token) }
+func (cc *CaseClause) End() Position        { return lastEnd(cc.Token, lastOf(cc.Values), cc.Body) }
+func (cc *CaseClause) String() string {
+	var out bytes.Buffer
+	out.WriteString(" CASE ")
+	for i, v := range cc.Values {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		out.WriteString(v.String())
+	}
+	out.WriteString("\n")
+	out.WriteString(cc.Body.String())
+	return out.String()
+}
+
+// writeLabel writes the label of a statement, if there is one
+func writeLabel(out *bytes.Buffer, label *Identifier) {
+	if label != nil {
+		out.WriteString(label.String())
+	}
+}
+
+// CallStatement is a call of a routine or label, e.g. CALL SUB USING A,B
+type CallStatement struct {
+	Token     tokens.Token // the CALL token
+	Label     *Identifier  // optional label in front of the CALL
+	Target    *Identifier  // the called routine or label
+	Args      []Expression // actual arguments following USING
+	Condition Expression   // optional trailing IF condition
+}
+
+func (cs *CallStatement) statementNode()       {}
+func (cs *CallStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *CallStatement) Pos() Position        { return labelledPos(cs.Label, cs.Token) }
+func (cs *CallStatement) End() Position {
+	return lastEnd(cs.Token, cs.Target, lastOf(cs.Args), cs.Condition)
+}
+func (cs *CallStatement) String() string {
+	var out bytes.Buffer
+	writeLabel(&out, cs.Label)
+	out.WriteString(" CALL " + cs.Target.String())
+	for i, arg := range cs.Args {
+		if i == 0 {
+			out.WriteString(" USING ")
+		} else {
+			out.WriteString(",")
+		}
+		out.WriteString(arg.String())
+	}
+	if cs.Condition != nil {
+		out.WriteString(" IF " + cs.Condition.String())
+	}
+	out.WriteString("\n")
+	return out.String()
+}
+
+// ListItemKind tells what an item of a DISPLAY, KEYIN or PRINT list is
+type ListItemKind int
+
+const (
+	VariableItem  ListItemKind = iota // a variable to show or, for KEYIN, to read into
+	LiteralItem                       // a literal to show
+	PositionItem                      // a positioning control, e.g. *P=10:2
+	AttributeItem                     // an attribute control, e.g. *ES
+	SuppressItem                      // ! or ; at the end of the list, suppressing the new line
+)
+
+func (k ListItemKind) String() string {
+	switch k {
+	case VariableItem:
+		return "variable"
+	case LiteralItem:
+		return "literal"
+	case PositionItem:
+		return "positioning control"
+	case AttributeItem:
+		return "attribute control"
+	case SuppressItem:
+		return "new line suppression"
+	}
+	return "unknown item"
+}
+
+// MarshalText encodes the kind by its name, e.g. in the JSON encoding of a program
+func (k ListItemKind) MarshalText() ([]byte, error) {
+	if k < VariableItem || k > SuppressItem {
+		return nil, fmt.Errorf("unknown list item kind %d", int(k))
+	}
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decodes a kind encoded by MarshalText
+func (k *ListItemKind) UnmarshalText(text []byte) error {
+	for kind := VariableItem; kind <= SuppressItem; kind++ {
+		if kind.String() == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown list item kind %q", text)
+}
+
+// ListItem is a single item of a DISPLAY, KEYIN or PRINT list
+type ListItem struct {
+	Token    tokens.Token // the first token of the item
+	Kind     ListItemKind
+	Value    Expression   // the variable or literal, nil for controls
+	Control  string       // upper-cased name of a control without the *, e.g. P or ES
+	Args     []Expression // arguments of a control, e.g. 10 and 2 of *P=10:2
+	EndToken tokens.Token // the last token of the item
+}
+
+func (li *ListItem) TokenLiteral() string { return li.Token.Literal }
+func (li *ListItem) Pos() Position        { return tokenPos(li.Token) }
+func (li *ListItem) End() Position        { return endOr(li.EndToken, li.Token, li.Value, lastOf(li.Args)) }
+func (li *ListItem) String() string {
+	switch li.Kind {
+	case VariableItem, LiteralItem:
+		return li.Value.String()
+	case SuppressItem:
+		if li.Token.Literal == "" {
+			return ";"
+		}
+		return li.Token.Literal
+	}
+	out := "*" + li.Control
+	for i, arg := range li.Args {
+		if i == 0 {
+			out += "="
+		} else {
+			out += ":"
+		}
+		out += arg.String()
+	}
+	return out
+}
+
+// ListStatement is a DISPLAY, KEYIN or PRINT with its items in source order, e.g. DISPLAY *P=10:2,VARTWO
+type ListStatement struct {
+	Token tokens.Token // the verb token
+	Label *Identifier  // nil if the statement is not labelled
+	Verb  string       // upper-cased verb
+	Items []*ListItem
+}
+
+func (ls *ListStatement) statementNode()       {}
+func (ls *ListStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *ListStatement) Pos() Position        { return labelledPos(ls.Label, ls.Token) }
+func (ls *ListStatement) End() Position        { return lastEnd(ls.Token, lastOf(ls.Items)) }
+func (ls *ListStatement) String() string {
+	var out bytes.Buffer
+	writeLabel(&out, ls.Label)
+	out.WriteString(" " + ls.Verb)
+	for i, item := range ls.Items {
+		switch {
+		case i == 0:
+			out.WriteString(" ")
+		case item.Kind != SuppressItem:
+			out.WriteString(",")
+		}
+		out.WriteString(item.String())
+	}
+	out.WriteString("\n")
+	return out.String()
+}
+
+// IncludeStatement is an INCLUDE of another source file, e.g. INCLUDE common.pls.
+// The statements of the included file are parsed into Body, which is nil if the file was not included.
+type IncludeStatement struct {
+	Token tokens.Token // the INCLUDE or INC token
+	Label *Identifier  // nil if the statement is not labelled
+	Path  string       // the file name as written, without quotes
+	File  string       // the path the file was read from, empty if it was not included
+	Body  *BlockStatement
+}
+
+func (is *IncludeStatement) statementNode()       {}
+func (is *IncludeStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *IncludeStatement) Pos() Position        { return labelledPos(is.Label, is.Token) }
+
+// End returns the end of the file name on the INCLUDE line, the included statements are in another file
+func (is *IncludeStatement) End() Position {
+	end := tokenPos(is.Token)
+	line := strings.TrimRight(is.Token.LineTxt, " \t\r\n")
+	if len(line) >= is.Token.Col {
+		end.Col = len(line) + 1
+	} else {
+		end = tokenEnd(is.Token)
+	}
+	return end
+}
+func (is *IncludeStatement) String() string {
+	var out bytes.Buffer
+	writeLabel(&out, is.Label)
+	out.WriteString(" INCLUDE " + is.Path + "\n")
+	return out.String()
+}
+
+// BadStatement stands in for a line that could not be parsed, it spans the skipped tokens.
+// The error explaining it is reported by the parser.
+type BadStatement struct {
+	Token    tokens.Token // the first skipped token
+	EndToken tokens.Token // the last skipped token
+	Text     string       // the skipped source text
+}
+
+func (bs *BadStatement) statementNode()       {}
+func (bs *BadStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BadStatement) Pos() Position        { return tokenPos(bs.Token) }
+func (bs *BadStatement) End() Position        { return tokenEnd(bs.EndToken) }
+func (bs *BadStatement) String() string       { return bs.Text + "\n" }
@@ -0,0 +1,243 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is called for every node encountered by Walk.
+// If the returned visitor w is not nil, Walk visits each of the children of node with w,
+// followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node depth-first in source order.
+// It starts by calling v.Visit(node), node must not be nil.
+// Nil children, e.g. a missing label or condition, are not visited.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case Program:
+		walkStatements(v, n.Statements)
+	case *Program:
+		walkStatements(v, n.Statements)
+
+	// expressions
+	case *Identifier, *StringLiteral, *NumberLiteral, *FlagExpression, *EventExpression, *BadExpression:
+		// leaves
+	case *PrefixExpression:
+		walkExpression(v, n.Right)
+	case *InfixExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Right)
+	case *GroupedExpression:
+		walkExpression(v, n.Expression)
+	case *IndexExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Index)
+	case *DerefExpression:
+		walkIdentifier(v, n.Pointer)
+
+	// statements
+	case *BadStatement:
+		// leaf
+	case *LabelStatement:
+		walkIdentifier(v, n.Name)
+	case *VerbStatement:
+		walkIdentifier(v, n.Label)
+		walkExpressions(v, n.Operands)
+		walkExpression(v, n.Condition)
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+	case *IfStatement:
+		walkIdentifier(v, n.Label)
+		walkExpression(v, n.Condition)
+		walkBlock(v, n.Consequence)
+		for _, clause := range n.ElseIfs {
+			if clause != nil {
+				Walk(v, clause)
+			}
+		}
+		walkBlock(v, n.Alternative)
+	case *ElseIfClause:
+		walkExpression(v, n.Condition)
+		walkBlock(v, n.Body)
+	case *LoopStatement:
+		walkIdentifier(v, n.Label)
+		walkBlock(v, n.Body)
+	case *LoopConditionStatement:
+		walkIdentifier(v, n.Label)
+		walkExpression(v, n.Condition)
+	case *ForStatement:
+		walkIdentifier(v, n.Label)
+		walkExpression(v, n.Variable)
+		walkExpression(v, n.From)
+		walkExpression(v, n.To)
+		walkExpression(v, n.By)
+		walkBlock(v, n.Body)
+	case *SwitchStatement:
+		walkIdentifier(v, n.Label)
+		walkExpression(v, n.Subject)
+		for _, clause := range n.Cases {
+			if clause != nil {
+				Walk(v, clause)
+			}
+		}
+		walkBlock(v, n.Default)
+	case *CaseClause:
+		walkExpressions(v, n.Values)
+		walkBlock(v, n.Body)
+	case *CallStatement:
+		walkIdentifier(v, n.Label)
+		walkIdentifier(v, n.Target)
+		walkExpressions(v, n.Args)
+		walkExpression(v, n.Condition)
+	case *ListStatement:
+		walkIdentifier(v, n.Label)
+		for _, item := range n.Items {
+			if item != nil {
+				Walk(v, item)
+			}
+		}
+	case *ListItem:
+		walkExpression(v, n.Value)
+		walkExpressions(v, n.Args)
+	case *IncludeStatement:
+		walkIdentifier(v, n.Label)
+		walkBlock(v, n.Body)
+
+	// declarations
+	case *DataDeclaration:
+		walkIdentifier(v, n.Name)
+		walkExpressions(v, n.Operands)
+	case *FileDeclaration:
+		walkIdentifier(v, n.Name)
+	case *PointerDeclaration:
+		walkIdentifier(v, n.Name)
+	case *RoutineStatement:
+		walkIdentifier(v, n.Name)
+		for _, param := range n.Params {
+			walkIdentifier(v, param)
+		}
+		walkBlock(v, n.Body)
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+// walkIdentifier visits an optional identifier, a nil pointer would otherwise be a non-nil Node
+func walkIdentifier(v Visitor, ident *Identifier) {
+	if ident != nil {
+		Walk(v, ident)
+	}
+}
+
+// walkBlock visits an optional block, e.g. the ELSE of an IF
+func walkBlock(v Visitor, block *BlockStatement) {
+	if block != nil {
+		Walk(v, block)
+	}
+}
+
+func walkExpression(v Visitor, expr Expression) {
+	if expr != nil {
+		Walk(v, expr)
+	}
+}
+
+func walkExpressions(v Visitor, exprs []Expression) {
+	for _, expr := range exprs {
+		walkExpression(v, expr)
+	}
+}
+
+func walkStatements(v Visitor, stmts []Statement) {
+	for _, stmt := range stmts {
+		if stmt != nil {
+			Walk(v, stmt)
+		}
+	}
+}
+
+// inspector adapts a function to the Visitor interface
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node depth-first in source order, calling f(node) for every node.
+// If f returns true, Inspect visits the children of node, followed by a call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Label returns the label in front of an executable statement, or nil if there is none.
+// A label on a line of its own is a LabelStatement of its own, and the name of a declaration is not a label.
+func Label(stmt Statement) *Identifier {
+	switch s := stmt.(type) {
+	case *VerbStatement:
+		return s.Label
+	case *CallStatement:
+		return s.Label
+	case *ListStatement:
+		return s.Label
+	case *IncludeStatement:
+		return s.Label
+	case *IfStatement:
+		return s.Label
+	case *LoopStatement:
+		return s.Label
+	case *LoopConditionStatement:
+		return s.Label
+	case *ForStatement:
+		return s.Label
+	case *SwitchStatement:
+		return s.Label
+	}
+	return nil
+}
+
+// Blocks calls fn for every block directly nested in stmt, in source order: the bodies of structured statements,
+// routines and INCLUDEs. A block itself is passed to fn. Like Walk, Blocks skips nil blocks.
+func Blocks(stmt Statement, fn func(block *BlockStatement)) {
+	visit := func(block *BlockStatement) {
+		if block != nil {
+			fn(block)
+		}
+	}
+	switch s := stmt.(type) {
+	case *BlockStatement:
+		visit(s)
+	case *RoutineStatement:
+		visit(s.Body)
+	case *IncludeStatement:
+		visit(s.Body)
+	case *IfStatement:
+		visit(s.Consequence)
+		for _, clause := range s.ElseIfs {
+			if clause != nil {
+				visit(clause.Body)
+			}
+		}
+		visit(s.Alternative)
+	case *LoopStatement:
+		visit(s.Body)
+	case *ForStatement:
+		visit(s.Body)
+	case *SwitchStatement:
+		for _, clause := range s.Cases {
+			if clause != nil {
+				visit(clause.Body)
+			}
+		}
+		visit(s.Default)
+	}
+}
@@ -0,0 +1,309 @@
+// Package callgraph builds the call graph of a project: which routines of its programs CALL which others,
+// and which programs they CHAIN to or load by LOADMOD. The targets of CALLs are found in the execution label
+// table of the calling program, or among the routines of the modules it loads. Targets that cannot be found,
+// e.g. a CHAIN to a program outside of the project or to a name held in a variable, are external nodes.
+//
+// Routines in INCLUDEd files belong to the program including them. The graph tells the CALLs that are part of
+// a recursion, and the routines that no one calls.
+package callgraph
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/cfg"
+	"PLB-Interpreter/symbols"
+	"PLB-Interpreter/tokens"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// NodeKind tells what a node of the graph stands for
+type NodeKind int
+
+const (
+	Main     NodeKind = iota // the main line of a program
+	Routine                  // a ROUTINE or LROUTINE
+	Label                    // an execution label that is called without being a routine
+	External                 // a target outside of the project
+)
+
+func (k NodeKind) String() string {
+	switch k {
+	case Main:
+		return "main"
+	case Routine:
+		return "routine"
+	case Label:
+		return "label"
+	}
+	return "external"
+}
+
+// EdgeKind tells how a node refers to another one
+type EdgeKind int
+
+const (
+	Call    EdgeKind = iota // a CALL of a routine or label
+	Chain                   // a CHAIN to the main line of a program
+	LoadMod                 // a LOADMOD of a program, whose routines can be called afterwards
+)
+
+func (k EdgeKind) String() string {
+	switch k {
+	case Call:
+		return "CALL"
+	case Chain:
+		return "CHAIN"
+	}
+	return "LOADMOD"
+}
+
+// Node is a routine, label or main line of a program, or an external target
+type Node struct {
+	Program  *Program // the program of the node, nil for an external one
+	Name     string   // the name of the routine or label, cfg.MainName for a main line
+	Kind     NodeKind
+	Declared ast.Position // the declaration of a routine or label, not valid for other nodes
+}
+
+// ID returns the name of the node qualified by its program, e.g. PAYROLL:TAX
+func (n *Node) ID() string {
+	if n.Program == nil {
+		return n.Name
+	}
+	return n.Program.Name + ":" + n.Name
+}
+
+// Edge is a statement of one node referring to another one
+type Edge struct {
+	From, To *Node
+	Kind     EdgeKind
+	Pos      ast.Position // the CALL, CHAIN or LOADMOD
+}
+
+// Graph is the call graph of a project
+type Graph struct {
+	Programs []*Program
+	Nodes    []*Node // the main line and routines of every program in the order of the programs, then labels and externals
+	Edges    []*Edge // in source order for every program
+
+	nodes map[string]*Node // by upper-cased ID
+}
+
+// Build returns the call graph of the given programs
+func Build(progs []*Program) *Graph {
+	g := &Graph{Programs: progs, nodes: map[string]*Node{}}
+	for _, p := range progs {
+		g.node(p, cfg.MainName, Main, ast.Position{})
+		ast.Inspect(p.AST, func(node ast.Node) bool {
+			if r, ok := node.(*ast.RoutineStatement); ok {
+				g.node(p, r.Name.Value, Routine, r.Name.Pos())
+			}
+			return true
+		})
+	}
+	for _, p := range progs {
+		g.addEdges(p, p.AST, g.node(p, cfg.MainName, Main, ast.Position{}), g.loaded(p))
+	}
+	return g
+}
+
+// node returns the node of a program with the given name, adding it if it is new. p is nil for an external node.
+func (g *Graph) node(p *Program, name string, kind NodeKind, declared ast.Position) *Node {
+	n := &Node{Program: p, Name: name, Kind: kind, Declared: declared}
+	id := strings.ToUpper(n.ID())
+	if found, ok := g.nodes[id]; ok {
+		return found
+	}
+	g.nodes[id] = n
+	g.Nodes = append(g.Nodes, n)
+	return n
+}
+
+// addEdges adds the references of the statements in root, made by the node from.
+// The statements of a routine are made by the routine.
+func (g *Graph) addEdges(p *Program, root ast.Node, from *Node, modules []*Program) {
+	ast.Inspect(root, func(node ast.Node) bool {
+		switch s := node.(type) {
+		case *ast.RoutineStatement:
+			if s == root {
+				return true
+			}
+			g.addEdges(p, s, g.node(p, s.Name.Value, Routine, s.Name.Pos()), modules)
+			return false
+		case *ast.CallStatement:
+			g.Edges = append(g.Edges, &Edge{From: from, To: g.callee(p, s.Target, modules), Kind: Call, Pos: tokenPos(s.Token)})
+		case *ast.VerbStatement:
+			kind := Chain
+			switch s.Verb {
+			case "CHAIN":
+			case "LOADMOD":
+				kind = LoadMod
+			default:
+				return true
+			}
+			if len(s.Operands) > 0 {
+				g.Edges = append(g.Edges, &Edge{From: from, To: g.program(s.Operands[0]), Kind: kind, Pos: tokenPos(s.Token)})
+			}
+		}
+		return true
+	})
+}
+
+// callee returns the node called by target: a routine or label of p, a routine of one of the modules p loads,
+// or an external node
+func (g *Graph) callee(p *Program, target *ast.Identifier, modules []*Program) *Node {
+	if sym, ok := p.Table.Uses[target]; ok {
+		switch sym.Kind {
+		case symbols.Routine:
+			return g.node(p, sym.Name, Routine, ast.Position{})
+		case symbols.Label:
+			return g.node(p, sym.Name, Label, tokenPos(sym.Token))
+		}
+	}
+	for _, m := range modules {
+		if sym, ok := m.Table.Global.Lookup(symbols.ExecutionSpace, target.Value); ok && sym.Kind == symbols.Routine {
+			return g.node(m, sym.Name, Routine, ast.Position{})
+		}
+	}
+	return g.node(nil, target.Value, External, ast.Position{})
+}
+
+// program returns the main line of the program named by the operand of a CHAIN or LOADMOD,
+// or an external node if it is not a program of the project
+func (g *Graph) program(operand ast.Expression) *Node {
+	if lit, ok := operand.(*ast.StringLiteral); ok {
+		if p, ok := g.lookup(lit.Value); ok {
+			return g.node(p, cfg.MainName, Main, ast.Position{})
+		}
+		return g.node(nil, lit.Value, External, ast.Position{})
+	}
+	return g.node(nil, operand.String(), External, ast.Position{})
+}
+
+// lookup returns the program of the project a CHAIN or LOADMOD names, ignoring directories, extensions and case
+func (g *Graph) lookup(name string) (*Program, bool) {
+	name = programName(name)
+	for _, p := range g.Programs {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// loaded returns the programs of the project p loads by LOADMOD
+func (g *Graph) loaded(p *Program) []*Program {
+	var modules []*Program
+	ast.Inspect(p.AST, func(node ast.Node) bool {
+		if s, ok := node.(*ast.VerbStatement); ok && s.Verb == "LOADMOD" && len(s.Operands) > 0 {
+			if lit, ok := s.Operands[0].(*ast.StringLiteral); ok {
+				if m, ok := g.lookup(lit.Value); ok {
+					modules = append(modules, m)
+				}
+			}
+		}
+		return true
+	})
+	return modules
+}
+
+// Recursion returns the groups of nodes that call themselves, directly or through each other, in the order of Nodes
+func (g *Graph) Recursion() [][]*Node {
+	calls := map[*Node][]*Node{}
+	self := map[*Node]bool{}
+	for _, e := range g.Edges {
+		if e.Kind == Call {
+			calls[e.From] = append(calls[e.From], e.To)
+			if e.From == e.To {
+				self[e.From] = true
+			}
+		}
+	}
+
+	// Tarjan's strongly connected components
+	index := map[*Node]int{}
+	low := map[*Node]int{}
+	onStack := map[*Node]bool{}
+	var stack []*Node
+	var groups [][]*Node
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		index[n] = len(index)
+		low[n] = index[n]
+		stack = append(stack, n)
+		onStack[n] = true
+		for _, m := range calls[n] {
+			if _, seen := index[m]; !seen {
+				visit(m)
+				if low[m] < low[n] {
+					low[n] = low[m]
+				}
+			} else if onStack[m] && index[m] < low[n] {
+				low[n] = index[m]
+			}
+		}
+		if low[n] != index[n] {
+			return
+		}
+		var group []*Node
+		for {
+			m := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[m] = false
+			group = append(group, m)
+			if m == n {
+				break
+			}
+		}
+		if len(group) > 1 || self[n] {
+			groups = append(groups, group)
+		}
+	}
+	for _, n := range g.Nodes {
+		if _, seen := index[n]; !seen {
+			visit(n)
+		}
+	}
+
+	order := map[*Node]int{}
+	for i, n := range g.Nodes {
+		order[n] = i
+	}
+	for _, group := range groups {
+		sort.Slice(group, func(i, j int) bool {
+			return order[group[i]] < order[group[j]]
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return order[groups[i][0]] < order[groups[j][0]]
+	})
+	return groups
+}
+
+// Uncalled returns the routines no other node calls
+func (g *Graph) Uncalled() []*Node {
+	called := map[*Node]bool{}
+	for _, e := range g.Edges {
+		if e.Kind == Call && e.From != e.To {
+			called[e.To] = true
+		}
+	}
+	var nodes []*Node
+	for _, n := range g.Nodes {
+		if n.Kind == Routine && !called[n] {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// programName returns the name of the program in a file: its base name without extension
+func programName(file string) string {
+	base := filepath.Base(strings.ReplaceAll(file, `\`, "/"))
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+func tokenPos(tok tokens.Token) ast.Position {
+	return ast.Position{File: tok.FileName, Line: tok.Line, Col: tok.Col}
+}
@@ -0,0 +1,176 @@
+package callgraph
+
+import (
+	"PLB-Interpreter/parser"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// project writes the files to a temporary directory and returns the call graph of its programs,
+// parser errors fail the test
+func project(t *testing.T, files map[string]string) *Graph {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	progs, diag := Load(dir, parser.Options{})
+	if len(diag.Errors) > 0 {
+		t.Fatalf("parser errors: %v", diag.Errors)
+	}
+	return Build(progs)
+}
+
+// edges returns the edges of g as "FROM -KIND-> TO"
+func edges(g *Graph) []string {
+	var got []string
+	for _, e := range g.Edges {
+		got = append(got, fmt.Sprintf("%s -%s-> %s", e.From.ID(), e.Kind, e.To.ID()))
+	}
+	return got
+}
+
+var payroll = map[string]string{
+	"main.pls": `        INCLUDE common.inc
+        LOADMOD "lib.plc"
+        CALL    FACT
+        CALL    PING
+        CALL    MISSING
+        CHAIN   "REPORT"
+ALONE   ROUTINE
+        RETURN
+`,
+	"common.inc": `PING    ROUTINE
+        CALL    PONG
+        RETURN
+PONG    ROUTINE
+        CALL    PING
+        RETURN
+`,
+	"lib.pls": `        STOP
+FACT    ROUTINE
+        CALL    FACT
+        RETURN
+`,
+	"report.pls": `        CALL    DONE
+        CHAIN   NEXT
+        STOP
+DONE
+        RETURN
+NEXT    INIT    "MENU"
+`,
+	"notes.txt": "not a program",
+}
+
+func TestBuild(t *testing.T) {
+	g := project(t, payroll)
+	var programs []string
+	for _, p := range g.Programs {
+		programs = append(programs, p.Name)
+	}
+	if got := strings.Join(programs, " "); got != "lib main report" {
+		t.Errorf("got programs %s, want lib main report", got)
+	}
+	want := []string{
+		"lib:FACT -CALL-> lib:FACT",
+		"main:PING -CALL-> main:PONG",
+		"main:PONG -CALL-> main:PING",
+		"main:MAIN -LOADMOD-> lib:MAIN",
+		"main:MAIN -CALL-> lib:FACT",
+		"main:MAIN -CALL-> main:PING",
+		"main:MAIN -CALL-> MISSING",
+		"main:MAIN -CHAIN-> report:MAIN",
+		"report:MAIN -CALL-> report:DONE",
+		"report:MAIN -CHAIN-> NEXT",
+	}
+	if got := edges(g); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got edges\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	kinds := map[string]NodeKind{"main:MAIN": Main, "main:PING": Routine, "report:DONE": Label, "MISSING": External}
+	for _, n := range g.Nodes {
+		if kind, ok := kinds[n.ID()]; ok && n.Kind != kind {
+			t.Errorf("%s is a %s, want a %s", n.ID(), n.Kind, kind)
+		}
+	}
+}
+
+func TestGraph_Recursion(t *testing.T) {
+	g := project(t, payroll)
+	var groups []string
+	for _, group := range g.Recursion() {
+		groups = append(groups, strings.Join(ids(group), ","))
+	}
+	if got := strings.Join(groups, " "); got != "lib:FACT main:PING,main:PONG" {
+		t.Errorf("got recursion %s, want lib:FACT main:PING,main:PONG", got)
+	}
+	if got := strings.Join(ids(g.Uncalled()), " "); got != "main:ALONE" {
+		t.Errorf("got uncalled %s, want main:ALONE", got)
+	}
+}
+
+func TestGraph_WriteDOT(t *testing.T) {
+	g := project(t, map[string]string{"a.pls": `        CALL    SUB
+        CALL    SUB
+        CHAIN   "B"
+SUB     ROUTINE
+        CALL    SUB
+        RETURN
+OLD     ROUTINE
+        RETURN
+`})
+	var out bytes.Buffer
+	if err := g.WriteDOT(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph calls {
+  node [shape=box, fontname="monospace"];
+  subgraph cluster_0 {
+    label="a";
+    "a:MAIN" [label="MAIN", shape=ellipse];
+    "a:SUB" [label="SUB"];
+    "a:OLD" [label="OLD", style=filled, fillcolor="lightgrey"];
+  }
+  "B" [label="B", style=dashed];
+  "a:MAIN" -> "a:SUB";
+  "a:MAIN" -> "B" [label="CHAIN", style=dashed];
+  "a:SUB" -> "a:SUB" [color=red];
+}
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestGraph_WriteJSON(t *testing.T) {
+	g := project(t, payroll)
+	var out bytes.Buffer
+	if err := g.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	var doc jsonGraph
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != JSONVersion || len(doc.Programs) != 3 || len(doc.Edges) != 10 {
+		t.Fatalf("got version %d with %d programs and %d edges, want %d, 3 and 10",
+			doc.Version, len(doc.Programs), len(doc.Edges), JSONVersion)
+	}
+	if e := doc.Edges[3]; e.From != "main:MAIN" || e.To != "lib:MAIN" || e.Kind != "LOADMOD" || e.Line != 2 || e.Col != 9 {
+		t.Errorf("got edge %+v, want the LOADMOD of main.pls 2:9", e)
+	}
+	for _, n := range doc.Nodes {
+		if (n.Declared != nil) != (n.Kind == "routine" || n.Kind == "label") {
+			t.Errorf("node %s of kind %s has declaration %v", n.ID, n.Kind, n.Declared)
+		}
+	}
+	if fmt.Sprint(doc.Recursion, doc.Uncalled) != "[[lib:FACT] [main:PING main:PONG]] [main:ALONE]" {
+		t.Errorf("got recursion %v and uncalled %v", doc.Recursion, doc.Uncalled)
+	}
+}
@@ -0,0 +1,97 @@
+package callgraph
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/parser"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/resolver"
+	"PLB-Interpreter/symbols"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SourceExtensions are the extensions of the files Load reads from a project directory
+var SourceExtensions = []string{".pls", ".plb", ".pli", ".inc"}
+
+// Program is a program of a project, parsed with the files it includes and resolved
+type Program struct {
+	Name  string // the base name of the file without extension, the name CHAIN and LOADMOD refer to
+	File  string
+	AST   *ast.Program
+	Table *symbols.Table
+}
+
+// NewProgram resolves a program parsed from file, without reporting the errors of the resolver
+func NewProgram(file string, prog *ast.Program) *Program {
+	return &Program{Name: programName(file), File: file, AST: prog, Table: resolver.New().Resolve(prog)}
+}
+
+// Load parses the program in the file path, or the programs of the directory path, see SourceExtensions.
+// Subdirectories are not read. A file included by another one of the directory is part of that program,
+// not a program of its own. The programs are sorted by file name, the errors of the parser are returned.
+func Load(path string, opts parser.Options) ([]*Program, *plbErrors.Collector) {
+	diag := &plbErrors.Collector{}
+	info, err := os.Stat(path)
+	if err != nil {
+		diag.Error(plbErrors.NewFileError(plbErrors.ErrCannotRead, err.Error(), path))
+		return nil, diag
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			diag.Error(plbErrors.NewFileError(plbErrors.ErrCannotRead, err.Error(), path))
+			return nil, diag
+		}
+		files = nil
+		for _, entry := range entries {
+			if !entry.IsDir() && isSource(entry.Name()) {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+		sort.Strings(files)
+	}
+
+	parsed := make([]*ast.Program, len(files))
+	diags := make([]*plbErrors.Collector, len(files))
+	included := map[string]bool{}
+	for i, file := range files {
+		parsed[i], diags[i] = parser.ParseFile(file, opts)
+		ast.Inspect(parsed[i], func(node ast.Node) bool {
+			if inc, ok := node.(*ast.IncludeStatement); ok && inc.File != "" {
+				included[absPath(inc.File)] = true
+			}
+			return true
+		})
+	}
+	// an included file is only reported by the programs including it, it may not even parse on its own
+	var programs []*Program
+	for i, file := range files {
+		if included[absPath(file)] {
+			continue
+		}
+		diag.Errors = append(diag.Errors, diags[i].Errors...)
+		diag.Warnings = append(diag.Warnings, diags[i].Warnings...)
+		programs = append(programs, NewProgram(file, parsed[i]))
+	}
+	return programs, diag
+}
+
+func isSource(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range SourceExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+func absPath(file string) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		return abs
+	}
+	return filepath.Clean(file)
+}
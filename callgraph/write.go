@@ -0,0 +1,167 @@
+package callgraph
+
+import (
+	"PLB-Interpreter/ast"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JSONVersion is the version of the JSON encoding written by WriteJSON
+const JSONVersion = 1
+
+// WriteDOT writes the graph in the Graphviz DOT language, e.g. for dot -Tsvg, with a cluster for every program.
+// Main lines are ellipses and external targets dashed. Every node refers to another one by at most one edge
+// of each kind, CALLs that are part of a recursion are red and routines no one calls are grey.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	recursive := map[*Node]int{}
+	for i, group := range g.Recursion() {
+		for _, n := range group {
+			recursive[n] = i + 1
+		}
+	}
+	uncalled := map[*Node]bool{}
+	for _, n := range g.Uncalled() {
+		uncalled[n] = true
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "digraph calls {")
+	fmt.Fprintln(out, `  node [shape=box, fontname="monospace"];`)
+	for i, p := range g.Programs {
+		fmt.Fprintf(out, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(out, "    label=%s;\n", dotQuote(p.Name))
+		for _, n := range g.Nodes {
+			if n.Program == p {
+				fmt.Fprintf(out, "    %s [%s];\n", dotQuote(n.ID()), nodeAttributes(n, uncalled[n]))
+			}
+		}
+		fmt.Fprintln(out, "  }")
+	}
+	for _, n := range g.Nodes {
+		if n.Program == nil {
+			fmt.Fprintf(out, "  %s [%s];\n", dotQuote(n.ID()), nodeAttributes(n, false))
+		}
+	}
+
+	type key struct {
+		from, to *Node
+		kind     EdgeKind
+	}
+	drawn := map[key]bool{}
+	for _, e := range g.Edges {
+		if drawn[key{e.From, e.To, e.Kind}] {
+			continue
+		}
+		drawn[key{e.From, e.To, e.Kind}] = true
+		var attrs []string
+		switch {
+		case e.Kind != Call:
+			attrs = append(attrs, "label="+dotQuote(e.Kind.String()), "style=dashed")
+		case recursive[e.From] != 0 && recursive[e.From] == recursive[e.To]:
+			attrs = append(attrs, "color=red")
+		}
+		fmt.Fprintf(out, "  %s -> %s", dotQuote(e.From.ID()), dotQuote(e.To.ID()))
+		if len(attrs) > 0 {
+			fmt.Fprintf(out, " [%s]", strings.Join(attrs, ", "))
+		}
+		fmt.Fprintln(out, ";")
+	}
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+func nodeAttributes(n *Node, uncalled bool) string {
+	attrs := []string{"label=" + dotQuote(n.Name)}
+	switch n.Kind {
+	case Main:
+		attrs = append(attrs, "shape=ellipse")
+	case External:
+		attrs = append(attrs, "style=dashed")
+	}
+	if uncalled {
+		attrs = append(attrs, "style=filled", `fillcolor="lightgrey"`)
+	}
+	return strings.Join(attrs, ", ")
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// The JSON encoding of a graph is a document
+//
+//	{"version": 1, "programs": [{"name": "PAYROLL", "file": ...}],
+//	 "nodes": [{"id": "PAYROLL:TAX", "program": "PAYROLL", "name": "TAX", "kind": "routine", "declared": {...}}],
+//	 "edges": [{"from": "PAYROLL:MAIN", "to": "PAYROLL:TAX", "kind": "CALL", "file": ..., "line": ..., "col": ...}],
+//	 "recursion": [["PAYROLL:TAX"]], "uncalled": ["PAYROLL:OLD"]}
+//
+// An external node has no program, only routines and labels have a declaration.
+type jsonGraph struct {
+	Version   int            `json:"version"`
+	Programs  []*jsonProgram `json:"programs"`
+	Nodes     []*jsonNode    `json:"nodes"`
+	Edges     []*jsonEdge    `json:"edges"`
+	Recursion [][]string     `json:"recursion"`
+	Uncalled  []string       `json:"uncalled"`
+}
+
+type jsonProgram struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+}
+
+type jsonNode struct {
+	ID       string        `json:"id"`
+	Program  string        `json:"program,omitempty"`
+	Name     string        `json:"name"`
+	Kind     string        `json:"kind"`
+	Declared *ast.Position `json:"declared,omitempty"`
+}
+
+type jsonEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+	ast.Position
+}
+
+// WriteJSON writes the graph as an indented JSON document
+func (g *Graph) WriteJSON(w io.Writer) error {
+	doc := jsonGraph{Version: JSONVersion, Programs: []*jsonProgram{}, Nodes: []*jsonNode{}, Edges: []*jsonEdge{},
+		Recursion: [][]string{}, Uncalled: []string{}}
+	for _, p := range g.Programs {
+		doc.Programs = append(doc.Programs, &jsonProgram{Name: p.Name, File: p.File})
+	}
+	for _, n := range g.Nodes {
+		node := &jsonNode{ID: n.ID(), Name: n.Name, Kind: n.Kind.String()}
+		if n.Program != nil {
+			node.Program = n.Program.Name
+		}
+		if n.Declared.IsValid() {
+			pos := n.Declared
+			node.Declared = &pos
+		}
+		doc.Nodes = append(doc.Nodes, node)
+	}
+	for _, e := range g.Edges {
+		doc.Edges = append(doc.Edges, &jsonEdge{From: e.From.ID(), To: e.To.ID(), Kind: e.Kind.String(), Position: e.Pos})
+	}
+	for _, group := range g.Recursion() {
+		doc.Recursion = append(doc.Recursion, ids(group))
+	}
+	doc.Uncalled = append(doc.Uncalled, ids(g.Uncalled())...)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func ids(nodes []*Node) []string {
+	var ids []string
+	for _, n := range nodes {
+		ids = append(ids, n.ID())
+	}
+	return ids
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"PLB-Interpreter/callgraph"
+	"PLB-Interpreter/parser"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// callsCommand prints the call graph of a program, or of the programs in a directory, as Graphviz DOT or as JSON
+// with -json. The recursions and the routines no one calls are reported as well.
+func callsCommand(args []string) int {
+	flags := flag.NewFlagSet("calls", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the call graph as JSON instead of DOT")
+	dialect := flags.String("dialect", "", "PL/B dialect, SUNBELT or ANSI")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	progs, diag := callgraph.Load(sourcePath(flags.Args()), parser.Options{Dialect: *dialect})
+	for _, err := range diag.Errors {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if unreadable(diag) {
+		return 1
+	}
+
+	g := callgraph.Build(progs)
+	write := g.WriteDOT
+	if *asJSON {
+		write = g.WriteJSON
+	}
+	if err := write(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, group := range g.Recursion() {
+		var names []string
+		for _, n := range group {
+			names = append(names, n.ID())
+		}
+		if len(names) == 1 {
+			fmt.Fprintf(os.Stderr, "recursion: %s calls itself\n", names[0])
+		} else {
+			fmt.Fprintf(os.Stderr, "recursion: %s call each other\n", strings.Join(names, ", "))
+		}
+	}
+	for _, n := range g.Uncalled() {
+		fmt.Fprintf(os.Stderr, "uncalled: %s declared at %s\n", n.ID(), n.Declared)
+	}
+	if len(diag.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"PLB-Interpreter/cfg"
+	"PLB-Interpreter/parser"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cfgCommand prints the control-flow graph of the main line and of every routine of a file as Graphviz DOT,
+// or with -dir writes each graph to a file of its own named after the routine.
+func cfgCommand(args []string) int {
+	flags := flag.NewFlagSet("cfg", flag.ContinueOnError)
+	routine := flags.String("routine", "", "only the graph of the routine with this name, "+cfg.MainName+" for the main line")
+	dir := flags.String("dir", "", "write every graph to NAME.dot in this directory instead of printing them")
+	dialect := flags.String("dialect", "", "PL/B dialect, SUNBELT or ANSI")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	prog, diag := parser.ParseFile(sourcePath(flags.Args()), parser.Options{Dialect: *dialect})
+	for _, err := range diag.Errors {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if unreadable(diag) {
+		return 1
+	}
+
+	found := false
+	for _, g := range cfg.Build(prog) {
+		if *routine != "" && !strings.EqualFold(g.Name, *routine) {
+			continue
+		}
+		found = true
+		if err := writeGraph(g, *dir); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if !found {
+		fmt.Fprintf(os.Stderr, "no routine %s\n", *routine)
+		return 1
+	}
+	if len(diag.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// writeGraph prints g, or writes it to dir if dir is not empty
+func writeGraph(g *cfg.Graph, dir string) error {
+	if dir == "" {
+		return g.WriteDOT(os.Stdout)
+	}
+	file, err := os.Create(filepath.Join(dir, g.Name+".dot"))
+	if err != nil {
+		return err
+	}
+	if err := g.WriteDOT(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
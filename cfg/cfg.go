@@ -0,0 +1,452 @@
+// Package cfg builds control-flow graphs of PL/B programs.
+//
+// A program has one graph for its main line and one for every ROUTINE and LROUTINE.
+// A graph is made of basic blocks, sequences of statements executed one after the other,
+// connected by the jumps of GOTO, BRANCH, RETURN and STOP, by TRAP and by the structured blocks, e.g. IF or LOOP.
+// A CALL ends its block, since control returns to the statement after it; the called routine is recorded with the block.
+package cfg
+
+import (
+	"PLB-Interpreter/ast"
+	"strconv"
+	"strings"
+)
+
+// EdgeKind tells why control passes from one block to another
+type EdgeKind int
+
+const (
+	Next    EdgeKind = iota // falling through to the following statement
+	Jump                    // an unconditional GOTO
+	True                    // the condition of an IF, WHILE, FOR or conditional verb holds
+	False                   // the condition does not hold
+	Branch                  // one of the labels of a BRANCH, see Edge.Label
+	Case                    // a CASE of a SWITCH, see Edge.Label
+	Default                 // the DEFAULT of a SWITCH, or no CASE matching
+	Repeat                  // the REPEAT at the end of a LOOP or FOR back to its start
+	Return                  // a RETURN leaving the routine
+	Stop                    // a STOP or CHAIN ending the program
+	Trap                    // a TRAP to its handler, which may be entered at any later statement
+)
+
+func (k EdgeKind) String() string {
+	switch k {
+	case Next:
+		return "next"
+	case Jump:
+		return "goto"
+	case True:
+		return "true"
+	case False:
+		return "false"
+	case Branch:
+		return "branch"
+	case Case:
+		return "case"
+	case Default:
+		return "default"
+	case Repeat:
+		return "repeat"
+	case Return:
+		return "return"
+	case Stop:
+		return "stop"
+	case Trap:
+		return "trap"
+	}
+	return "unknown"
+}
+
+// Edge is a transfer of control between two blocks
+type Edge struct {
+	From, To *Block
+	Kind     EdgeKind
+	Label    string // the position of a BRANCH label or the values of a CASE, empty for the other kinds
+}
+
+// Block is a basic block of a graph
+type Block struct {
+	ID    int
+	Label string // the execution label the block starts with, empty if it has none
+	// Nodes are the statements of the block in execution order. A structured statement is represented by its
+	// header, e.g. an IfStatement stands for IF and its condition, its clauses (ElseIfClause, CaseClause) for theirs.
+	Nodes    []ast.Node
+	Calls    []string // the routines called by the block
+	External bool     // the block stands for a label outside of the graph, e.g. a GOTO into another routine
+	Succs    []*Edge
+	Preds    []*Edge
+}
+
+// Graph is the control-flow graph of the main line of a program or of one routine
+type Graph struct {
+	Name   string   // the name of the routine, MAIN for the main line
+	Node   ast.Node // the RoutineStatement, nil for the main line
+	Entry  *Block   // an empty block every execution starts in
+	Exit   *Block   // an empty block every RETURN, STOP and the end of the statements lead to
+	Blocks []*Block // every block including Entry and Exit, in the order they were created
+}
+
+// MainName is the name of the graph of the main line of a program
+const MainName = "MAIN"
+
+// Build returns the graph of the main line of prog followed by the graphs of its routines in source order,
+// including the routines of included files.
+func Build(prog *ast.Program) []*Graph {
+	var main []ast.Statement
+	var routines []*ast.RoutineStatement
+	split(prog.Statements, &main, &routines)
+
+	graphs := []*Graph{build(MainName, nil, main)}
+	for _, routine := range routines {
+		name := ""
+		if routine.Name != nil {
+			name = routine.Name.Value
+		}
+		graphs = append(graphs, build(name, routine, routine.Body.Statements))
+	}
+	return graphs
+}
+
+// split separates the statements of the main line from the routines, looking into the bodies of INCLUDEs
+func split(stmts []ast.Statement, main *[]ast.Statement, routines *[]*ast.RoutineStatement) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.RoutineStatement:
+			*routines = append(*routines, s)
+		case *ast.IncludeStatement:
+			// the label of an INCLUDE names the first statement of the included file
+			if s.Label != nil {
+				*main = append(*main, &ast.LabelStatement{Token: s.Label.Token, Name: s.Label})
+			}
+			if s.Body != nil {
+				split(s.Body.Statements, main, routines)
+			}
+		default:
+			*main = append(*main, stmt)
+		}
+	}
+}
+
+// pending is an edge whose target is the block of the next statement, not known yet
+type pending struct {
+	from  *Block
+	kind  EdgeKind
+	label string
+}
+
+// loop is a LOOP or FOR enclosing the statements being built
+type loop struct {
+	head   *Block    // the block CONTINUE and REPEAT go to
+	breaks []pending // the edges leaving the loop
+}
+
+type builder struct {
+	g       *Graph
+	cur     *Block    // the open block statements are added to, nil if the next statement starts a new one
+	pending []pending // edges to the next block
+	labels  map[string]*Block
+	placed  map[*Block]bool // label blocks reached by the statements rather than only by jumps
+	loops   []*loop
+}
+
+func build(name string, node ast.Node, stmts []ast.Statement) *Graph {
+	b := &builder{g: &Graph{Name: name, Node: node}, labels: map[string]*Block{}, placed: map[*Block]bool{}}
+	b.g.Entry = b.newBlock()
+	b.g.Exit = b.newBlock()
+	b.pending = []pending{{from: b.g.Entry, kind: Next}}
+	b.statements(stmts)
+	b.connect(b.finish(), b.g.Exit)
+
+	for _, block := range b.labels {
+		if !b.placed[block] {
+			block.External = true
+		}
+	}
+	return b.g
+}
+
+func (b *builder) newBlock() *Block {
+	block := &Block{ID: len(b.g.Blocks)}
+	b.g.Blocks = append(b.g.Blocks, block)
+	return block
+}
+
+func (b *builder) edge(from, to *Block, kind EdgeKind, label string) {
+	e := &Edge{From: from, To: to, Kind: kind, Label: label}
+	from.Succs = append(from.Succs, e)
+	to.Preds = append(to.Preds, e)
+}
+
+func (b *builder) connect(edges []pending, to *Block) {
+	for _, p := range edges {
+		b.edge(p.from, to, p.kind, p.label)
+	}
+}
+
+// labelBlock returns the block starting with an execution label, created by the first jump to it or by the label itself
+func (b *builder) labelBlock(name string) *Block {
+	key := strings.ToUpper(name)
+	if block, ok := b.labels[key]; ok {
+		return block
+	}
+	block := b.newBlock()
+	block.Label = name
+	b.labels[key] = block
+	return block
+}
+
+// label starts the block of an execution label, the open block falls through to it
+func (b *builder) label(name string) {
+	block := b.labelBlock(name)
+	b.connect(b.finish(), block)
+	b.placed[block] = true
+	b.cur = block
+}
+
+// add appends a statement to the open block, starting a new one if there is none
+func (b *builder) add(node ast.Node) *Block {
+	if b.cur == nil {
+		b.cur = b.newBlock()
+		b.connect(b.pending, b.cur)
+		b.pending = nil
+	}
+	b.cur.Nodes = append(b.cur.Nodes, node)
+	return b.cur
+}
+
+// begin puts the header of a structured statement or clause into a block of its own
+func (b *builder) begin(node ast.Node) *Block {
+	if b.cur != nil && len(b.cur.Nodes) > 0 {
+		b.pending = append(b.pending, pending{from: b.cur, kind: Next})
+		b.cur = nil
+	}
+	block := b.add(node)
+	b.cur = nil
+	return block
+}
+
+// finish closes the open block and returns the edges leaving the statements built so far
+func (b *builder) finish() []pending {
+	if b.cur != nil {
+		b.pending = append(b.pending, pending{from: b.cur, kind: Next})
+		b.cur = nil
+	}
+	edges := b.pending
+	b.pending = nil
+	return edges
+}
+
+// leave closes block after a jump; a conditional jump falls through to the next statement if its condition fails
+func (b *builder) leave(block *Block, conditional bool) {
+	b.cur = nil
+	if conditional {
+		b.pending = append(b.pending, pending{from: block, kind: False})
+	}
+}
+
+// jumpKind is the kind of the edge of a jump, True if the jump is conditional
+func jumpKind(kind EdgeKind, conditional bool) EdgeKind {
+	if conditional {
+		return True
+	}
+	return kind
+}
+
+func (b *builder) statements(stmts []ast.Statement) {
+	for i, stmt := range stmts {
+		if _, ok := stmt.(*ast.LabelStatement); ok && i+1 < len(stmts) && isDeclaration(stmts[i+1]) {
+			// a label on a line of its own names the data declared on the next line
+			continue
+		}
+		b.statement(stmt)
+	}
+}
+
+func isDeclaration(stmt ast.Statement) bool {
+	switch stmt.(type) {
+	case *ast.DataDeclaration, *ast.FileDeclaration, *ast.PointerDeclaration:
+		return true
+	}
+	return false
+}
+
+func (b *builder) statement(stmt ast.Statement) {
+	if label := ast.Label(stmt); label != nil {
+		b.label(label.Value)
+	}
+	switch s := stmt.(type) {
+	case *ast.LabelStatement:
+		b.label(s.Name.Value)
+	case *ast.DataDeclaration, *ast.FileDeclaration, *ast.PointerDeclaration, *ast.RoutineStatement:
+		// not executed
+	case *ast.IncludeStatement:
+		if s.Body != nil {
+			b.statements(s.Body.Statements)
+		}
+	case *ast.BlockStatement:
+		b.statements(s.Statements)
+	case *ast.VerbStatement:
+		b.verb(s)
+	case *ast.CallStatement:
+		block := b.add(s)
+		if s.Target != nil {
+			block.Calls = append(block.Calls, s.Target.Value)
+		}
+		b.finish()
+		b.pending = []pending{{from: block, kind: Next}}
+	case *ast.IfStatement:
+		b.ifStatement(s)
+	case *ast.LoopStatement:
+		head := b.begin(s)
+		b.pending = []pending{{from: head, kind: Next}}
+		b.loop(head, s.Body)
+	case *ast.LoopConditionStatement:
+		b.loopCondition(s)
+	case *ast.ForStatement:
+		head := b.begin(s)
+		b.pending = []pending{{from: head, kind: True}}
+		b.loop(head, s.Body)
+		b.pending = append(b.pending, pending{from: head, kind: False})
+	case *ast.SwitchStatement:
+		b.switchStatement(s)
+	default:
+		b.add(stmt)
+	}
+}
+
+// verb adds a verb to the open block, ending the block if the verb jumps
+func (b *builder) verb(s *ast.VerbStatement) {
+	conditional := s.Condition != nil
+	switch s.Verb {
+	case "GOTO":
+		block := b.add(s)
+		for _, op := range s.Operands {
+			if target, ok := op.(*ast.Identifier); ok {
+				b.edge(block, b.labelBlock(target.Value), jumpKind(Jump, conditional), "")
+			}
+		}
+		b.leave(block, conditional)
+	case "BRANCH":
+		block := b.add(s)
+		for i, op := range s.Operands {
+			if target, ok := op.(*ast.Identifier); ok && i > 0 {
+				b.edge(block, b.labelBlock(target.Value), Branch, strconv.Itoa(i))
+			}
+		}
+		// an index out of range continues with the next statement
+		b.cur = nil
+		b.pending = append(b.pending, pending{from: block, kind: Default})
+	case "RETURN":
+		block := b.add(s)
+		b.edge(block, b.g.Exit, jumpKind(Return, conditional), "")
+		b.leave(block, conditional)
+	case "STOP", "CHAIN":
+		block := b.add(s)
+		b.edge(block, b.g.Exit, jumpKind(Stop, conditional), "")
+		b.leave(block, conditional)
+	case "TRAP":
+		// the handler is entered when the event occurs, the TRAP itself continues with the next statement
+		block := b.add(s)
+		for _, op := range s.Operands {
+			if target, ok := op.(*ast.Identifier); ok {
+				b.edge(block, b.labelBlock(target.Value), Trap, "")
+			}
+		}
+	case "BREAK", "CONTINUE":
+		if len(b.loops) == 0 {
+			b.add(s)
+			return
+		}
+		l := b.loops[len(b.loops)-1]
+		block := b.add(s)
+		if s.Verb == "BREAK" {
+			l.breaks = append(l.breaks, pending{from: block, kind: jumpKind(Jump, conditional)})
+		} else {
+			b.edge(block, l.head, jumpKind(Jump, conditional), "")
+		}
+		b.leave(block, conditional)
+	default:
+		b.add(s)
+	}
+}
+
+func (b *builder) ifStatement(s *ast.IfStatement) {
+	cond := b.begin(s)
+	var joins []pending
+	b.pending = []pending{{from: cond, kind: True}}
+	b.statements(blockStatements(s.Consequence))
+	joins = append(joins, b.finish()...)
+
+	for _, clause := range s.ElseIfs {
+		b.pending = []pending{{from: cond, kind: False}}
+		cond = b.begin(clause)
+		b.pending = []pending{{from: cond, kind: True}}
+		b.statements(blockStatements(clause.Body))
+		joins = append(joins, b.finish()...)
+	}
+
+	b.pending = []pending{{from: cond, kind: False}}
+	if s.Alternative != nil {
+		b.statements(s.Alternative.Statements)
+	}
+	b.pending = append(joins, b.finish()...)
+}
+
+// loop builds the body of a LOOP or FOR starting at head, REPEAT goes back to head.
+// The breaks of the loop are left pending.
+func (b *builder) loop(head *Block, body *ast.BlockStatement) {
+	l := &loop{head: head}
+	b.loops = append(b.loops, l)
+	b.statements(blockStatements(body))
+	for _, p := range b.finish() {
+		kind := p.kind
+		if kind == Next {
+			kind = Repeat
+		}
+		b.edge(p.from, head, kind, p.label)
+	}
+	b.loops = b.loops[:len(b.loops)-1]
+	b.pending = l.breaks
+}
+
+// loopCondition builds a WHILE or UNTIL, leaving the enclosing loop if the condition says so
+func (b *builder) loopCondition(s *ast.LoopConditionStatement) {
+	block := b.begin(s)
+	stay, leave := True, False
+	if s.Verb == "UNTIL" {
+		stay, leave = False, True
+	}
+	b.pending = []pending{{from: block, kind: stay}}
+	if len(b.loops) == 0 {
+		b.pending = append(b.pending, pending{from: block, kind: leave})
+		return
+	}
+	l := b.loops[len(b.loops)-1]
+	l.breaks = append(l.breaks, pending{from: block, kind: leave})
+}
+
+func (b *builder) switchStatement(s *ast.SwitchStatement) {
+	head := b.begin(s)
+	var joins []pending
+	for _, clause := range s.Cases {
+		values := make([]string, len(clause.Values))
+		for i, v := range clause.Values {
+			values[i] = v.String()
+		}
+		b.pending = []pending{{from: head, kind: Case, label: strings.Join(values, ",")}}
+		b.statements(blockStatements(clause.Body))
+		joins = append(joins, b.finish()...)
+	}
+	b.pending = []pending{{from: head, kind: Default}}
+	if s.Default != nil {
+		b.statements(s.Default.Statements)
+	}
+	b.pending = append(joins, b.finish()...)
+}
+
+func blockStatements(block *ast.BlockStatement) []ast.Statement {
+	if block == nil {
+		return nil
+	}
+	return block.Statements
+}
@@ -0,0 +1,207 @@
+package cfg
+
+import (
+	"PLB-Interpreter/parser"
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, input string) []*Graph {
+	t.Helper()
+	prog, diag := parser.ParseReader(strings.NewReader(input), "test", parser.Options{})
+	if len(diag.Errors) > 0 {
+		t.Fatalf("parser errors: %v", diag.Errors)
+	}
+	return Build(prog)
+}
+
+// edges describes the edges of a graph sorted, e.g. "ENTRY -> TOP" or "TOP -true-> DONE".
+// A block is named by its label or its first statement.
+func edges(g *Graph) []string {
+	var out []string
+	for _, block := range g.Blocks {
+		for _, e := range block.Succs {
+			arrow := " -> "
+			if e.Kind != Next {
+				arrow = " -" + e.Kind.String() + "-> "
+				if e.Label != "" {
+					arrow = " -" + e.Kind.String() + " " + e.Label + "-> "
+				}
+			}
+			out = append(out, name(g, e.From)+arrow+name(g, e.To))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func name(g *Graph, block *Block) string {
+	switch {
+	case block == g.Entry:
+		return "ENTRY"
+	case block == g.Exit:
+		return "EXIT"
+	case block.Label != "":
+		return block.Label
+	case len(block.Nodes) > 0:
+		return Text(block.Nodes[0])
+	}
+	return "?"
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "straight line",
+			input: "A DIM 1\n    MOVE \"X\" TO A\n    DISPLAY A\n",
+			want:  []string{"ENTRY -> MOVE \"X\" TO A", "MOVE \"X\" TO A -> EXIT"},
+		},
+		{
+			name:  "labels start blocks",
+			input: "    DISPLAY \"A\"\nTOP DISPLAY \"B\"\nNEXT\n    STOP\n",
+			want:  []string{"ENTRY -> DISPLAY \"A\"", "DISPLAY \"A\" -> TOP", "TOP -> NEXT", "NEXT -stop-> EXIT"},
+		},
+		{
+			name:  "GOTO and conditional GOTO",
+			input: "TOP\n    GOTO DONE IF EQUAL\n    GOTO TOP\n    DISPLAY \"DEAD\"\nDONE STOP\n",
+			want: []string{
+				"ENTRY -> TOP", "TOP -true-> DONE", "TOP -false-> GOTO TOP", "GOTO TOP -goto-> TOP",
+				"DISPLAY \"DEAD\" -> DONE", "DONE -stop-> EXIT",
+			},
+		},
+		{
+			name:  "BRANCH",
+			input: "N FORM 1\n    BRANCH N OF ONE,TWO\n    STOP\nONE STOP\nTWO STOP\n",
+			want: []string{
+				"ENTRY -> BRANCH N OF ONE,TWO", "BRANCH N OF ONE,TWO -branch 1-> ONE", "BRANCH N OF ONE,TWO -branch 2-> TWO",
+				"BRANCH N OF ONE,TWO -default-> STOP", "STOP -stop-> EXIT", "ONE -stop-> EXIT", "TWO -stop-> EXIT",
+			},
+		},
+		{
+			name:  "CALL ends its block",
+			input: "    CALL SUB\n    STOP\nSUB ROUTINE\n    RETURN IF EQUAL\n    DISPLAY \"X\"\n    RETURN\n",
+			want:  []string{"ENTRY -> CALL SUB", "CALL SUB -> STOP", "STOP -stop-> EXIT"},
+		},
+		{
+			name:  "IF ELSEIF ELSE",
+			input: "    IF EQUAL\n    DISPLAY \"1\"\n    ELSEIF LESS\n    DISPLAY \"2\"\n    ELSE\n    DISPLAY \"3\"\n    ENDIF\n    STOP\n",
+			want: []string{
+				"ENTRY -> IF EQUAL", "IF EQUAL -true-> DISPLAY \"1\"", "IF EQUAL -false-> ELSEIF LESS",
+				"DISPLAY \"1\" -> STOP", "ELSEIF LESS -true-> DISPLAY \"2\"", "ELSEIF LESS -false-> DISPLAY \"3\"",
+				"DISPLAY \"2\" -> STOP", "DISPLAY \"3\" -> STOP", "STOP -stop-> EXIT",
+			},
+		},
+		{
+			name:  "IF without ELSE",
+			input: "    IF EQUAL\n    DISPLAY \"1\"\n    ENDIF\n",
+			want:  []string{"ENTRY -> IF EQUAL", "IF EQUAL -true-> DISPLAY \"1\"", "IF EQUAL -false-> EXIT", "DISPLAY \"1\" -> EXIT"},
+		},
+		{
+			name:  "LOOP with WHILE, BREAK and CONTINUE",
+			input: "    LOOP\n    WHILE EQUAL\n    BREAK IF LESS\n    CONTINUE IF OVER\n    DISPLAY \"X\"\n    REPEAT\n    STOP\n",
+			want: []string{
+				"ENTRY -> LOOP", "LOOP -> WHILE EQUAL", "WHILE EQUAL -true-> BREAK IF LESS", "WHILE EQUAL -false-> STOP",
+				"BREAK IF LESS -true-> STOP", "BREAK IF LESS -false-> CONTINUE IF OVER",
+				"CONTINUE IF OVER -true-> LOOP", "CONTINUE IF OVER -false-> DISPLAY \"X\"", "DISPLAY \"X\" -repeat-> LOOP",
+				"STOP -stop-> EXIT",
+			},
+		},
+		{
+			name:  "UNTIL leaves when the condition holds",
+			input: "    LOOP\n    UNTIL EQUAL\n    REPEAT\n",
+			want:  []string{"ENTRY -> LOOP", "LOOP -> UNTIL EQUAL", "UNTIL EQUAL -false-> LOOP", "UNTIL EQUAL -true-> EXIT"},
+		},
+		{
+			name:  "FOR",
+			input: "I FORM 2\n    FOR I FROM 1 TO 3\n    DISPLAY I\n    REPEAT\n",
+			want: []string{
+				"ENTRY -> FOR I FROM 1 TO 3", "FOR I FROM 1 TO 3 -true-> DISPLAY I", "FOR I FROM 1 TO 3 -false-> EXIT",
+				"DISPLAY I -repeat-> FOR I FROM 1 TO 3",
+			},
+		},
+		{
+			name:  "SWITCH",
+			input: "N FORM 1\n    SWITCH N\n    CASE 1,2\n    DISPLAY \"A\"\n    CASE 3\n    DEFAULT\n    DISPLAY \"C\"\n    ENDSWITCH\n",
+			want: []string{
+				"ENTRY -> SWITCH N", "SWITCH N -case 1,2-> DISPLAY \"A\"", "SWITCH N -case 3-> EXIT", "SWITCH N -default-> DISPLAY \"C\"",
+				"DISPLAY \"A\" -> EXIT", "DISPLAY \"C\" -> EXIT",
+			},
+		},
+		{
+			name:  "TRAP",
+			input: "    TRAP FAILED IF RANGE\n    STOP\nFAILED\n    STOP\n",
+			want: []string{
+				"ENTRY -> TRAP FAILED IF RANGE", "TRAP FAILED IF RANGE -trap-> FAILED", "TRAP FAILED IF RANGE -stop-> EXIT",
+				"FAILED -stop-> EXIT",
+			},
+		},
+		{
+			name:  "GOTO out of the graph",
+			input: "    GOTO ELSEWHERE\nSUB ROUTINE\nELSEWHERE RETURN\n",
+			want:  []string{"ENTRY -> GOTO ELSEWHERE", "GOTO ELSEWHERE -goto-> ELSEWHERE"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := edges(parse(t, tt.input)[0])
+			sort.Strings(tt.want)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("edges are\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestBuild_Routines(t *testing.T) {
+	graphs := parse(t, "    CALL SUB\n    STOP\nSUB ROUTINE\n    RETURN IF EQUAL\n    GOTO OUT\n    DISPLAY \"X\"\nLOC LROUTINE\nOUT RETURN\n")
+	if len(graphs) != 3 || graphs[0].Name != MainName || graphs[1].Name != "SUB" || graphs[2].Name != "LOC" {
+		t.Fatalf("got %d graphs, want MAIN, SUB and LOC", len(graphs))
+	}
+	sub := graphs[1]
+	want := []string{
+		"ENTRY -> RETURN IF EQUAL", "RETURN IF EQUAL -true-> EXIT", "RETURN IF EQUAL -false-> GOTO OUT",
+		"GOTO OUT -goto-> OUT", "DISPLAY \"X\" -> EXIT",
+	}
+	sort.Strings(want)
+	if got := edges(sub); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("edges of SUB are\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	for _, block := range sub.Blocks {
+		if block.Label == "OUT" && !block.External {
+			t.Error("OUT is declared by LOC but not external to SUB")
+		}
+		if len(block.Nodes) > 0 && Text(block.Nodes[0]) == "DISPLAY \"X\"" && len(block.Preds) != 0 {
+			t.Error("the DISPLAY after the GOTO is reachable")
+		}
+	}
+	if calls := graphs[0].Blocks[2].Calls; len(calls) != 1 || calls[0] != "SUB" {
+		t.Errorf("the CALL block calls %v, want SUB", calls)
+	}
+}
+
+func TestGraph_WriteDOT(t *testing.T) {
+	g := parse(t, "TOP\n    DISPLAY \"SAY \"\"HI\"\"\"\n    CALL SUB\n    GOTO TOP IF EQUAL\nSUB ROUTINE\n    RETURN\n")[0]
+	var out bytes.Buffer
+	if err := g.WriteDOT(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`digraph "MAIN" {`,
+		`b0 [label="ENTRY", shape=oval];`,
+		`b2 [label="TOP:\lDISPLAY \"SAY \"\"HI\"\"\"\lCALL SUB\l"];`,
+		`"call SUB" [label="SUB", shape=ellipse, style=dashed];`,
+		`b2 -> "call SUB" [style=dashed];`,
+		`b3 -> b2 [label="true"];`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("DOT output does not contain %s:\n%s", want, out.String())
+		}
+	}
+}
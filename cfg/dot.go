@@ -0,0 +1,110 @@
+package cfg
+
+import (
+	"PLB-Interpreter/ast"
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteDOT writes the graph in the Graphviz DOT language, e.g. for dot -Tsvg.
+// Every block is a box listing its statements, the edges are labelled with their kind unless they fall through.
+// Routines called by a block are drawn as dashed ellipses.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "digraph %s {\n", dotQuote(g.Name))
+	fmt.Fprintln(out, `  node [shape=box, fontname="monospace"];`)
+
+	calls := map[string]bool{}
+	for _, block := range g.Blocks {
+		switch {
+		case block == g.Entry:
+			fmt.Fprintf(out, "  b%d [label=\"ENTRY\", shape=oval];\n", block.ID)
+		case block == g.Exit:
+			fmt.Fprintf(out, "  b%d [label=\"EXIT\", shape=oval];\n", block.ID)
+		case block.External:
+			fmt.Fprintf(out, "  b%d [label=%s, style=dashed];\n", block.ID, dotQuote(block.Label))
+		default:
+			fmt.Fprintf(out, "  b%d [label=\"%s\"];\n", block.ID, blockLabel(block))
+		}
+		for _, call := range block.Calls {
+			calls[strings.ToUpper(call)] = true
+		}
+	}
+	for _, block := range g.Blocks {
+		for _, call := range block.Calls {
+			if calls[strings.ToUpper(call)] {
+				fmt.Fprintf(out, "  %s [label=%s, shape=ellipse, style=dashed];\n", callNode(call), dotQuote(call))
+				calls[strings.ToUpper(call)] = false
+			}
+		}
+	}
+
+	for _, block := range g.Blocks {
+		for _, e := range block.Succs {
+			fmt.Fprintf(out, "  b%d -> b%d", e.From.ID, e.To.ID)
+			if label := edgeLabel(e); label != "" {
+				fmt.Fprintf(out, " [label=%s]", dotQuote(label))
+			}
+			fmt.Fprintln(out, ";")
+		}
+		for _, call := range block.Calls {
+			fmt.Fprintf(out, "  b%d -> %s [style=dashed];\n", block.ID, callNode(call))
+		}
+	}
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+// blockLabel lists the label and the statements of a block as left-justified lines
+func blockLabel(block *Block) string {
+	var lines []string
+	if block.Label != "" {
+		lines = append(lines, block.Label+":")
+	}
+	for _, node := range block.Nodes {
+		lines = append(lines, Text(node))
+	}
+	var out strings.Builder
+	for _, line := range lines {
+		out.WriteString(dotEscape(line) + `\l`)
+	}
+	return out.String()
+}
+
+func edgeLabel(e *Edge) string {
+	switch e.Kind {
+	case Next:
+		return ""
+	case Branch:
+		return e.Label
+	case Case:
+		return "CASE " + e.Label
+	}
+	return e.Kind.String()
+}
+
+// Text returns the statement a block node stands for on one line, without its label.
+// A structured statement is given by its header, e.g. IF (A = 1).
+func Text(node ast.Node) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(node.String()), "\n")
+	if stmt, ok := node.(ast.Statement); ok {
+		if label := ast.Label(stmt); label != nil {
+			line = strings.TrimSpace(strings.TrimPrefix(line, label.Value))
+		}
+	}
+	return strings.TrimSpace(line)
+}
+
+func callNode(name string) string {
+	return dotQuote("call " + strings.ToUpper(name))
+}
+
+func dotQuote(s string) string {
+	return `"` + dotEscape(s) + `"`
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
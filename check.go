@@ -0,0 +1,38 @@
+package main
+
+import (
+	"PLB-Interpreter/checker"
+	"PLB-Interpreter/parser"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/resolver"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// checkCommand reports the compile time errors of a file: parser errors, undeclared or duplicate labels found by
+// the resolver, and calls or operands that do not match their declarations. The exit status is 1 if there are any.
+func checkCommand(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	dialect := flags.String("dialect", "", "PL/B dialect, SUNBELT or ANSI")
+	defs := defineFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	opts, env := resolveOptions(*dialect, defs)
+	prog, diag := parser.ParseFile(sourcePath(flags.Args()), opts)
+	for _, err := range diag.Errors {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	var checkErrors plbErrors.Collector
+	table := resolver.New(resolver.WithEnvironment(env), resolver.WithDiagnostics(&checkErrors)).Resolve(prog)
+	checker.New(checker.WithSymbols(table), checker.WithDiagnostics(&checkErrors)).Check(prog)
+	for _, err := range checkErrors.Errors {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if len(diag.Errors) > 0 || len(checkErrors.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
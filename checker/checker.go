@@ -0,0 +1,97 @@
+package checker
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/resolver"
+	"PLB-Interpreter/symbols"
+	"PLB-Interpreter/tokens"
+	"fmt"
+)
+
+// Checker performs the compile time checks that need the whole program, e.g. matching CALL arguments
+// against the parameters of the called routine. Declarations are checked by the resolver, whose table
+// the checker looks names up in.
+type Checker struct {
+	errors []error
+	diag   plbErrors.Diagnostics
+
+	table *symbols.Table // the declarations calls and operands are checked against
+}
+
+// Option configures a Checker, see New
+type Option func(*Checker)
+
+// WithDiagnostics reports every error found by the checker to d, in addition to Errors
+func WithDiagnostics(d plbErrors.Diagnostics) Option {
+	return func(c *Checker) {
+		c.diag = d
+	}
+}
+
+// WithSymbols checks calls and the operands of verbs against a table the program has already been resolved to.
+// Without it Check resolves the program itself, without reporting the errors of the resolver.
+func WithSymbols(table *symbols.Table) Option {
+	return func(c *Checker) {
+		c.table = table
+	}
+}
+
+// New creates a checker, the checks are run by Check
+func New(opts ...Option) *Checker {
+	c := &Checker{diag: plbErrors.Discard}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Errors returns true if there are any errors indicated in the checker
+// If the boolean is true, the slice of errors will be non-empty
+// If the boolean is false, the slice of errors will be empty
+func (c *Checker) Errors() (bool, []error) {
+	return len(c.errors) > 0, c.errors
+}
+
+func (c *Checker) addError(tok tokens.Token, code, msg string) {
+	newErr := plbErrors.NewPLBError(code, msg, tok.FileName, tok.Line, tok.Col, tok.LineTxt)
+	c.errors = append(c.errors, newErr)
+	c.diag.Error(newErr)
+}
+
+// Check runs every check on the given program
+func (c *Checker) Check(prog *ast.Program) {
+	if c.table == nil {
+		c.table = resolver.New().Resolve(prog)
+	}
+	ast.Inspect(prog, func(node ast.Node) bool {
+		if call, ok := node.(*ast.CallStatement); ok {
+			c.checkCall(call)
+		}
+		return true
+	})
+	c.checkTypes(prog.Statements)
+}
+
+// checkCall verifies that the number of arguments of a call matches the parameters of the called routine.
+// Calls of labels not declared in the program cannot be checked here.
+func (c *Checker) checkCall(call *ast.CallStatement) {
+	sym, ok := c.table.Uses[call.Target]
+	if !ok {
+		return
+	}
+	switch sym.Kind {
+	case symbols.Routine:
+		routine := sym.Node.(*ast.RoutineStatement)
+		if len(call.Args) != len(routine.Params) {
+			c.addError(call.Token, plbErrors.ErrArgumentCount, fmt.Sprintf("%s %s at %s %d:%d takes %d arguments, got %d",
+				routine.Kind, routine.Name.Value, routine.Token.FileName, routine.Token.Line, routine.Token.Col,
+				len(routine.Params), len(call.Args)))
+		}
+	case symbols.Label:
+		if len(call.Args) > 0 {
+			c.addError(call.Token, plbErrors.ErrArgumentCount, fmt.Sprintf("%s is not a ROUTINE and takes no arguments, got %d",
+				call.Target.Value, len(call.Args)))
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package checker
+
+import (
+	"PLB-Interpreter/lexer"
+	"PLB-Interpreter/parser"
+	"PLB-Interpreter/plbErrors"
+	"bufio"
+	"strings"
+	"testing"
+)
+
+// check parses and checks the given input, parser errors fail the test
+func check(t *testing.T, input string) []error {
+	t.Helper()
+	p := parser.New(lexer.New(bufio.NewReader(strings.NewReader(input)), "test"))
+	prog := p.ParseProgram()
+	if has, errs := p.Errors(); has {
+		t.Fatalf("parser errors: %v", errs)
+	}
+	c := New()
+	c.Check(prog)
+	_, errs := c.Errors()
+	return errs
+}
+
+func TestChecker_CallArguments(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		codes []string
+	}{
+		{
+			name:  "matching arguments",
+			input: "    CALL SUB USING A,B\n    CALL NONE\nSUB ROUTINE X,Y\n    RETURN\nNONE ROUTINE\n    RETURN\n",
+		},
+		{
+			name:  "too few arguments",
+			input: "    CALL SUB USING A\nSUB ROUTINE X,Y\n    RETURN\n",
+			codes: []string{plbErrors.ErrArgumentCount},
+		},
+		{
+			name:  "too many arguments to an LROUTINE",
+			input: "SUB LROUTINE\n    RETURN\n    CALL SUB USING A\n",
+			codes: []string{plbErrors.ErrArgumentCount},
+		},
+		{
+			name:  "arguments to a plain label",
+			input: "TOP\n    CALL TOP USING A\n",
+			codes: []string{plbErrors.ErrArgumentCount},
+		},
+		{
+			name:  "call inside a block",
+			input: "    IF EQUAL\n    CALL SUB\n    ENDIF\nSUB ROUTINE X\n    RETURN\n",
+			codes: []string{plbErrors.ErrArgumentCount},
+		},
+		{
+			name:  "unknown label is not checked",
+			input: "    CALL EXTERNAL USING A\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertCodes(t, check(t, tt.input), tt.codes)
+		})
+	}
+}
+
+func TestChecker_OperandTypes(t *testing.T) {
+	decls := "C DIM 10\nN FORM 3\nI INIT \"X\"\nK EQU 5\nF FILE\nX IFILE\nPR PFILE\nCP DIM ^\nNP FORM ^\nAP VAR @\n" +
+		"CL LIST\nC1 DIM 2\nC2 DIM 3\n    LISTEND\nML LIST\nM1 DIM 2\nM2 FORM 3\n    LISTEND\n"
+	tests := []struct {
+		name  string
+		input string
+		codes []string
+	}{
+		{
+			name:  "matching classes",
+			input: "    ADD N TO N\n    APPEND C TO I\n    MOVE N TO C\n    MOVE C TO N\n    READ X,C,C,N\n    CLOSE PR\n    ADD K TO N\n",
+		},
+		{
+			name:  "character added",
+			input: "    ADD C TO N\n",
+			codes: []string{plbErrors.ErrNumericOperand},
+		},
+		{
+			name:  "numeric destination of APPEND",
+			input: "    APPEND C TO N\n",
+			codes: []string{plbErrors.ErrCharacterOperand},
+		},
+		{
+			name:  "READ from a data variable",
+			input: "    READ C,N,C\n",
+			codes: []string{plbErrors.ErrFileOperand},
+		},
+		{
+			name:  "READ from a print file",
+			input: "    READ PR,N,C\n",
+			codes: []string{plbErrors.ErrFileOperand},
+		},
+		{
+			name:  "file moved",
+			input: "    MOVE F TO C\n",
+			codes: []string{plbErrors.ErrOperandKind},
+		},
+		{
+			name:  "constant as destination",
+			input: "    ADD N TO K\n",
+			codes: []string{plbErrors.ErrConstantOperand},
+		},
+		{
+			name:  "MOVE converts numeric literals only",
+			input: "    MOVE \"12.5\" TO N\n    MOVE \"ABC\" TO N\n    MOVE \"ABC\" TO C\n",
+			codes: []string{plbErrors.ErrConversion},
+		},
+		{
+			name:  "pointers refer to their kind of variable",
+			input: "    MOVEADR C TO CP\n    MOVEADR N TO NP\n    MOVEADR N TO AP\n    MOVEPTR CP TO AP\n    MOVEADR N TO CP\n    MOVEPTR NP TO CP\n",
+			codes: []string{plbErrors.ErrPointerOperand, plbErrors.ErrPointerOperand},
+		},
+		{
+			name:  "data where a pointer is needed",
+			input: "    MOVEADR C TO N\n",
+			codes: []string{plbErrors.ErrPointerOperand},
+		},
+		{
+			name:  "dereferenced pointers have the class they point to",
+			input: "    ADD NP TO N\n    ADD CP TO N\n",
+			codes: []string{plbErrors.ErrNumericOperand},
+		},
+		{
+			name:  "character lists",
+			input: "    MOVE CL TO C\n    READ X,C,CL\n    READ X,C,ML\n    MOVE ML TO C\n",
+			codes: []string{plbErrors.ErrOperandKind},
+		},
+		{
+			name:  "lists, calls and loops",
+			input: "    DISPLAY *P=C:1,F\n    CALL S USING F\n    FOR C FROM 1 TO N\n    REPEAT\nS ROUTINE N\n    RETURN\n",
+			codes: []string{plbErrors.ErrNumericOperand, plbErrors.ErrOperandKind, plbErrors.ErrOperandKind, plbErrors.ErrNumericOperand},
+		},
+		{
+			name:  "undeclared names are left to the resolver",
+			input: "    ADD U TO N\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertCodes(t, check(t, decls+tt.input), tt.codes)
+		})
+	}
+}
+
+// assertCodes compares the codes of the given errors with the expected ones
+func assertCodes(t *testing.T, errs []error, codes []string) {
+	t.Helper()
+	if len(errs) != len(codes) {
+		t.Fatalf("got %d errors %v, want %d", len(errs), errs, len(codes))
+	}
+	for i, err := range errs {
+		if got := err.(*plbErrors.PLBError).ErrorCode; got != codes[i] {
+			t.Errorf("error %d: got %s (%s), want %s", i, got, err, codes[i])
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package checker
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/parser"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/symbols"
+	"PLB-Interpreter/tokens"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The operand classes checked against the declarations of the operands, see Operand in the parser.
+// A constant label, e.g. declared by EQU, has the class EQUATELABEL.
+var (
+	numericClasses = classSet(tokens.NVAR, tokens.SIMPLENVAR, tokens.NVARARRAY, tokens.NUMERICLITERAL, tokens.NUMERICCONSTANT,
+		tokens.DNUM, tokens.SIGNEDDNUM, tokens.ONUM, tokens.XNUM, tokens.DOXNUM)
+	characterClasses = classSet(tokens.CVAR, tokens.CVARARRAY, tokens.CVARLISTVAR, tokens.LITERAL, tokens.SINGLECHARLITERAL)
+	fileClasses      = classSet(tokens.FILE, tokens.IFILE, tokens.AFILE, tokens.PFILE)
+	pointerClasses   = classSet(tokens.POINTERVAR)
+	constantClasses  = classSet(tokens.EQUATELABEL, tokens.NUMERICLITERAL, tokens.NUMERICCONSTANT,
+		tokens.DNUM, tokens.SIGNEDDNUM, tokens.ONUM, tokens.XNUM, tokens.DOXNUM)
+
+	// untyped are the classes of operands that do not name data, they are checked by the parser and the resolver
+	untyped = classSet(tokens.EXECUTIONLABEL, tokens.IDENT, tokens.PREP)
+
+	// forCounter and forValue are the operand classes of the counter and of the bounds and step of a FOR
+	forCounter = parser.Operand{tokens.NVAR}
+	forValue   = parser.Operand{tokens.NVAR, tokens.NUMERICLITERAL, tokens.DNUM}
+)
+
+func classSet(classes ...tokens.TokenType) map[tokens.TokenType]bool {
+	set := map[tokens.TokenType]bool{}
+	for _, class := range classes {
+		set[class] = true
+	}
+	return set
+}
+
+// typed is an operand naming declared data, with the operand class of its declaration
+type typed struct {
+	class tokens.TokenType
+	sym   *symbols.Symbol
+	ident *ast.Identifier // the identifier in the operand naming sym
+}
+
+// checkTypes checks the operands of the verbs in stmts against the declarations of the data they name
+func (c *Checker) checkTypes(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.DataDeclaration:
+			if spec, ok := parser.LookupVerb(s.Kind); ok {
+				for i, op := range s.Operands {
+					c.checkOperand(s.Token, i, spec.OperandAt(i), op)
+				}
+			}
+		case *ast.VerbStatement:
+			c.checkVerb(s)
+		case *ast.CallStatement:
+			spec := parser.Verbs["CALL"]
+			for i, arg := range s.Args {
+				c.checkOperand(s.Token, i+1, spec.OperandAt(i+1), arg)
+			}
+		case *ast.ListStatement:
+			spec, _ := parser.LookupVerb(s.Verb)
+			for i, item := range s.Items {
+				if item == nil {
+					continue
+				}
+				if item.Value != nil {
+					c.checkOperand(s.Token, i, spec.OperandAt(i), item.Value)
+				}
+				for _, arg := range item.Args {
+					c.checkOperand(s.Token, i, parser.ControlArg, arg)
+				}
+			}
+		case *ast.ForStatement:
+			c.checkOperand(s.Token, 0, forCounter, s.Variable)
+			for i, value := range []ast.Expression{s.From, s.To, s.By} {
+				c.checkOperand(s.Token, i+1, forValue, value)
+			}
+		case *ast.RoutineStatement:
+			c.checkTypes(s.Body.Statements)
+		default:
+			ast.Blocks(stmt, func(block *ast.BlockStatement) {
+				c.checkTypes(block.Statements)
+			})
+		}
+	}
+}
+
+// checkVerb checks the operands of a verb against its operand positions and the rules of MOVE and the pointer verbs
+func (c *Checker) checkVerb(s *ast.VerbStatement) {
+	spec, ok := parser.LookupVerb(s.Verb)
+	if !ok {
+		return
+	}
+	for i, op := range s.Operands {
+		if !c.checkOperand(s.Token, i, spec.OperandAt(i), op) {
+			return
+		}
+	}
+	if len(s.Operands) != 2 {
+		return
+	}
+	switch s.Verb {
+	case "MOVE":
+		c.checkMove(s.Operands[0], s.Operands[1])
+	case "MOVEADR", "MOVEPTR":
+		c.checkPointer(s.Verb, s.Operands[0], s.Operands[1])
+	}
+}
+
+// checkOperand reports an operand naming data whose class is not one of the classes of its operand position.
+// The error code tells what the verb needs, e.g. a number or a file.
+func (c *Checker) checkOperand(verb tokens.Token, i int, pos parser.Operand, expr ast.Expression) bool {
+	op, ok := c.typeOf(expr)
+	if !ok || len(pos) == 0 || accepts(pos, op.class) {
+		return true
+	}
+	code := plbErrors.ErrOperandKind
+	switch {
+	case op.class == tokens.EQUATELABEL:
+		code = plbErrors.ErrConstantOperand
+	case within(pos, numericClasses):
+		code = plbErrors.ErrNumericOperand
+	case within(pos, characterClasses):
+		code = plbErrors.ErrCharacterOperand
+	case within(pos, fileClasses):
+		code = plbErrors.ErrFileOperand
+	case within(pos, pointerClasses):
+		code = plbErrors.ErrPointerOperand
+	}
+	c.addError(op.ident.Token, code, fmt.Sprintf("operand %d of %s must be %s, %s",
+		i+1, strings.ToUpper(verb.Literal), pos, describe(op)))
+	return false
+}
+
+// checkMove reports a character literal moved to a numeric variable that is not a number
+func (c *Checker) checkMove(src, dst ast.Expression) {
+	lit, ok := src.(*ast.StringLiteral)
+	if !ok {
+		return
+	}
+	if op, ok := c.typeOf(dst); ok && op.class == tokens.NVAR {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(lit.Value), 64); err != nil {
+			c.addError(lit.Token, plbErrors.ErrConversion, fmt.Sprintf("%s cannot be moved to a number, %s",
+				ast.Quote(lit.Value), describe(op)))
+		}
+	}
+}
+
+// checkPointer reports a pointer made to refer to a variable of the wrong kind by MOVEADR or MOVEPTR.
+// A VAR @ pointer may refer to any variable.
+func (c *Checker) checkPointer(verb string, src, dst ast.Expression) {
+	to, ok := c.typeOf(dst)
+	if !ok {
+		return
+	}
+	want := pointerKind(to.sym)
+	if want == "" || want == "VAR" {
+		return
+	}
+	from, ok := c.typeOf(src)
+	if !ok {
+		return
+	}
+	got := ""
+	if verb == "MOVEPTR" {
+		got = pointerKind(from.sym)
+	} else if from.class == tokens.CVAR {
+		got = "DIM"
+	} else if from.class == tokens.NVAR {
+		got = "FORM"
+	}
+	if got != "" && got != "VAR" && got != want {
+		c.addError(from.ident.Token, plbErrors.ErrPointerOperand, fmt.Sprintf("%s cannot make %s refer to %s",
+			verb, describe(to), describe(from)))
+	}
+}
+
+// typeOf returns the class of an operand naming declared data, false for literals and names that are not resolved
+func (c *Checker) typeOf(expr ast.Expression) (typed, bool) {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		sym, ok := c.table.Uses[e]
+		if !ok {
+			return typed{}, false
+		}
+		class := c.symbolClass(sym)
+		return typed{class: class, sym: sym, ident: e}, class != ""
+	case *ast.IndexExpression:
+		return c.typeOf(e.Left)
+	case *ast.DerefExpression:
+		sym, ok := c.table.Uses[e.Pointer]
+		if !ok {
+			return typed{}, false
+		}
+		var class tokens.TokenType
+		switch pointerKind(sym) {
+		case "DIM":
+			class = tokens.CVAR
+		case "FORM":
+			class = tokens.NVAR
+		}
+		return typed{class: class, sym: sym, ident: e.Pointer}, class != ""
+	}
+	return typed{}, false
+}
+
+// symbolClass returns the operand class of a data symbol, or "" if it has none, e.g. for a define
+func (c *Checker) symbolClass(sym *symbols.Symbol) tokens.TokenType {
+	switch decl := sym.Node.(type) {
+	case *ast.DataDeclaration:
+		switch decl.Kind {
+		case "DIM", "INIT":
+			return tokens.CVAR
+		case "FORM":
+			return tokens.NVAR
+		case "EQU", "EQUATE":
+			return tokens.EQUATELABEL
+		case "LIST", "RECORD":
+			// a list of character variables only can be used like one
+			if scope, ok := c.table.Scopes[decl]; ok {
+				for _, member := range scope.Symbols() {
+					if member.Kind.Space() == symbols.DataSpace && c.symbolClass(member) != tokens.CVAR {
+						return tokens.LISTVAR
+					}
+				}
+			}
+			return tokens.CVARLISTVAR
+		}
+	case *ast.FileDeclaration:
+		return tokens.TokenType(decl.Kind)
+	case *ast.PointerDeclaration:
+		return tokens.POINTERVAR
+	}
+	return ""
+}
+
+// accepts returns true if an operand of the given class may stand at the operand position
+func accepts(pos parser.Operand, class tokens.TokenType) bool {
+	for _, want := range pos {
+		switch {
+		case want == class, untyped[want]:
+			return true
+		case want == tokens.VARLABEL && class != tokens.EQUATELABEL:
+			return true
+		case (want == tokens.CVAR || want == tokens.LISTVAR) && class == tokens.CVARLISTVAR:
+			return true
+		case class == tokens.EQUATELABEL && constantClasses[want]:
+			return true
+		}
+	}
+	return false
+}
+
+// within returns true if every class of the operand position is in the set
+func within(pos parser.Operand, set map[tokens.TokenType]bool) bool {
+	for _, class := range pos {
+		if !set[class] {
+			return false
+		}
+	}
+	return true
+}
+
+// pointerKind returns DIM, FORM or VAR for a pointer symbol, "" for any other symbol
+func pointerKind(sym *symbols.Symbol) string {
+	if decl, ok := sym.Node.(*ast.PointerDeclaration); ok {
+		return decl.Kind
+	}
+	return ""
+}
+
+// describe names an operand and its declaration for messages, e.g. A is declared by DIM at main.pls 1:1
+func describe(op typed) string {
+	tok := op.sym.Token
+	return fmt.Sprintf("%s is declared by %s at %s %d:%d", op.ident.Value, op.sym.Verb(), tok.FileName, tok.Line, tok.Col)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"PLB-Interpreter/format"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// formatCommand formats files, printing the result, rewriting the files with -w,
+// or with -check listing the files that are not formatted and failing if there are any.
+func formatCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	check := flags.Bool("check", false, "list the files that are not formatted and exit 1 if there are any")
+	write := flags.Bool("w", false, "write the result to the files instead of printing it")
+	verbCase := flags.String("case", "upper", "case of verbs, upper, lower or keep")
+	verbColumn := flags.Int("verb-col", format.DefaultConfig.VerbColumn, "column of verbs")
+	operandColumn := flags.Int("operand-col", format.DefaultConfig.OperandColumn, "column of operands")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	cfg := format.Config{VerbColumn: *verbColumn, OperandColumn: *operandColumn}
+	var err error
+	if cfg.VerbCase, err = format.ParseCase(*verbCase); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{sourcePath(nil)}
+	}
+	status := 0
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+		out, err := format.Source(src, file, cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+		switch {
+		case *check:
+			if !bytes.Equal(src, out) {
+				fmt.Println(file)
+				status = 1
+			}
+		case *write:
+			if bytes.Equal(src, out) {
+				continue
+			}
+			if err := os.WriteFile(file, out, 0o644); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				status = 1
+			}
+		default:
+			os.Stdout.Write(out)
+		}
+	}
+	return status
+}
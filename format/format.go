@@ -0,0 +1,268 @@
+// Package format lays out PL/B source in columns, like gofmt does for Go.
+//
+// Formatting works line by line on the lexer's tokens: a label starts in column 1,
+// the verb starts in the verb column and the operands in the operand column.
+// Blanks around commas in the operands are removed and other runs of blanks become one.
+// Comment lines, i.e. lines starting with '.', '*' or '+', and lines the formatter does not
+// understand are left as they are.
+package format
+
+import (
+	"PLB-Interpreter/lexer"
+	"PLB-Interpreter/tokens"
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Case is how the formatter writes verbs
+type Case int
+
+const (
+	KeepCase  Case = iota // verbs are written as in the source
+	UpperCase             // verbs are written in upper case, e.g. MOVE
+	LowerCase             // verbs are written in lower case, e.g. move
+)
+
+// ParseCase returns the Case named keep, upper or lower
+func ParseCase(name string) (Case, error) {
+	switch strings.ToLower(name) {
+	case "keep":
+		return KeepCase, nil
+	case "upper":
+		return UpperCase, nil
+	case "lower":
+		return LowerCase, nil
+	}
+	return KeepCase, fmt.Errorf("unknown case %q, want keep, upper or lower", name)
+}
+
+// Config is the layout written by Source. Columns are 1-based.
+type Config struct {
+	VerbColumn    int  // the column of the verb, at least 2 so an unlabelled verb is not read as a label
+	OperandColumn int  // the column of the first operand, after the verb column
+	VerbCase      Case // how verbs are written
+}
+
+// DefaultConfig is the layout of the examples: verbs in column 9, operands in column 17, upper case verbs
+var DefaultConfig = Config{VerbColumn: 9, OperandColumn: 17, VerbCase: UpperCase}
+
+func (c Config) validate() error {
+	if c.VerbColumn < 2 {
+		return fmt.Errorf("verb column %d has to be at least 2", c.VerbColumn)
+	}
+	if c.OperandColumn <= c.VerbColumn {
+		return fmt.Errorf("operand column %d has to be after the verb column %d", c.OperandColumn, c.VerbColumn)
+	}
+	return nil
+}
+
+// Source formats the PL/B source src read from the file filename.
+// The tokens of the result are compared with those of src, so formatting never changes what a program means;
+// if they differ an error is returned instead.
+func Source(src []byte, filename string, cfg Config) ([]byte, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	toks, err := lex(src, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	byLine := map[int][]tokens.Token{}
+	for _, tok := range toks {
+		switch tok.Type {
+		case tokens.NEWLINE, tokens.NULLLINE, tokens.EOF, tokens.COMMENT:
+			continue
+		}
+		if tok.Line > 0 {
+			byLine[tok.Line] = append(byLine[tok.Line], tok)
+		}
+	}
+
+	var out bytes.Buffer
+	for i, line := range strings.SplitAfter(string(src), "\n") {
+		text, ending := splitEnding(line)
+		out.WriteString(formatLine(text, byLine[i+1], cfg))
+		out.WriteString(ending)
+	}
+
+	if err := sameTokens(toks, out.Bytes(), filename); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// splitEnding splits the line ending, "\n" or "\r\n", from a line
+func splitEnding(line string) (text, ending string) {
+	text = strings.TrimSuffix(line, "\n")
+	text = strings.TrimSuffix(text, "\r")
+	return text, line[len(text):]
+}
+
+// isComment reports whether the line is a comment line, which is one whose first non blank is '.', '*' or '+'
+func isComment(line string) bool {
+	line = strings.TrimLeft(line, " \t")
+	return line != "" && strings.ContainsRune(".*+", rune(line[0]))
+}
+
+// formatLine lays out one line given its tokens, without the line endings
+func formatLine(text string, toks []tokens.Token, cfg Config) string {
+	if isComment(text) {
+		return text
+	}
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	for _, tok := range toks {
+		if tok.Type == tokens.ILLEGAL || tok.Col < 1 || tok.Col > len(text) {
+			return text
+		}
+	}
+
+	var b strings.Builder
+	i := 0
+	if toks[0].Type == tokens.IDENT && toks[0].Col == 1 {
+		if len(toks) > 1 && toks[1].Type != tokens.WHITESPACE {
+			return text
+		}
+		b.WriteString(tokenText(text, toks, 0))
+		i++
+	}
+	for i < len(toks) && toks[i].Type == tokens.WHITESPACE {
+		i++
+	}
+	if i == len(toks) {
+		return b.String()
+	}
+	if toks[i].Type != tokens.IDENT {
+		return text
+	}
+
+	pad(&b, cfg.VerbColumn)
+	b.WriteString(cfg.VerbCase.apply(tokenText(text, toks, i)))
+	i++
+	for i < len(toks) && toks[i].Type == tokens.WHITESPACE {
+		i++
+	}
+	if i == len(toks) {
+		return b.String()
+	}
+
+	pad(&b, cfg.OperandColumn)
+	blank, afterComma := false, false
+	for ; i < len(toks); i++ {
+		switch toks[i].Type {
+		case tokens.WHITESPACE:
+			blank = true
+			continue
+		case tokens.COMMA:
+			afterComma = true
+		default:
+			if blank && !afterComma {
+				b.WriteByte(' ')
+			}
+			afterComma = false
+		}
+		blank = false
+		b.WriteString(tokenText(text, toks, i))
+	}
+	return b.String()
+}
+
+// tokenText returns the source of the i-th token of a line, up to the next token or the end of the line.
+// Taking the source rather than the literal keeps the quotes of literals and anything the lexer skips.
+func tokenText(text string, toks []tokens.Token, i int) string {
+	end := len(text)
+	if i+1 < len(toks) {
+		end = toks[i+1].Col - 1
+	}
+	return strings.TrimRight(text[toks[i].Col-1:end], " \t")
+}
+
+// pad writes blanks up to the 1-based column col, or a single blank if b is already past it
+func pad(b *strings.Builder, col int) {
+	if b.Len() >= col-1 {
+		b.WriteByte(' ')
+		return
+	}
+	b.WriteString(strings.Repeat(" ", col-1-b.Len()))
+}
+
+func (c Case) apply(verb string) string {
+	switch c {
+	case UpperCase:
+		return strings.ToUpper(verb)
+	case LowerCase:
+		return strings.ToLower(verb)
+	}
+	return verb
+}
+
+func lex(src []byte, filename string) ([]tokens.Token, error) {
+	l := lexer.New(bufio.NewReader(bytes.NewReader(src)), filename)
+	var toks []tokens.Token
+	for {
+		tok, err := l.NextToken()
+		if err != nil {
+			return nil, err
+		}
+		toks = append(toks, tok)
+		if tok.Type == tokens.EOF {
+			return toks, nil
+		}
+	}
+}
+
+// sameTokens checks that the formatted source lexes to the tokens of the original,
+// apart from blanks and the case of identifiers
+func sameTokens(want []tokens.Token, formatted []byte, filename string) error {
+	got, err := lex(formatted, filename)
+	if err != nil {
+		return err
+	}
+	want, got = significant(want), significant(got)
+	for i := 0; i < len(want) || i < len(got); i++ {
+		if i >= len(want) || i >= len(got) || !sameToken(want[i], got[i]) {
+			line := 0
+			if i < len(want) {
+				line = want[i].Line
+			}
+			return fmt.Errorf("%s: formatting line %d would change the program", filename, line)
+		}
+	}
+	return nil
+}
+
+func sameToken(a, b tokens.Token) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	if a.Type == tokens.IDENT {
+		return strings.EqualFold(a.Literal, b.Literal)
+	}
+	return a.Literal == b.Literal
+}
+
+// significant drops the tokens that formatting may change: blanks other than the one marking an unlabelled line.
+// Blank lines are all the same, with or without blanks on them.
+func significant(toks []tokens.Token) []tokens.Token {
+	var out []tokens.Token
+	for i, tok := range toks {
+		switch {
+		case tok.Type == tokens.WHITESPACE && (tok.Col != 1 || i+1 < len(toks) && endsLine(toks[i+1])):
+			continue
+		case tok.Type == tokens.WHITESPACE:
+			tok.Literal = " "
+		case tok.Type == tokens.NULLLINE:
+			tok.Type = tokens.NEWLINE
+		}
+		out = append(out, tok)
+	}
+	return out
+}
+
+func endsLine(tok tokens.Token) bool {
+	return tok.Type == tokens.NEWLINE || tok.Type == tokens.NULLLINE || tok.Type == tokens.EOF
+}
@@ -0,0 +1,157 @@
+package format
+
+import (
+	"PLB-Interpreter/lexer"
+	"PLB-Interpreter/parser"
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	lower := Config{VerbColumn: 5, OperandColumn: 13, VerbCase: LowerCase}
+	tests := []struct {
+		name  string
+		cfg   Config
+		input string
+		want  string
+	}{
+		{
+			name:  "columns",
+			cfg:   DefaultConfig,
+			input: "TOP\n    MOVE \"A\" TO B\nX DISPLAY B\n",
+			want:  "TOP\n        MOVE    \"A\" TO B\nX       DISPLAY B\n",
+		},
+		{
+			name:  "verb case",
+			cfg:   DefaultConfig,
+			input: "A dim 10\n  listend\n",
+			want:  "A       DIM     10\n        LISTEND\n",
+		},
+		{
+			name:  "configured columns and lower case",
+			cfg:   lower,
+			input: "LABEL DISPLAY A\n  STOP\n",
+			want:  "LABEL display A\n    stop\n",
+		},
+		{
+			name:  "keep case",
+			cfg:   Config{VerbColumn: 3, OperandColumn: 10, VerbCase: KeepCase},
+			input: " Display A\n",
+			want:  "  Display A\n",
+		},
+		{
+			name:  "long label and verb",
+			cfg:   DefaultConfig,
+			input: "LONGLABEL MOVEADR A TO B\n",
+			want:  "LONGLABEL MOVEADR A TO B\n",
+		},
+		{
+			name:  "spacing around commas",
+			cfg:   DefaultConfig,
+			input: " DISPLAY *P=1 : 2 ,  A ,\t\"X, Y\"\n CALL S  USING   A , B\n",
+			want:  "        DISPLAY *P=1:2,A,\"X, Y\"\n        CALL    S USING A,B\n",
+		},
+		{
+			name:  "literals are kept as written",
+			cfg:   DefaultConfig,
+			input: "Q INIT \"SAY \"\"HI\"\"\"\n",
+			want:  "Q       INIT    \"SAY \"\"HI\"\"\"\n",
+		},
+		{
+			name:  "relational operators",
+			cfg:   DefaultConfig,
+			input: " GOTO TOP IF (A <= 25)\n IF (A>=B OR A<>C)\n ENDIF\n",
+			want:  "        GOTO    TOP IF (A <= 25)\n        IF      (A>=B OR A<>C)\n        ENDIF\n",
+		},
+		{
+			name:  "comment lines are left alone",
+			cfg:   DefaultConfig,
+			input: ". a  comment ,  here  \n   * indented\n+ form feed\n move a\n",
+			want:  ". a  comment ,  here  \n   * indented\n+ form feed\n        MOVE    a\n",
+		},
+		{
+			name:  "blank lines and trailing blanks",
+			cfg:   DefaultConfig,
+			input: "   \n STOP   \n\nEND",
+			want:  "\n        STOP\n\nEND",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Source([]byte(tt.input), "test", tt.cfg)
+			if err != nil {
+				t.Fatalf("Source() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Source() =\n%q\nwant\n%q", got, tt.want)
+			}
+			again, err := Source(got, "test", tt.cfg)
+			if err != nil {
+				t.Fatalf("Source() of the result error = %v", err)
+			}
+			if !bytes.Equal(again, got) {
+				t.Errorf("Source() is not idempotent, formatting the result gives\n%q", again)
+			}
+		})
+	}
+}
+
+func TestSource_InvalidConfig(t *testing.T) {
+	for _, cfg := range []Config{
+		{VerbColumn: 1, OperandColumn: 10},
+		{VerbColumn: 9, OperandColumn: 9},
+	} {
+		if _, err := Source([]byte(" STOP\n"), "test", cfg); err == nil {
+			t.Errorf("Source() with %+v succeeded, want an error", cfg)
+		}
+	}
+}
+
+func TestParseCase(t *testing.T) {
+	for name, want := range map[string]Case{"keep": KeepCase, "UPPER": UpperCase, "lower": LowerCase} {
+		if got, err := ParseCase(name); err != nil || got != want {
+			t.Errorf("ParseCase(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseCase("title"); err == nil {
+		t.Error("ParseCase(\"title\") succeeded, want an error")
+	}
+}
+
+// TestSource_KeepsMeaning formats the examples and checks they parse to the same program
+func TestSource_KeepsMeaning(t *testing.T) {
+	files, err := filepath.Glob("../examples/*.plb")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no examples: %v", err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, cfg := range []Config{DefaultConfig, {VerbColumn: 2, OperandColumn: 3, VerbCase: LowerCase}} {
+				out, err := Source(src, file, cfg)
+				if err != nil {
+					t.Fatalf("Source() error = %v", err)
+				}
+				if got, want := parse(t, out), parse(t, src); got != want {
+					t.Errorf("with %+v the program changed to\n%s\nwant\n%s", cfg, got, want)
+				}
+			}
+		})
+	}
+}
+
+func parse(t *testing.T, src []byte) string {
+	t.Helper()
+	p := parser.New(lexer.New(bufio.NewReader(bytes.NewReader(src)), "test"))
+	prog := p.ParseProgram()
+	if has, errs := p.Errors(); has {
+		t.Fatalf("parser errors: %v", errs)
+	}
+	return prog.String()
+}
@@ -0,0 +1,395 @@
+package interpreter
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/storage"
+	"PLB-Interpreter/tokens"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// trapEvents maps the runtime error codes that can be trapped to their TRAP event
+var trapEvents = map[string]string{
+	plbErrors.ErrNullPointer: "RANGE",
+	plbErrors.ErrPointerType: "RANGE",
+}
+
+// Interpreter executes the top level statements of a parsed program one after the other.
+// It only knows a few verbs so far, executing anything else stops the program with ErrNotSupported.
+type Interpreter struct {
+	stmts  []ast.Statement
+	labels map[string]int      // execution labels by upper-cased name, to the index of the labelled statement
+	vars   map[string]Variable // the data area by upper-cased name
+	flags  map[string]bool     // condition flags set by the last operation
+	traps  map[string]int      // trapped events, to the index of the statement to continue with
+	pc     int                 // index of the next statement
+}
+
+// New prepares the data area and the execution labels of prog, the program is executed by Run.
+// The statements of included files are executed in place of their INCLUDE.
+func New(prog *ast.Program) *Interpreter {
+	in := &Interpreter{
+		stmts:  flatten(prog.Statements),
+		labels: map[string]int{},
+		vars:   map[string]Variable{},
+		flags:  map[string]bool{},
+		traps:  map[string]int{},
+	}
+
+	var label *ast.Identifier // a label on a line of its own, naming the next declaration
+	for i, stmt := range in.stmts {
+		switch s := stmt.(type) {
+		case *ast.LabelStatement:
+			in.labels[strings.ToUpper(s.Name.Value)] = i
+			label = s.Name
+			continue
+		case *ast.DataDeclaration:
+			in.declare(s, ast.DeclaredName(s.Name, label))
+		case *ast.PointerDeclaration:
+			if name := ast.DeclaredName(s.Name, label); name != nil {
+				in.vars[strings.ToUpper(name.Value)] = &Pointer{To: s.Kind}
+			}
+		case *ast.VerbStatement:
+			if s.Label != nil {
+				in.labels[strings.ToUpper(s.Label.Value)] = i
+			}
+		case *ast.ListStatement:
+			if s.Label != nil {
+				in.labels[strings.ToUpper(s.Label.Value)] = i
+			}
+		}
+		label = nil
+	}
+	return in
+}
+
+// flatten replaces every INCLUDE by the statements of the included file
+func flatten(stmts []ast.Statement) []ast.Statement {
+	var flat []ast.Statement
+	for _, stmt := range stmts {
+		if inc, ok := stmt.(*ast.IncludeStatement); ok && inc.Body != nil {
+			flat = append(flat, flatten(inc.Body.Statements)...)
+			continue
+		}
+		flat = append(flat, stmt)
+	}
+	return flat
+}
+
+// declare adds the variable defined by decl to the data area
+func (in *Interpreter) declare(decl *ast.DataDeclaration, name *ast.Identifier) {
+	if name == nil {
+		return
+	}
+	var v Variable
+	switch decl.Kind {
+	case "DIM":
+		size, _ := strconv.Atoi(decl.Operands[0].String())
+		v = &Char{Size: size}
+	case "INIT":
+		var value strings.Builder
+		for _, op := range decl.Operands {
+			value.WriteString(storage.LiteralValue(op))
+		}
+		v = &Char{Size: value.Len(), Value: value.String()}
+	case "FORM":
+		num := &Num{}
+		num.Digits, num.Decimals, _ = storage.Digits(decl)
+		if lit, ok := decl.Operands[0].(*ast.NumberLiteral); ok && lit.Token.Type == tokens.NUMERICLITERAL {
+			// the initial value defines the format, e.g. FORM "-10.5" like FORM 3.1
+			num.set(lit.Value)
+		}
+		v = num
+	default:
+		// EQU and LIST or RECORD grouping define no storage of their own
+		return
+	}
+	in.vars[strings.ToUpper(name.Value)] = v
+}
+
+// Variable returns the variable with the given name, case-insensitive
+func (in *Interpreter) Variable(name string) (Variable, bool) {
+	v, ok := in.vars[strings.ToUpper(name)]
+	return v, ok
+}
+
+// Flag returns the state of a condition flag, e.g. EQUAL
+func (in *Interpreter) Flag(name string) bool {
+	return in.flags[strings.ToUpper(name)]
+}
+
+// Run executes the program until STOP or its last statement.
+// A runtime error ends the program unless its event is trapped by an earlier TRAP,
+// in which case the program continues at the trap label.
+func (in *Interpreter) Run() error {
+	for in.pc < len(in.stmts) {
+		stmt := in.stmts[in.pc]
+		in.pc++
+		stop, err := in.execute(stmt)
+		if err != nil {
+			if target, ok := in.trapped(err); ok {
+				in.pc = target
+				continue
+			}
+			return err
+		}
+		if stop {
+			return nil
+		}
+	}
+	return nil
+}
+
+// trapped returns the statement to continue with if the event of err is trapped
+func (in *Interpreter) trapped(err error) (int, bool) {
+	plbErr, ok := err.(*plbErrors.PLBError)
+	if !ok {
+		return 0, false
+	}
+	event, ok := trapEvents[plbErr.ErrorCode]
+	if !ok {
+		return 0, false
+	}
+	target, ok := in.traps[event]
+	return target, ok
+}
+
+// execute runs a single statement, it returns true if the program has to stop
+func (in *Interpreter) execute(stmt ast.Statement) (bool, error) {
+	switch s := stmt.(type) {
+	case *ast.LabelStatement, *ast.DataDeclaration, *ast.PointerDeclaration:
+		return false, nil
+	case *ast.VerbStatement:
+		if s.Verb == "TRAP" {
+			return false, in.trap(s)
+		}
+		if s.Condition != nil {
+			ok, err := in.condition(s.Token, s.Condition)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return in.verb(s)
+	}
+	return false, newError(tokenOf(stmt), plbErrors.ErrNotSupported, fmt.Sprintf("%T cannot be executed yet", stmt))
+}
+
+// verb executes a verb statement whose condition, if any, is met
+func (in *Interpreter) verb(s *ast.VerbStatement) (bool, error) {
+	switch s.Verb {
+	case "MOVE":
+		return false, in.move(s.Operands[0], s.Operands[1])
+	case "MOVEADR":
+		return false, in.moveAdr(s.Operands[0], s.Operands[1])
+	case "MOVEPTR":
+		return false, in.movePtr(s.Operands[0], s.Operands[1])
+	case "TYPE":
+		return false, in.typeOf(s.Operands[0])
+	case "GOTO":
+		target, err := in.label(s.Operands[0])
+		if err != nil {
+			return false, err
+		}
+		in.pc = target
+		return false, nil
+	case "STOP":
+		return true, nil
+	}
+	return false, newError(s.Token, plbErrors.ErrNotSupported, fmt.Sprintf("%s cannot be executed yet", s.Verb))
+}
+
+// move copies the value of src to dst, converting between character and numeric values
+func (in *Interpreter) move(src, dst ast.Expression) error {
+	value, err := in.value(src)
+	if err != nil {
+		return err
+	}
+	v, err := in.variable(dst)
+	if err != nil {
+		return err
+	}
+	switch d := v.(type) {
+	case *Char:
+		d.set(value.String())
+	case *Num:
+		if n, ok := value.(*Num); ok {
+			d.Value = n.Value
+		} else {
+			d.set(value.String())
+		}
+	case *Pointer:
+		return newError(tokenOf(dst), plbErrors.ErrPointerType, fmt.Sprintf("cannot MOVE to the pointer %s, use MOVEADR or MOVEPTR", dst))
+	}
+	return nil
+}
+
+// moveAdr makes the pointer ptr refer to the variable src
+func (in *Interpreter) moveAdr(src, ptr ast.Expression) error {
+	target, err := in.variable(src)
+	if err != nil {
+		return err
+	}
+	p, err := in.pointer(ptr)
+	if err != nil {
+		return err
+	}
+	return in.point(p, ptr, target)
+}
+
+// movePtr makes the pointer dst refer to the variable the pointer src refers to
+func (in *Interpreter) movePtr(src, dst ast.Expression) error {
+	from, err := in.pointer(src)
+	if err != nil {
+		return err
+	}
+	to, err := in.pointer(dst)
+	if err != nil {
+		return err
+	}
+	if from.Target == nil {
+		to.Target = nil
+		return nil
+	}
+	return in.point(to, dst, from.Target)
+}
+
+// point makes p refer to target, if the pointer may refer to that kind of variable
+func (in *Interpreter) point(p *Pointer, ptr ast.Expression, target Variable) error {
+	if !p.canReferTo(target) {
+		return newError(tokenOf(ptr), plbErrors.ErrPointerType, fmt.Sprintf("%s is a %s ^ pointer and cannot refer to a %s", ptr, p.To, target.Kind()))
+	}
+	p.Target = target
+	return nil
+}
+
+// typeOf sets the flags according to the type of a variable, or of the variable a pointer refers to:
+// EQUAL for a character variable, EOS for a null pointer
+func (in *Interpreter) typeOf(expr ast.Expression) error {
+	v, err := in.variable(expr)
+	if err != nil {
+		return err
+	}
+	if p, ok := v.(*Pointer); ok {
+		v = p.Target
+	}
+	in.flags["EOS"] = v == nil
+	in.flags["EQUAL"] = v != nil && v.Kind() == "DIM"
+	return nil
+}
+
+// trap records the label to continue with when the event of the TRAP occurs
+func (in *Interpreter) trap(s *ast.VerbStatement) error {
+	event, ok := s.Condition.(*ast.EventExpression)
+	if !ok {
+		// the parser reported the malformed event
+		return newError(s.Token, plbErrors.ErrNotSupported, fmt.Sprintf("TRAP event %s cannot be trapped", s.Condition))
+	}
+	target, err := in.label(s.Operands[0])
+	if err != nil {
+		return err
+	}
+	in.traps[event.Event] = target
+	return nil
+}
+
+// condition evaluates the IF suffix of a statement
+func (in *Interpreter) condition(tok tokens.Token, cond ast.Expression) (bool, error) {
+	switch c := cond.(type) {
+	case *ast.FlagExpression:
+		return in.flags[c.Flag], nil
+	case *ast.PrefixExpression:
+		if flag, ok := c.Right.(*ast.FlagExpression); ok && c.Operator == "NOT" {
+			return !in.flags[flag.Flag], nil
+		}
+	}
+	return false, newError(tok, plbErrors.ErrNotSupported, fmt.Sprintf("the condition %s cannot be evaluated yet", cond))
+}
+
+// label returns the index of the statement with the execution label expr
+func (in *Interpreter) label(expr ast.Expression) (int, error) {
+	target, ok := in.labels[strings.ToUpper(expr.String())]
+	if !ok {
+		return 0, newError(tokenOf(expr), plbErrors.ErrUndefined, fmt.Sprintf("undefined execution label %s", expr))
+	}
+	return target, nil
+}
+
+// value returns the value of a literal or variable operand
+func (in *Interpreter) value(expr ast.Expression) (Variable, error) {
+	switch e := expr.(type) {
+	case *ast.StringLiteral:
+		return &Char{Size: len(e.Value), Value: e.Value}, nil
+	case *ast.NumberLiteral:
+		num := &Num{}
+		num.set(e.Value)
+		if _, decimals, ok := strings.Cut(e.Value, "."); ok {
+			num.Decimals = len(decimals)
+		}
+		return num, nil
+	}
+	return in.variable(expr)
+}
+
+// variable returns the variable an operand refers to, following a dereferenced pointer
+func (in *Interpreter) variable(expr ast.Expression) (Variable, error) {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		v, ok := in.vars[strings.ToUpper(e.Value)]
+		if !ok {
+			return nil, newError(e.Token, plbErrors.ErrUndefined, fmt.Sprintf("undefined variable %s", e.Value))
+		}
+		return v, nil
+	case *ast.DerefExpression:
+		p, err := in.pointer(e.Pointer)
+		if err != nil {
+			return nil, err
+		}
+		if p.Target == nil {
+			return nil, newError(e.Token, plbErrors.ErrNullPointer, fmt.Sprintf("%s is a null pointer", e.Pointer))
+		}
+		return p.Target, nil
+	}
+	return nil, newError(tokenOf(expr), plbErrors.ErrNotSupported, fmt.Sprintf("the operand %s cannot be evaluated yet", expr))
+}
+
+// pointer returns the pointer variable named by expr
+func (in *Interpreter) pointer(expr ast.Expression) (*Pointer, error) {
+	ident, ok := expr.(*ast.Identifier)
+	if !ok {
+		return nil, newError(tokenOf(expr), plbErrors.ErrPointerType, fmt.Sprintf("%s is not a pointer", expr))
+	}
+	v, err := in.variable(ident)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := v.(*Pointer)
+	if !ok {
+		return nil, newError(ident.Token, plbErrors.ErrPointerType, fmt.Sprintf("%s is not a pointer", ident.Value))
+	}
+	return p, nil
+}
+
+// tokenOf returns the token of a node, used to place runtime errors
+func tokenOf(node ast.Node) tokens.Token {
+	switch n := node.(type) {
+	case *ast.Identifier:
+		return n.Token
+	case *ast.DerefExpression:
+		return n.Token
+	case *ast.StringLiteral:
+		return n.Token
+	case *ast.NumberLiteral:
+		return n.Token
+	case *ast.VerbStatement:
+		return n.Token
+	case *ast.CallStatement:
+		return n.Token
+	}
+	return tokens.Token{}
+}
+
+func newError(tok tokens.Token, code, msg string) *plbErrors.PLBError {
+	return plbErrors.NewPLBError(code, msg, tok.FileName, tok.Line, tok.Col, tok.LineTxt)
+}
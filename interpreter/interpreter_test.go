@@ -0,0 +1,183 @@
+package interpreter
+
+import (
+	"PLB-Interpreter/lexer"
+	"PLB-Interpreter/parser"
+	"PLB-Interpreter/plbErrors"
+	"bufio"
+	"strings"
+	"testing"
+)
+
+// run parses and runs the given input, parser errors fail the test
+func run(t *testing.T, input string) (*Interpreter, error) {
+	t.Helper()
+	p := parser.New(lexer.New(bufio.NewReader(strings.NewReader(input)), "test"))
+	prog := p.ParseProgram()
+	if has, errs := p.Errors(); has {
+		t.Fatalf("parser errors: %v", errs)
+	}
+	in := New(prog)
+	return in, in.Run()
+}
+
+// value returns the displayed value of a variable, a missing variable fails the test
+func value(t *testing.T, in *Interpreter, name string) string {
+	t.Helper()
+	v, ok := in.Variable(name)
+	if !ok {
+		t.Fatalf("variable %s is not defined", name)
+	}
+	return v.String()
+}
+
+func TestInterpreter_Pointers(t *testing.T) {
+	input := `NAME DIM 10
+OTHER DIM 10
+COUNT FORM 3
+PTR DIM ^
+ANY VAR @
+    MOVEADR NAME TO PTR
+    MOVE "HELLO" TO PTR
+    MOVEPTR PTR TO ANY
+    MOVE "WORLD" TO ANY
+    MOVEADR COUNT TO ANY
+    MOVE "42" TO ANY
+    MOVE PTR TO OTHER
+`
+	in, err := run(t, input)
+	if err != nil {
+		t.Fatalf("got runtime error %s", err)
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "NAME", want: "WORLD"},
+		{name: "OTHER", want: "WORLD"},
+		{name: "COUNT", want: "42"},
+		{name: "PTR", want: "WORLD"},
+		{name: "ANY", want: "42"},
+	}
+	for _, tt := range tests {
+		if got := value(t, in, tt.name); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestInterpreter_PointerErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		code  string
+	}{
+		{
+			name:  "move through a null pointer",
+			input: "PTR DIM ^\n    MOVE \"X\" TO PTR\n",
+			code:  plbErrors.ErrNullPointer,
+		},
+		{
+			name:  "read through a null pointer",
+			input: "PTR FORM ^\nN FORM 2\n    MOVE PTR TO N\n",
+			code:  plbErrors.ErrNullPointer,
+		},
+		{
+			name:  "pointer to the wrong kind",
+			input: "N FORM 2\nPTR DIM ^\n    MOVEADR N TO PTR\n",
+			code:  plbErrors.ErrPointerType,
+		},
+		{
+			name:  "null pointer copied",
+			input: "P DIM ^\nQ DIM ^\nC DIM 1\n    MOVEADR C TO Q\n    MOVEPTR P TO Q\n    MOVE \"X\" TO Q\n",
+			code:  plbErrors.ErrNullPointer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(t, tt.input)
+			if err == nil {
+				t.Fatalf("got no error, want %s", tt.code)
+			}
+			if code := err.(*plbErrors.PLBError).ErrorCode; code != tt.code {
+				t.Errorf("got %s (%s), want %s", code, err, tt.code)
+			}
+		})
+	}
+}
+
+func TestInterpreter_TrapNullPointer(t *testing.T) {
+	input := `PTR DIM ^
+RESULT DIM 10
+    TRAP BADPTR IF RANGE
+    MOVE "LOST" TO PTR
+    MOVE "NOT TRAPPED" TO RESULT
+    STOP
+BADPTR
+    MOVE "TRAPPED" TO RESULT
+`
+	in, err := run(t, input)
+	if err != nil {
+		t.Fatalf("got runtime error %s, want it trapped", err)
+	}
+	if got := value(t, in, "RESULT"); got != "TRAPPED" {
+		t.Errorf("got RESULT %q, want TRAPPED", got)
+	}
+}
+
+func TestInterpreter_Type(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup string
+		equal bool
+		eos   bool
+	}{
+		{name: "character variable", setup: "    MOVEADR C TO ANY\n", equal: true},
+		{name: "numeric variable", setup: "    MOVEADR N TO ANY\n"},
+		{name: "null pointer", eos: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, err := run(t, "C DIM 1\nN FORM 1\nANY VAR @\n"+tt.setup+"    TYPE ANY\n")
+			if err != nil {
+				t.Fatalf("got runtime error %s", err)
+			}
+			if in.Flag("EQUAL") != tt.equal || in.Flag("EOS") != tt.eos {
+				t.Errorf("got EQUAL %t EOS %t, want EQUAL %t EOS %t", in.Flag("EQUAL"), in.Flag("EOS"), tt.equal, tt.eos)
+			}
+		})
+	}
+}
+
+func TestInterpreter_Declarations(t *testing.T) {
+	in, err := run(t, "A FORM 5.2\nB FORM \"-10\"\nC FORM \"-1.25\"\nD INIT \"AB\",\"C\"\n")
+	if err != nil {
+		t.Fatalf("got runtime error %s", err)
+	}
+	tests := []struct {
+		name     string
+		digits   int
+		decimals int
+		want     string
+	}{
+		{name: "A", digits: 5, decimals: 2, want: "0.00"},
+		{name: "B", digits: 3, decimals: 0, want: "-10"},
+		{name: "C", digits: 2, decimals: 2, want: "-1.25"},
+	}
+	for _, tt := range tests {
+		v, _ := in.Variable(tt.name)
+		num, ok := v.(*Num)
+		if !ok {
+			t.Fatalf("%s: got %T, want *Num", tt.name, v)
+		}
+		if num.Digits != tt.digits || num.Decimals != tt.decimals || num.String() != tt.want {
+			t.Errorf("%s: got FORM %d.%d with %q, want FORM %d.%d with %q",
+				tt.name, num.Digits, num.Decimals, num, tt.digits, tt.decimals, tt.want)
+		}
+	}
+	if got := value(t, in, "D"); got != "ABC" {
+		t.Errorf("D: got %q, want %q", got, "ABC")
+	}
+}
@@ -0,0 +1,67 @@
+package interpreter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Variable is a variable in the data area of a running program
+type Variable interface {
+	Kind() string   // DIM, FORM or VAR for a pointer
+	String() string // the current value as it would be displayed
+}
+
+// Char is a character variable defined by DIM or INIT
+type Char struct {
+	Size  int // maximum number of characters
+	Value string
+}
+
+func (c *Char) Kind() string   { return "DIM" }
+func (c *Char) String() string { return c.Value }
+
+// set stores s, truncated to the size of the variable
+func (c *Char) set(s string) {
+	if len(s) > c.Size {
+		s = s[:c.Size]
+	}
+	c.Value = s
+}
+
+// Num is a numeric variable defined by FORM
+type Num struct {
+	Digits   int // digits before the decimal point, including the place of a minus sign
+	Decimals int // digits after the decimal point
+	Value    float64
+}
+
+func (n *Num) Kind() string   { return "FORM" }
+func (n *Num) String() string { return strconv.FormatFloat(n.Value, 'f', n.Decimals, 64) }
+
+// set parses s as a number, a value that is not a number is stored as zero
+func (n *Num) set(s string) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		v = 0
+	}
+	n.Value = v
+}
+
+// Pointer is a pointer variable defined by DIM ^, FORM ^ or VAR @
+type Pointer struct {
+	To     string   // DIM or FORM for a pointer to that kind of variable, VAR for a pointer to any variable
+	Target Variable // the variable the pointer refers to, nil for a null pointer
+}
+
+func (p *Pointer) Kind() string { return "VAR" }
+func (p *Pointer) String() string {
+	if p.Target == nil {
+		return ""
+	}
+	return p.Target.String()
+}
+
+// canReferTo returns true if the pointer may be made to refer to v
+func (p *Pointer) canReferTo(v Variable) bool {
+	return p.To == "VAR" || p.To == v.Kind()
+}
@@ -5,6 +5,7 @@ import (
 	"PLB-Interpreter/tokens"
 	"bufio"
 	"errors"
+	"fmt"
 	"io"
 	"strings"
 	"unicode"
@@ -128,6 +129,9 @@ func (l *Lexer) NextToken() (tokens.Token, error) {
 	case ';':
 		tok = l.newToken(tokens.SEMICOLON, l.ch)
 		l.lineHadNonWS = true
+	case '!':
+		tok = l.newToken(tokens.EXCLAMATION, l.ch)
+		l.lineHadNonWS = true
 	case '(':
 		tok = l.newToken(tokens.LPAREN, l.ch)
 		l.lineHadNonWS = true
@@ -149,7 +153,6 @@ func (l *Lexer) NextToken() (tokens.Token, error) {
 				tok.Type = tokens.POWER
 				tok.Literal = "**"
 				l.readChar()
-				l.col++
 			} else {
 				tok = l.newToken(tokens.ASTERISK, l.ch)
 			}
@@ -179,7 +182,6 @@ func (l *Lexer) NextToken() (tokens.Token, error) {
 			tok.Type = tokens.LEQ
 			tok.Literal = "<="
 			l.readChar()
-			l.col++
 		} else if l.peekChar() == '>' {
 			tok = tokens.Token{
 				Line:     l.lineNumber,
@@ -190,7 +192,6 @@ func (l *Lexer) NextToken() (tokens.Token, error) {
 			tok.Type = tokens.NEQ
 			tok.Literal = "<>"
 			l.readChar()
-			l.col++
 		} else {
 			tok = l.newToken(tokens.LT, l.ch)
 		}
@@ -206,13 +207,18 @@ func (l *Lexer) NextToken() (tokens.Token, error) {
 			tok.Type = tokens.GEQ
 			tok.Literal = ">="
 			l.readChar()
-			l.col++
 		} else {
 			tok = l.newToken(tokens.GT, l.ch)
 		}
 	case '=':
 		l.lineHadNonWS = true
 		tok = l.newToken(tokens.EQ, l.ch)
+	case '^':
+		l.lineHadNonWS = true
+		tok = l.newToken(tokens.CARET, l.ch)
+	case '@':
+		l.lineHadNonWS = true
+		tok = l.newToken(tokens.AT, l.ch)
 	case '"':
 		if l.peekChar() == '-' || l.isDigit(l.peekChar()) || l.peekChar() == '.' {
 			tok = tokens.Token{
@@ -269,6 +275,12 @@ func (l *Lexer) NextToken() (tokens.Token, error) {
 			}
 			tok.Type = tokens.DNUM
 			tok.Literal = l.readDec()
+			if l.ch == '.' && l.isDigit(l.peekChar()) {
+				// DIGITS . DIGITS form, e.g. the size of FORM 5.2
+				l.readChar()
+				tok.Type = tokens.NUMERICCONSTANT
+				tok.Literal += "." + l.readDec()
+			}
 			return tok, nil
 		} else if l.isLetter(l.ch) {
 			tok = tokens.Token{
@@ -289,14 +301,16 @@ func (l *Lexer) NextToken() (tokens.Token, error) {
 			}
 			tok = l.newToken(tokens.ILLEGAL, l.ch)
 			err := plbErrors.NewPLBError(
-				"Lexer",
-				"Invalid token type",
+				plbErrors.ErrIllegalCharacter,
+				fmt.Sprintf("illegal character %q", l.ch),
 				l.fileName,
 				l.lineNumber,
 				l.col,
 				strings.TrimRight(l.lines[l.lineNumber-1], "\r\n"),
 			)
 			l.errors = append(l.errors, err)
+			// skip the offending character, so lexing can continue after the error
+			l.readChar()
 			return tok, err
 		}
 	}
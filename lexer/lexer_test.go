@@ -87,13 +87,13 @@ func TestLexer_NextToken_Error(t *testing.T) {
 	}{
 		{
 			name:  "Invalid token 1",
-			input: "!hello",
-			want:  &plbErrors.PLBError{ErrorCode: "Lexer", Message: "Invalid token type", File: "test", LineNumber: 1, Column: 1, LineText: "!hello"},
+			input: "~hello",
+			want:  &plbErrors.PLBError{ErrorCode: plbErrors.ErrIllegalCharacter, Message: `illegal character '~'`, File: "test", LineNumber: 1, Column: 1, LineText: "~hello"},
 		},
 		{
 			name:  "Invalid token 2",
-			input: "1383!",
-			want:  &plbErrors.PLBError{ErrorCode: "Lexer", Message: "Invalid token type", File: "test", LineNumber: 1, Column: 5, LineText: "1383!"},
+			input: "1383~",
+			want:  &plbErrors.PLBError{ErrorCode: plbErrors.ErrIllegalCharacter, Message: `illegal character '~'`, File: "test", LineNumber: 1, Column: 5, LineText: "1383~"},
 		},
 	}
 
@@ -101,17 +101,18 @@ func TestLexer_NextToken_Error(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			reader := bufio.NewReader(strings.NewReader(tt.input))
 			l := New(reader, "test")
-			cont := true
-			for cont {
-				_, err := l.NextToken()
+			for {
+				tok, err := l.NextToken()
 				if err != nil {
 					if err.Error() != tt.want.Error() {
 						t.Errorf("got %q, want %q", err, tt.want)
 					}
-					cont = false
+					return
+				}
+				if tok.Type == tokens.EOF {
+					t.Fatalf("got no error before the end of the input, want %q", tt.want)
 				}
 			}
-
 		})
 	}
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"PLB-Interpreter/lint"
+	"PLB-Interpreter/parser"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/resolver"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// lintCommand prints the warnings about a file, e.g. unreachable statements or unused labels, after the errors
+// of the parser and the resolver, e.g. undeclared labels. The exit status is 1 if there are any, so it can be used in scripts.
+func lintCommand(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	suppress := flags.String("suppress", "", "comma-separated codes of warnings not to report, e.g. W503")
+	dialect := flags.String("dialect", "", "PL/B dialect, SUNBELT or ANSI")
+	defs := defineFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	opts, env := resolveOptions(*dialect, defs)
+	prog, diag := parser.ParseFile(sourcePath(flags.Args()), opts)
+	for _, err := range diag.Errors {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	var codes []string
+	if *suppress != "" {
+		codes = strings.Split(*suppress, ",")
+	}
+	var resolveErrors plbErrors.Collector
+	table := resolver.New(resolver.WithEnvironment(env), resolver.WithDiagnostics(&resolveErrors)).Resolve(prog)
+	for _, err := range resolveErrors.Errors {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	l := lint.New(lint.WithSymbols(table), lint.WithSuppressed(codes...))
+	l.Lint(prog)
+	_, warnings := l.Warnings()
+	for _, warning := range warnings {
+		fmt.Fprintln(os.Stderr, warning)
+	}
+	if len(diag.Errors) > 0 || len(resolveErrors.Errors) > 0 || len(warnings) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,405 @@
+package lint
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/cfg"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/symbols"
+	"PLB-Interpreter/tokens"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// access is the use of a variable by a statement
+type access struct {
+	sym   *symbols.Symbol
+	ident *ast.Identifier // the identifier naming sym, or the LIST sym is a member of
+	use   symbols.Access
+}
+
+// flow follows the values assigned to the variables declared by DIM, FORM and INIT through the graphs of a program.
+// A state tells for every variable whether a value has been assigned to it on every path.
+type flow struct {
+	l        *Linter
+	vars     map[*symbols.Symbol]int                     // the index of every variable in a state
+	initial  []bool                                      // the variables assigned by their declaration, by INIT or FORM with a value
+	accesses map[ast.Node][]access                       // the accesses of every statement of the graphs
+	writes   map[string]map[*symbols.Symbol]bool         // the variables a routine and the routines it calls may assign, by upper-cased name
+	used     map[*symbols.Symbol]map[symbols.Access]bool // every use of every variable anywhere in the program
+}
+
+// checkData reports variables read before they are assigned, variables assigned but never read
+// and declarations never referred to
+func (l *Linter) checkData(graphs []*cfg.Graph) {
+	f := &flow{
+		l:        l,
+		vars:     map[*symbols.Symbol]int{},
+		accesses: map[ast.Node][]access{},
+		writes:   map[string]map[*symbols.Symbol]bool{},
+		used:     map[*symbols.Symbol]map[symbols.Access]bool{},
+	}
+	decls := l.declarations()
+	for _, sym := range decls {
+		if decl, ok := sym.Node.(*ast.DataDeclaration); ok && variable(decl) {
+			f.vars[sym] = len(f.initial)
+			f.initial = append(f.initial, assigned(decl))
+		}
+	}
+
+	for _, g := range graphs {
+		for _, block := range g.Blocks {
+			for _, node := range block.Nodes {
+				f.accesses[node] = f.accessesOf(node)
+				for _, acc := range f.accesses[node] {
+					if f.used[acc.sym] == nil {
+						f.used[acc.sym] = map[symbols.Access]bool{}
+					}
+					f.used[acc.sym][acc.use] = true
+				}
+			}
+		}
+	}
+	f.routineWrites(graphs)
+
+	for _, g := range graphs {
+		f.analyse(g)
+	}
+	f.checkDeclarations(decls)
+}
+
+// declarations returns the data, file and pointer symbols declared by the program in source order.
+// A duplicate declaration is left out, it has been reported by the resolver.
+func (l *Linter) declarations() []*symbols.Symbol {
+	var decls []*symbols.Symbol
+	for _, sym := range l.table.Defs {
+		if sym.Kind.Space() == symbols.DataSpace && sym.Scope != nil {
+			decls = append(decls, sym)
+		}
+	}
+	sort.Slice(decls, func(i, j int) bool {
+		return before(decls[i].Token, decls[j].Token)
+	})
+	return decls
+}
+
+func before(a, b tokens.Token) bool {
+	if a.FileName != b.FileName {
+		return a.FileName < b.FileName
+	}
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Col < b.Col
+}
+
+// variable returns true for a declaration whose value is followed, i.e. DIM, FORM and INIT
+func variable(decl *ast.DataDeclaration) bool {
+	switch decl.Kind {
+	case "DIM", "FORM", "INIT":
+		return true
+	}
+	return false
+}
+
+// assigned returns true if the declaration gives the variable a value, i.e. INIT and FORM "10.5"
+func assigned(decl *ast.DataDeclaration) bool {
+	switch decl.Kind {
+	case "INIT":
+		return true
+	case "FORM":
+		lit, ok := decl.Operands[0].(*ast.NumberLiteral)
+		return ok && lit.Token.Type == tokens.NUMERICLITERAL
+	}
+	return false
+}
+
+// accessesOf returns the variables a statement of a graph uses, as recorded by the resolver.
+// The operands of a structured statement are those of its header, e.g. the condition of an IF.
+func (f *flow) accessesOf(node ast.Node) []access {
+	var exprs []ast.Expression
+	switch s := node.(type) {
+	case *ast.VerbStatement:
+		exprs = append(append(exprs, s.Operands...), s.Condition)
+	case *ast.CallStatement:
+		exprs = append(append(exprs, s.Args...), s.Condition)
+	case *ast.ListStatement:
+		for _, item := range s.Items {
+			if item != nil {
+				exprs = append(append(exprs, item.Value), item.Args...)
+			}
+		}
+	case *ast.IfStatement:
+		exprs = []ast.Expression{s.Condition}
+	case *ast.ElseIfClause:
+		exprs = []ast.Expression{s.Condition}
+	case *ast.LoopConditionStatement:
+		exprs = []ast.Expression{s.Condition}
+	case *ast.ForStatement:
+		exprs = []ast.Expression{s.Variable, s.From, s.To, s.By}
+	case *ast.SwitchStatement:
+		exprs = []ast.Expression{s.Subject}
+		for _, clause := range s.Cases {
+			exprs = append(exprs, clause.Values...)
+		}
+	}
+
+	var accs []access
+	for _, expr := range exprs {
+		if expr == nil {
+			continue
+		}
+		ast.Inspect(expr, func(node ast.Node) bool {
+			ident, ok := node.(*ast.Identifier)
+			if !ok {
+				return true
+			}
+			sym, ok := f.l.table.Uses[ident]
+			if !ok || sym.Kind.Space() != symbols.DataSpace {
+				return true
+			}
+			// a LIST stands for all of its members
+			for _, member := range f.members(sym) {
+				accs = append(accs, access{sym: member, ident: ident, use: f.l.table.Accesses[ident]})
+			}
+			return true
+		})
+	}
+	return accs
+}
+
+// members returns the members of a LIST in source order, including those of the LISTs nested in it,
+// or sym itself if it is not a LIST
+func (f *flow) members(sym *symbols.Symbol) []*symbols.Symbol {
+	scope, ok := f.l.table.Scopes[sym.Node]
+	if sym.Kind != symbols.List || !ok {
+		return []*symbols.Symbol{sym}
+	}
+	var members []*symbols.Symbol
+	for _, member := range scope.Symbols() {
+		if member.Kind.Space() == symbols.DataSpace {
+			members = append(members, f.members(member)...)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return before(members[i].Token, members[j].Token)
+	})
+	return members
+}
+
+// routineWrites collects the variables every routine may assign, itself or by the routines it calls
+func (f *flow) routineWrites(graphs []*cfg.Graph) {
+	calls := map[string][]string{}
+	for _, g := range graphs {
+		if g.Node == nil {
+			continue
+		}
+		name := strings.ToUpper(g.Name)
+		writes := map[*symbols.Symbol]bool{}
+		for _, block := range g.Blocks {
+			for _, node := range block.Nodes {
+				for _, acc := range f.accesses[node] {
+					if acc.use.Writes() {
+						writes[acc.sym] = true
+					}
+				}
+			}
+			for _, call := range block.Calls {
+				calls[name] = append(calls[name], strings.ToUpper(call))
+			}
+		}
+		f.writes[name] = writes
+	}
+	for changed := true; changed; {
+		changed = false
+		for name, callees := range calls {
+			for _, callee := range callees {
+				for sym := range f.writes[callee] {
+					if !f.writes[name][sym] {
+						f.writes[name][sym] = true
+						changed = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// analyse computes the state at the start of every block of g reachable from its entry, then reports
+// the first read of every variable that has not been assigned on some path to it
+func (f *flow) analyse(g *cfg.Graph) {
+	in := map[*cfg.Block][]bool{g.Entry: f.entry(g)}
+	out := map[*cfg.Block][]bool{}
+	for changed := true; changed; {
+		changed = false
+		for _, block := range g.Blocks {
+			state := in[g.Entry]
+			if block != g.Entry {
+				state = meet(block.Preds, out)
+			}
+			if state == nil {
+				continue
+			}
+			in[block] = state
+			next := f.transfer(block, state, nil)
+			if !equal(next, out[block]) {
+				out[block] = next
+				changed = true
+			}
+		}
+	}
+
+	reported := map[*symbols.Symbol]bool{}
+	for _, block := range g.Blocks {
+		if state, ok := in[block]; ok {
+			f.transfer(block, state, func(acc access) {
+				if !reported[acc.sym] {
+					reported[acc.sym] = true
+					f.reportUninitialised(acc)
+				}
+			})
+		}
+	}
+}
+
+// entry returns the state at the start of g. The main line starts with the values of the declarations.
+// A routine may be called after any variable has been assigned, except for the local variables of an LROUTINE,
+// its parameters are assigned by the caller.
+func (f *flow) entry(g *cfg.Graph) []bool {
+	state := append([]bool(nil), f.initial...)
+	routine, ok := g.Node.(*ast.RoutineStatement)
+	if !ok {
+		return state
+	}
+	local := f.l.table.Scopes[routine]
+	for sym, i := range f.vars {
+		if !within(sym.Scope, local) {
+			state[i] = true
+		}
+	}
+	for _, param := range routine.Params {
+		if sym, ok := f.l.table.Uses[param]; ok {
+			for _, member := range f.members(sym) {
+				if i, ok := f.vars[member]; ok {
+					state[i] = true
+				}
+			}
+		}
+	}
+	return state
+}
+
+// within returns true if scope is local or nested in it
+func within(scope, local *symbols.Scope) bool {
+	for ; scope != nil && local != nil; scope = scope.Parent {
+		if scope == local {
+			return true
+		}
+	}
+	return false
+}
+
+// meet returns the variables assigned at the end of every predecessor whose state is known, nil if there is none
+func meet(preds []*cfg.Edge, out map[*cfg.Block][]bool) []bool {
+	var state []bool
+	for _, e := range preds {
+		from, ok := out[e.From]
+		if !ok {
+			continue
+		}
+		if state == nil {
+			state = append([]bool(nil), from...)
+			continue
+		}
+		for i := range state {
+			state[i] = state[i] && from[i]
+		}
+	}
+	return state
+}
+
+func equal(a, b []bool) bool {
+	if a == nil || len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// transfer returns the state at the end of block given the state at its start, calling uninitialised
+// for every read of a variable not assigned yet. A statement reads its operands before it assigns any.
+// A variable passed by reference may be assigned by the routine, it is not reported.
+func (f *flow) transfer(block *cfg.Block, start []bool, uninitialised func(acc access)) []bool {
+	state := append([]bool(nil), start...)
+	for _, node := range block.Nodes {
+		for _, acc := range f.accesses[node] {
+			if i, ok := f.vars[acc.sym]; ok && (acc.use == symbols.Read || acc.use == symbols.Update) && !state[i] && uninitialised != nil {
+				uninitialised(acc)
+			}
+		}
+		for _, acc := range f.accesses[node] {
+			if i, ok := f.vars[acc.sym]; ok && acc.use.Writes() {
+				state[i] = true
+			}
+		}
+		if call, ok := node.(*ast.CallStatement); ok && call.Target != nil {
+			for sym := range f.writes[strings.ToUpper(call.Target.Value)] {
+				if i, ok := f.vars[sym]; ok {
+					state[i] = true
+				}
+			}
+		}
+	}
+	return state
+}
+
+func (f *flow) reportUninitialised(acc access) {
+	name := acc.sym.Name
+	if !strings.EqualFold(acc.ident.Value, acc.sym.Name) {
+		name = fmt.Sprintf("member %s of LIST %s", acc.sym.Name, acc.ident.Value)
+	}
+	f.l.addWarning(acc.ident.Token, plbErrors.WarnUninitialised, fmt.Sprintf("%s is read before it is assigned on some path, %s",
+		name, declaredBy(acc.sym)))
+}
+
+// checkDeclarations reports the variables assigned but never read and the declarations never referred to.
+// A LIST is referred to if it or any of its members is, a member if it or the LIST is.
+func (f *flow) checkDeclarations(decls []*symbols.Symbol) {
+	direct := map[*symbols.Symbol]bool{}
+	for _, sym := range f.l.table.Uses {
+		direct[sym] = true
+	}
+	referenced := map[*symbols.Symbol]bool{}
+	for _, sym := range decls {
+		for list := sym; list != nil; list = list.List {
+			if direct[list] {
+				referenced[sym] = true
+			}
+		}
+		if direct[sym] {
+			for list := sym.List; list != nil; list = list.List {
+				referenced[list] = true
+			}
+		}
+	}
+
+	for _, sym := range decls {
+		uses := f.used[sym]
+		switch {
+		case !referenced[sym]:
+			f.l.addWarning(sym.Token, plbErrors.WarnUnusedData, fmt.Sprintf("%s is never used, %s", sym.Name, declaredBy(sym)))
+		case len(uses) > 0 && !uses[symbols.Read] && !uses[symbols.Update] && !uses[symbols.Address]:
+			f.l.addWarning(sym.Token, plbErrors.WarnNeverRead, fmt.Sprintf("%s is assigned but never read", sym.Name))
+		}
+	}
+}
+
+// declaredBy names the declaration of a symbol for messages, e.g. declared by DIM at main.pls 1:1
+func declaredBy(sym *symbols.Symbol) string {
+	tok := sym.Token
+	return fmt.Sprintf("declared by %s at %s %d:%d", sym.Verb(), tok.FileName, tok.Line, tok.Col)
+}
@@ -0,0 +1,268 @@
+// Package lint reports warnings about valid PL/B programs that are likely not doing what was intended,
+// using the control-flow graphs of the program and its resolved labels, e.g. unreachable statements
+// or variables read before a value is assigned to them.
+//
+// A warning can be suppressed by its code for the whole program, see WithSuppressed,
+// or for one line by a comment on the line before it:
+//
+//	. NOLINT W501
+//	         DISPLAY   "NEVER SHOWN"
+//
+// A NOLINT comment without codes suppresses every warning of the next line.
+package lint
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/cfg"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/resolver"
+	"PLB-Interpreter/symbols"
+	"PLB-Interpreter/tokens"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Linter runs the checks of the package on a program
+type Linter struct {
+	warnings []error
+	diag     plbErrors.Diagnostics
+
+	table      *symbols.Table
+	suppressed map[string]bool     // the codes suppressed for the whole program
+	sources    map[string][]string // the lines of the source files NOLINT comments are looked up in, by file name
+}
+
+// Option configures a Linter, see New
+type Option func(*Linter)
+
+// WithDiagnostics reports every warning of the linter to d, in addition to Warnings
+func WithDiagnostics(d plbErrors.Diagnostics) Option {
+	return func(l *Linter) {
+		l.diag = d
+	}
+}
+
+// WithSymbols uses a table the program has already been resolved to.
+// Without it Lint resolves the program itself, without reporting the errors of the resolver.
+func WithSymbols(table *symbols.Table) Option {
+	return func(l *Linter) {
+		l.table = table
+	}
+}
+
+// WithSuppressed suppresses the warnings with the given codes, e.g. plbErrors.WarnUnusedLabel
+func WithSuppressed(codes ...string) Option {
+	return func(l *Linter) {
+		for _, code := range codes {
+			l.suppressed[strings.ToUpper(code)] = true
+		}
+	}
+}
+
+// New creates a linter, the checks are run by Lint
+func New(opts ...Option) *Linter {
+	l := &Linter{diag: plbErrors.Discard, suppressed: map[string]bool{}, sources: map[string][]string{}}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Warnings returns true if there are any warnings indicated in the linter
+// If the boolean is true, the slice of warnings will be non-empty
+// If the boolean is false, the slice of warnings will be empty
+func (l *Linter) Warnings() (bool, []error) {
+	return len(l.warnings) > 0, l.warnings
+}
+
+func (l *Linter) addWarning(tok tokens.Token, code, msg string) {
+	if l.suppressed[code] || l.nolint(tok, code) {
+		return
+	}
+	newErr := plbErrors.NewPLBWarning(code, msg, tok.FileName, tok.Line, tok.Col, tok.LineTxt)
+	l.warnings = append(l.warnings, newErr)
+	l.diag.Warning(newErr)
+}
+
+// Lint runs every check on the given program
+func (l *Linter) Lint(prog *ast.Program) {
+	if l.table == nil {
+		l.table = resolver.New().Resolve(prog)
+	}
+	graphs := cfg.Build(prog)
+	for _, g := range graphs {
+		l.checkReachable(g)
+		if routine, ok := g.Node.(*ast.RoutineStatement); ok {
+			l.checkReturn(g, routine)
+		}
+	}
+	l.checkLabels()
+	l.checkData(graphs)
+}
+
+// checkReachable reports the first statement of every sequence of statements no path from the entry of g reaches.
+// The statements following it without a jump are part of the same sequence and not reported again.
+func (l *Linter) checkReachable(g *cfg.Graph) {
+	live := reachable(g)
+	for _, block := range g.Blocks {
+		if live[block] || len(block.Nodes) == 0 || continues(block, live) {
+			continue
+		}
+		node := block.Nodes[0]
+		l.addWarning(firstToken(node), plbErrors.WarnUnreachable, fmt.Sprintf("%s can never run", cfg.Text(node)))
+	}
+}
+
+// reachable returns the blocks of g a path from its entry leads to
+func reachable(g *cfg.Graph) map[*cfg.Block]bool {
+	live := map[*cfg.Block]bool{g.Entry: true}
+	work := []*cfg.Block{g.Entry}
+	for len(work) > 0 {
+		block := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, e := range block.Succs {
+			if !live[e.To] {
+				live[e.To] = true
+				work = append(work, e.To)
+			}
+		}
+	}
+	return live
+}
+
+// continues returns true if a dead block is entered from a dead block before it in the source,
+// i.e. by falling through or by a structured statement rather than by a jump
+func continues(block *cfg.Block, live map[*cfg.Block]bool) bool {
+	for _, e := range block.Preds {
+		switch e.Kind {
+		case cfg.Jump, cfg.Branch, cfg.Repeat, cfg.Trap:
+			continue
+		}
+		if !live[e.From] && len(e.From.Nodes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkReturn reports a routine that can reach the end of its statements, rather than leaving by RETURN or STOP
+func (l *Linter) checkReturn(g *cfg.Graph, routine *ast.RoutineStatement) {
+	if routine.Name == nil {
+		return
+	}
+	live := reachable(g)
+	for _, e := range g.Exit.Preds {
+		if !live[e.From] || leaves(e) {
+			continue
+		}
+		msg := fmt.Sprintf("%s %s can reach its end without RETURN", routine.Kind, routine.Name.Value)
+		if n := len(e.From.Nodes); n > 0 {
+			last := e.From.Nodes[n-1]
+			msg += fmt.Sprintf(", e.g. after %s at %s", cfg.Text(last), last.Pos())
+		}
+		l.addWarning(routine.Name.Token, plbErrors.WarnMissingReturn, msg)
+		return
+	}
+}
+
+// leaves returns true if an edge to the exit of a graph is taken by a RETURN, STOP or CHAIN,
+// not by falling off the end of the statements
+func leaves(e *cfg.Edge) bool {
+	if e.Kind == cfg.False || len(e.From.Nodes) == 0 {
+		return false
+	}
+	verb, ok := e.From.Nodes[len(e.From.Nodes)-1].(*ast.VerbStatement)
+	if !ok {
+		return false
+	}
+	switch verb.Verb {
+	case "RETURN", "STOP", "CHAIN":
+		return true
+	}
+	return false
+}
+
+// checkLabels reports the execution labels no statement refers to. Routines are not reported,
+// since they may be called from other programs.
+func (l *Linter) checkLabels() {
+	used := map[*symbols.Symbol]bool{}
+	for _, sym := range l.table.Uses {
+		used[sym] = true
+	}
+	for _, sym := range l.table.Global.Symbols() {
+		if sym.Kind == symbols.Label && !used[sym] {
+			l.addWarning(sym.Token, plbErrors.WarnUnusedLabel, fmt.Sprintf("label %s is never used", sym.Name))
+		}
+	}
+}
+
+// nolint returns true if the line before tok is a NOLINT comment suppressing code
+func (l *Linter) nolint(tok tokens.Token, code string) bool {
+	lines, ok := l.sources[tok.FileName]
+	if !ok {
+		if src, err := os.ReadFile(tok.FileName); err == nil {
+			lines = strings.Split(string(src), "\n")
+		}
+		l.sources[tok.FileName] = lines
+	}
+	if tok.Line < 2 || tok.Line-2 >= len(lines) {
+		return false
+	}
+	return suppresses(lines[tok.Line-2], code)
+}
+
+// suppresses returns true if line is a NOLINT comment for code, or for every code if it names none
+func suppresses(line, code string) bool {
+	text := strings.TrimSpace(line)
+	if text == "" || !strings.ContainsRune(".*+", rune(text[0])) {
+		return false
+	}
+	fields := strings.FieldsFunc(strings.ToUpper(text[1:]), func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	})
+	if len(fields) == 0 || fields[0] != "NOLINT" {
+		return false
+	}
+	if len(fields) == 1 {
+		return true
+	}
+	for _, field := range fields[1:] {
+		if field == code {
+			return true
+		}
+	}
+	return false
+}
+
+// firstToken returns the token a statement in a block starts with, its label if it has one
+func firstToken(node ast.Node) tokens.Token {
+	if stmt, ok := node.(ast.Statement); ok {
+		if label := ast.Label(stmt); label != nil {
+			return label.Token
+		}
+	}
+	switch s := node.(type) {
+	case *ast.VerbStatement:
+		return s.Token
+	case *ast.CallStatement:
+		return s.Token
+	case *ast.ListStatement:
+		return s.Token
+	case *ast.IfStatement:
+		return s.Token
+	case *ast.ElseIfClause:
+		return s.Token
+	case *ast.LoopStatement:
+		return s.Token
+	case *ast.LoopConditionStatement:
+		return s.Token
+	case *ast.ForStatement:
+		return s.Token
+	case *ast.SwitchStatement:
+		return s.Token
+	case *ast.BadStatement:
+		return s.Token
+	}
+	return tokens.Token{}
+}
@@ -0,0 +1,330 @@
+package lint
+
+import (
+	"PLB-Interpreter/parser"
+	"PLB-Interpreter/plbErrors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// lint parses and lints the given input, parser errors fail the test.
+// The warnings are described by their code and position, e.g. W501 3:5.
+func lint(t *testing.T, input string, opts ...Option) ([]string, []error) {
+	t.Helper()
+	prog, diag := parser.ParseReader(strings.NewReader(input), "test", parser.Options{})
+	if len(diag.Errors) > 0 {
+		t.Fatalf("parser errors: %v", diag.Errors)
+	}
+	l := New(opts...)
+	l.Lint(prog)
+	_, warnings := l.Warnings()
+	return describe(warnings), warnings
+}
+
+func describe(warnings []error) []string {
+	var out []string
+	for _, warning := range warnings {
+		w := warning.(*plbErrors.PLBError)
+		out = append(out, fmt.Sprintf("%s %d:%d", w.ErrorCode, w.LineNumber, w.Column))
+	}
+	return out
+}
+
+func TestLinter(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "every statement runs",
+			input: "TOP\n    DISPLAY \"X\"\n    GOTO TOP IF EQUAL\n    CALL SUB\n    STOP\nSUB ROUTINE\n    RETURN\n",
+		},
+		{
+			name:  "after GOTO",
+			input: "TOP\n    GOTO TOP\n    DISPLAY \"1\"\n    DISPLAY \"2\"\n",
+			want:  []string{"W501 3:5"},
+		},
+		{
+			name:  "after STOP",
+			input: "    STOP\n    DISPLAY \"1\"\n    IF EQUAL\n    DISPLAY \"2\"\n    ENDIF\n",
+			want:  []string{"W501 2:5"},
+		},
+		{
+			name:  "after a conditional GOTO",
+			input: "TOP\n    GOTO TOP IF EQUAL\n    DISPLAY \"1\"\n",
+		},
+		{
+			name:  "dead LOOP",
+			input: "    STOP\n    LOOP\n    DISPLAY \"1\"\n    REPEAT\n",
+			want:  []string{"W501 2:5"},
+		},
+		{
+			name:  "after RETURN in both branches",
+			input: "    CALL SUB\n    STOP\nSUB ROUTINE\n    IF EQUAL\n    RETURN\n    ELSE\n    RETURN\n    ENDIF\n    DISPLAY \"1\"\n",
+			want:  []string{"W501 9:5"},
+		},
+		{
+			name:  "label after STOP",
+			input: "    GOTO DONE IF EQUAL\n    STOP\nDONE\n    DISPLAY \"1\"\n",
+		},
+		{
+			name:  "labelled statement nothing jumps to",
+			input: "    STOP\nDONE DISPLAY \"1\"\n",
+			want:  []string{"W501 2:1", "W503 2:1"},
+		},
+		{
+			name:  "TRAP handler",
+			input: "    TRAP FAILED IF RANGE\n    STOP\nFAILED\n    STOP\n",
+		},
+		{
+			name:  "BRANCH targets",
+			input: "N FORM \"1\"\n    BRANCH N OF ONE,TWO\n    STOP\nONE STOP\nTWO STOP\nTHREE STOP\n",
+			want:  []string{"W501 6:1", "W503 6:1"},
+		},
+		{
+			name:  "routine falls off its end",
+			input: "    CALL SUB\n    STOP\nSUB ROUTINE\n    DISPLAY \"1\"\n",
+			want:  []string{"W502 3:1"},
+		},
+		{
+			name:  "routine falls off one branch",
+			input: "    CALL SUB\n    STOP\nSUB LROUTINE\n    IF EQUAL\n    RETURN\n    ENDIF\n",
+			want:  []string{"W502 3:1"},
+		},
+		{
+			name:  "conditional RETURN last",
+			input: "    CALL SUB\n    STOP\nSUB ROUTINE\n    RETURN IF EQUAL\n",
+			want:  []string{"W502 3:1"},
+		},
+		{
+			name:  "routine ends by STOP or GOTO",
+			input: "    CALL SUB\n    CALL OTHER\n    STOP\nSUB ROUTINE\n    STOP IF EQUAL\n    GOTO OUT\nOTHER ROUTINE\nOUT RETURN\n",
+		},
+		{
+			name:  "routine with two exits",
+			input: "    CALL SUB\n    STOP\nSUB LROUTINE\nT DIM 1\n    GOTO X IF EQUAL\n    RETURN\nX\n    MOVE \"1\" TO T\n    DISPLAY T\n    RETURN\n",
+		},
+		{
+			name:  "routine loops forever",
+			input: "    CALL SUB\n    STOP\nSUB ROUTINE\n    LOOP\n    RETURN IF EQUAL\n    REPEAT\n",
+		},
+		{
+			name:  "unused labels",
+			input: "TOP\n    DISPLAY \"1\"\nUSED\n    CALL USED IF EQUAL\n    STOP\nSUB ROUTINE\n    RETURN\n",
+			want:  []string{"W503 1:1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings := lint(t, tt.input)
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("got warnings %v, want %v", warnings, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinter_Messages(t *testing.T) {
+	_, warnings := lint(t, "    CALL SUB\n    STOP\nSUB ROUTINE\n    DISPLAY \"1\"\n    GOTO TOP\n    DISPLAY \"2\"\nTOP RETURN IF EQUAL\n")
+	want := []string{
+		`Warning W501: DISPLAY "2" can never run`,
+		`Warning W502: ROUTINE SUB can reach its end without RETURN, e.g. after RETURN IF EQUAL at test 7:1`,
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("got warnings %v, want %d", warnings, len(want))
+	}
+	for i, warning := range warnings {
+		if !strings.HasPrefix(warning.Error(), want[i]+"\n") {
+			t.Errorf("warning %d is %q, want %q", i, warning.Error(), want[i])
+		}
+	}
+	rendered := "Warning W501: DISPLAY \"2\" can never run\nLocation: test 6:5\n    DISPLAY \"2\"\n    ^\n"
+	if warnings[0].Error() != rendered {
+		t.Errorf("got rendered warning %q, want %q", warnings[0].Error(), rendered)
+	}
+}
+
+func TestLinter_Suppressed(t *testing.T) {
+	input := "    STOP\nDONE DISPLAY \"1\"\n"
+	got, _ := lint(t, input, WithSuppressed("w503"))
+	if strings.Join(got, ", ") != "W501 2:1" {
+		t.Errorf("got warnings %v, want W501 only", got)
+	}
+
+	collector := &plbErrors.Collector{}
+	got, _ = lint(t, input, WithSuppressed(plbErrors.WarnUnreachable, plbErrors.WarnUnusedLabel), WithDiagnostics(collector))
+	if len(got) != 0 || len(collector.Warnings) != 0 {
+		t.Errorf("got warnings %v and reported %v, want none", got, collector.Warnings)
+	}
+}
+
+func TestLinter_NOLINT(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "every code",
+			input: "    STOP\n. NOLINT\nDONE DISPLAY \"1\"\n",
+		},
+		{
+			name:  "one code",
+			input: "    STOP\n* nolint W503\nDONE DISPLAY \"1\"\n",
+			want:  []string{"W501 3:1"},
+		},
+		{
+			name:  "list of codes",
+			input: "    STOP\n. NOLINT W501,W503\nDONE DISPLAY \"1\"\n",
+		},
+		{
+			name:  "only the next line",
+			input: ". NOLINT\n    STOP\nDONE DISPLAY \"1\"\n",
+			want:  []string{"W501 3:1", "W503 3:1"},
+		},
+		{
+			name:  "not a comment",
+			input: "    STOP\n    DISPLAY \"NOLINT\"\n    DISPLAY \"1\"\n",
+			want:  []string{"W501 2:5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.plb")
+			if err := os.WriteFile(path, []byte(tt.input), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			prog, diag := parser.ParseFile(path, parser.Options{})
+			if len(diag.Errors) > 0 {
+				t.Fatalf("parser errors: %v", diag.Errors)
+			}
+			l := New()
+			l.Lint(prog)
+			_, warnings := l.Warnings()
+			if got := describe(warnings); strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("got warnings %v, want %v", warnings, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinter_DataFlow(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "read before any assignment",
+			input: "N FORM 2\n    DISPLAY N\n",
+			want:  []string{"W504 2:13"},
+		},
+		{
+			name:  "assigned on one branch",
+			input: "N FORM 2\n    IF EQUAL\n    MOVE \"1\" TO N\n    ENDIF\n    DISPLAY N\n",
+			want:  []string{"W504 5:13"},
+		},
+		{
+			name:  "assigned on every branch",
+			input: "N FORM 2\n    IF EQUAL\n    MOVE \"1\" TO N\n    ELSE\n    MOVE \"2\" TO N\n    ENDIF\n    DISPLAY N\n",
+		},
+		{
+			name:  "initial values",
+			input: "A INIT \"X\"\nN FORM \"1\"\n    DISPLAY A,N\n",
+		},
+		{
+			name:  "ADD reads its destination",
+			input: "N FORM 2\n    ADD \"1\" TO N\n    DISPLAY N\n",
+			want:  []string{"W504 2:16"},
+		},
+		{
+			name:  "assigned after the read in a loop",
+			input: "N FORM 2\nTOP\n    DISPLAY N\n    MOVE \"1\" TO N\n    GOTO TOP\n",
+			want:  []string{"W504 3:13"},
+		},
+		{
+			name:  "KEYIN and FOR assign",
+			input: "A DIM 5\nI FORM 2\n    KEYIN A\n    FOR I FROM 1 TO 3\n    DISPLAY A,I\n    REPEAT\n",
+		},
+		{
+			name:  "LIST read with an unassigned member",
+			input: "L LIST\nA DIM 5\nB FORM 2\n    LISTEND\n    MOVE \"X\" TO A\n    DISPLAY L\n",
+			want:  []string{"W504 6:13"},
+		},
+		{
+			name:  "LIST assigned by READ",
+			input: "F FILE\nL LIST\nA DIM 5\nB FORM 2\n    LISTEND\n    OPEN F,\"X\"\n    READ F,\"1\",L\n    DISPLAY A,B\n",
+		},
+		{
+			name:  "assigned by a called routine",
+			input: "N FORM 2\n    CALL SET\n    DISPLAY N\n    STOP\nSET ROUTINE\n    CALL INNER\n    RETURN\nINNER ROUTINE\n    MOVE \"1\" TO N\n    RETURN\n",
+		},
+		{
+			name:  "routine reads a global",
+			input: "N FORM 2\n    MOVE \"1\" TO N\n    CALL SHOW\n    STOP\nSHOW ROUTINE\n    DISPLAY N\n    RETURN\n",
+		},
+		{
+			name:  "local variable of an LROUTINE",
+			input: "    CALL SHOW\n    STOP\nSHOW LROUTINE\nN FORM 2\n    DISPLAY N\n    RETURN\n",
+			want:  []string{"W504 5:13"},
+		},
+		{
+			name:  "parameter of an LROUTINE",
+			input: "A DIM 5\n    MOVE \"X\" TO A\n    CALL SHOW USING A\n    STOP\nSHOW LROUTINE P\nP DIM 5\n    DISPLAY P\n    RETURN\n",
+		},
+		{
+			name:  "pointers",
+			input: "A DIM 5\nP DIM ^\n    MOVEADR A TO P\n    DISPLAY P\n",
+		},
+		{
+			name:  "assigned but never read",
+			input: "A DIM 5\n    MOVE \"X\" TO A\n",
+			want:  []string{"W505 1:1"},
+		},
+		{
+			name:  "never used",
+			input: "A DIM 5\nC EQU 3\nF FILE\nP DIM ^\n",
+			want:  []string{"W506 1:1", "W506 2:1", "W506 3:1", "W506 4:1"},
+		},
+		{
+			name:  "LIST used by a member",
+			input: "L LIST\nA DIM 5\nB DIM 5\n    LISTEND\n    MOVE \"X\" TO A\n    DISPLAY A\n",
+			want:  []string{"W506 3:1"},
+		},
+		{
+			name:  "LIST used as a whole",
+			input: "L LIST\nA DIM 5\nB DIM 5\n    LISTEND\n    KEYIN L\n    DISPLAY L\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings := lint(t, tt.input)
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("got warnings %v, want %v", warnings, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinter_DataFlowMessages(t *testing.T) {
+	_, warnings := lint(t, "L LIST\nA DIM 5\nB FORM 2\n    LISTEND\n    MOVE \"X\" TO A\n    DISPLAY L\nC DIM 1\n")
+	want := []string{
+		`Warning W504: member B of LIST L is read before it is assigned on some path, declared by FORM at test 3:1`,
+		`Warning W506: C is never used, declared by DIM at test 7:1`,
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("got warnings %v, want %d", warnings, len(want))
+	}
+	for i, warning := range warnings {
+		if !strings.HasPrefix(warning.Error(), want[i]+"\n") {
+			t.Errorf("warning %d is %q, want %q", i, warning.Error(), want[i])
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"PLB-Interpreter/listing"
+	"PLB-Interpreter/parser"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// listCommand prints the listing of a file and the files it includes, with the storage of its variables
+func listCommand(args []string) int {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	dialect := flags.String("dialect", "", "PL/B dialect, SUNBELT or ANSI")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	path := sourcePath(flags.Args())
+	prog, diag := parser.ParseFile(path, parser.Options{Dialect: *dialect})
+	for _, err := range diag.Errors {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if err := listing.Write(os.Stdout, prog, path); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(diag.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
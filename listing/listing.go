@@ -0,0 +1,67 @@
+// Package listing writes the listing of a program: every line of its source and of the files it includes,
+// numbered and with the depth of the INCLUDE it is read by. The lines declaring a variable give its offset
+// in the data area and the bytes it takes, see package storage, and the size of the data area ends the listing.
+package listing
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/storage"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// line is a line of a source file
+type line struct {
+	file string
+	num  int
+}
+
+// Write writes the listing of prog, parsed from file. The source lines are read again from the files.
+func Write(w io.Writer, prog *ast.Program, file string) error {
+	layout := storage.Layout(prog)
+	items := map[line]*storage.Item{}
+	for _, item := range layout.Items {
+		items[line{item.Decl.Token.FileName, item.Decl.Token.Line}] = item
+	}
+	includes := map[line]*ast.IncludeStatement{}
+	ast.Inspect(prog, func(node ast.Node) bool {
+		if inc, ok := node.(*ast.IncludeStatement); ok && inc.Body != nil {
+			includes[line{inc.Token.FileName, inc.Token.Line}] = inc
+		}
+		return true
+	})
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "%6s %3s %8s %6s  %s\n", "LINE", "INC", "OFFSET", "SIZE", "SOURCE")
+	var list func(file string, depth int) error
+	list = func(file string, depth int) error {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(strings.TrimSuffix(string(src), "\n"), "\n")
+		for i, text := range lines {
+			at := line{file, i + 1}
+			text = strings.TrimRight(text, "\r")
+			offset, size := "", ""
+			if item, ok := items[at]; ok {
+				offset, size = fmt.Sprint(item.Offset), fmt.Sprint(item.Size)
+			}
+			fmt.Fprintln(out, strings.TrimRight(fmt.Sprintf("%6d %3d %8s %6s  %s", at.num, depth, offset, size, text), " "))
+			if inc, ok := includes[at]; ok {
+				if err := list(inc.File, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := list(file, 0); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\nData area: %d bytes in %d variables\n", layout.Total, len(layout.Items))
+	return out.Flush()
+}
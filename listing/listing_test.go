@@ -0,0 +1,46 @@
+package listing
+
+import (
+	"PLB-Interpreter/parser"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.pls": ". CUSTOMER RECORD\nID      FORM    5\n        INCLUDE rec.pls\nTOTAL   FORM    7.2\n        STOP\n",
+		"rec.pls":  "NAME    DIM     30\nFLAG    INIT    \"Y\"\nMAX     EQU     10\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "main.pls")
+	prog, diag := parser.ParseFile(path, parser.Options{})
+	if len(diag.Errors) > 0 {
+		t.Fatalf("parser errors: %v", diag.Errors)
+	}
+	var out bytes.Buffer
+	if err := Write(&out, prog, path); err != nil {
+		t.Fatal(err)
+	}
+	want := `  LINE INC   OFFSET   SIZE  SOURCE
+     1   0                  . CUSTOMER RECORD
+     2   0        0      5  ID      FORM    5
+     3   0                          INCLUDE rec.pls
+     1   1        5     33  NAME    DIM     30
+     2   1       38      4  FLAG    INIT    "Y"
+     3   1                  MAX     EQU     10
+     4   0       42     10  TOTAL   FORM    7.2
+     5   0                          STOP
+
+Data area: 52 bytes in 4 variables
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
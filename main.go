@@ -1,44 +1,110 @@
 package main
 
 import (
-	"PLB-Interpreter/lexer"
 	"PLB-Interpreter/parser"
-	"bufio"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/symbols"
+	"flag"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 )
 
+// command runs a subcommand of plb with the arguments following its name and returns the exit status
+type command func(args []string) int
+
+// commands are the subcommands of plb by name
+var commands = map[string]command{
+	"calls":  callsCommand,
+	"cfg":    cfgCommand,
+	"check":  checkCommand,
+	"fmt":    formatCommand,
+	"lint":   lintCommand,
+	"list":   listCommand,
+	"parse":  parseCommand,
+	"record": recordCommand,
+	"xref":   xrefCommand,
+}
+
 func main() {
-	path := "examples/strings.plb"
-	file, err := os.Open(path)
-	if err != nil {
-		panic(err)
+	args := os.Args[1:]
+	if len(args) > 0 {
+		if cmd, ok := commands[args[0]]; ok {
+			os.Exit(cmd(args[1:]))
+		}
+		if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+			usage()
+			return
+		}
+	}
+	// without a command the file is parsed and printed, as before there were commands
+	os.Exit(parseCommand(args))
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
 	}
-	defer file.Close()
-
-	reader := bufio.NewReader(file)
-	lex := lexer.New(reader, path)
-	pars := parser.New(lex)
-
-	prog := pars.ParseProgram()
-
-	fmt.Println(prog)
-
-	//for {
-	//	tok, err := lex.NextToken()
-	//
-	//	if tok.Type == tokens.COMMENT || tok.Type == tokens.NEWLINE || tok.Type == tokens.NULLLINE {
-	//		fmt.Println(tok)
-	//	} else {
-	//		fmt.Print(tok)
-	//	}
-	//
-	//	if err != nil {
-	//		panic(err)
-	//	}
-	//	if tok.Type == tokens.EOF {
-	//		break
-	//	}
-	//}
+	sort.Strings(names)
+	fmt.Fprintln(os.Stderr, "usage: plb <command> [flags] [file]")
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, name := range names {
+		fmt.Fprintln(os.Stderr, "  "+name)
+	}
+	fmt.Fprintln(os.Stderr, `run "plb <command> -h" for the flags of a command`)
+}
+
+// defines are the constants given by -define NAME=VALUE, the flag may be repeated
+type defines map[string]string
+
+func (d defines) String() string {
+	pairs := make([]string, 0, len(d))
+	for name, value := range d {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
 
+func (d defines) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("%q is not NAME=VALUE", s)
+	}
+	d[name] = value
+	return nil
+}
+
+// defineFlag adds -define to the flags of a command that resolves names, see resolveOptions
+func defineFlag(flags *flag.FlagSet) defines {
+	d := defines{}
+	flags.Var(d, "define", "constant NAME=VALUE the program can refer to like an EQU, may be repeated")
+	return d
+}
+
+// resolveOptions returns the parser options of a command and the environment the defines are in,
+// the environment has to be given to the resolver so the program can refer to them
+func resolveOptions(dialect string, d defines) (parser.Options, *symbols.Environment) {
+	env := symbols.NewEnvironment()
+	return parser.Options{Dialect: dialect, Defines: d, Symbols: env}, env
+}
+
+// unreadable returns true if the file given to a command cannot be read, there is nothing to print then
+func unreadable(diag *plbErrors.Collector) bool {
+	for _, err := range diag.Errors {
+		if err.ErrorCode == plbErrors.ErrCannotRead {
+			return true
+		}
+	}
+	return false
+}
+
+// sourcePath returns the file named by the remaining arguments of a command, examples/test.plb if there is none
+func sourcePath(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return "examples/test.plb"
 }
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"PLB-Interpreter/plbErrors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		args  []string
+		want  int
+	}{
+		{
+			name:  "no errors",
+			input: "A DIM 10\nN FORM 2\n    MOVE \"X\" TO A\n    ADD 1 TO N\n    CALL SUB USING A\n    STOP\nSUB ROUTINE A\n    RETURN\n",
+			want:  0,
+		},
+		{
+			name:  "parser error",
+			input: "    FROBNICATE A\n",
+			want:  1,
+		},
+		{
+			name:  "undeclared label",
+			input: "    GOTO NOWHERE\n",
+			want:  1,
+		},
+		{
+			name:  "operand of the wrong class",
+			input: "A DIM 10\n    ADD A TO A\n",
+			want:  1,
+		},
+		{
+			name:  "argument count",
+			input: "A DIM 10\n    CALL SUB USING A\n    STOP\nSUB ROUTINE\n    RETURN\n",
+			want:  1,
+		},
+		{
+			name:  "define",
+			input: "A FORM 2\n    MOVE LIMIT TO A\n",
+			args:  []string{"-define", "LIMIT=10"},
+			want:  0,
+		},
+		{
+			name:  "define missing",
+			input: "A FORM 2\n    MOVE LIMIT TO A\n",
+			want:  1,
+		},
+		{
+			name:  "define without a value",
+			input: "    STOP\n",
+			args:  []string{"-define", "LIMIT"},
+			want:  2,
+		},
+		{
+			name:  "unknown flag",
+			args:  []string{"-unknown"},
+			input: "    STOP\n",
+			want:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeSource(t, tt.input)
+			if got := checkCommand(append(tt.args, path)); got != tt.want {
+				t.Errorf("checkCommand() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		args  []string
+		want  int
+	}{
+		{
+			name:  "no warnings",
+			input: "A DIM 10\n    KEYIN A\n    DISPLAY A\n    STOP\n",
+			want:  0,
+		},
+		{
+			name:  "warning",
+			input: "    STOP\n    DISPLAY \"X\"\n",
+			want:  1,
+		},
+		{
+			name:  "suppressed warning",
+			input: "    STOP\n    DISPLAY \"X\"\n",
+			args:  []string{"-suppress", "W501"},
+			want:  0,
+		},
+		{
+			name:  "undeclared label",
+			input: "    GOTO NOWHERE\n",
+			want:  1,
+		},
+		{
+			name:  "define",
+			input: "A FORM 2\n    MOVE LIMIT TO A\n    DISPLAY A\n    STOP\n",
+			args:  []string{"-define", "LIMIT=10"},
+			want:  0,
+		},
+		{
+			name:  "duplicate declaration",
+			input: "A DIM 10\nA DIM 5\n    KEYIN A\n    DISPLAY A\n    STOP\n",
+			want:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeSource(t, tt.input)
+			if got := lintCommand(append(tt.args, path)); got != tt.want {
+				t.Errorf("lintCommand() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommands_MissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.pls")
+	for _, name := range []string{"calls", "cfg", "check", "lint", "parse", "xref"} {
+		t.Run(name, func(t *testing.T) {
+			var status int
+			stdout, stderr := capture(t, func() {
+				status = commands[name]([]string{path})
+			})
+			if status != 1 {
+				t.Errorf("exit status %d, want 1", status)
+			}
+			if stdout != "" {
+				t.Errorf("printed %q, want nothing for a file that cannot be read", stdout)
+			}
+			if !strings.Contains(stderr, "Error "+plbErrors.ErrCannotRead) || !strings.Contains(stderr, "Location: "+path+"\n") ||
+				strings.Contains(stderr, "PANIC") {
+				t.Errorf("got errors %q, want %s with the location of the file", stderr, plbErrors.ErrCannotRead)
+			}
+		})
+	}
+}
+
+// capture runs fn and returns what it printed to the standard output and error
+func capture(t *testing.T, fn func()) (string, string) {
+	t.Helper()
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	files := make([]*os.File, 2)
+	for i := range files {
+		f, err := os.CreateTemp(t.TempDir(), "out")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		files[i] = f
+	}
+	os.Stdout, os.Stderr = files[0], files[1]
+	fn()
+	var out [2]string
+	for i, f := range files {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[i] = string(b)
+	}
+	return out[0], out[1]
+}
+
+// writeSource writes input to a file in a temporary directory and returns its path
+func writeSource(t *testing.T, input string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "main.pls")
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/parser"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// parseCommand parses a file and prints the program, or its JSON encoding with --json.
+// The program is printed even if there are errors, so tools get the partial AST.
+func parseCommand(args []string) int {
+	flags := flag.NewFlagSet("parse", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the AST as JSON instead of PL/B")
+	dialect := flags.String("dialect", "", "PL/B dialect, SUNBELT or ANSI")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	prog, diag := parser.ParseFile(sourcePath(flags.Args()), parser.Options{Dialect: *dialect})
+	for _, err := range diag.Errors {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	if *asJSON {
+		if err := ast.EncodeJSON(os.Stdout, prog); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	} else {
+		fmt.Print(prog)
+	}
+	if len(diag.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
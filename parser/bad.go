@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/tokens"
+	"strings"
+)
+
+// badExpression skips the rest of the current line and returns a BadExpression spanning it from start
+func (p *Parser) badExpression(start tokens.Token) *ast.BadExpression {
+	p.skipLine()
+	return &ast.BadExpression{Token: start, EndToken: p.badEnd(start), Text: strings.TrimSpace(spanText(start, p.peekToken))}
+}
+
+// badOperand skips the tokens of an operand up to the next separator, the end of the line or a trailing IF,
+// and returns a BadExpression spanning them from start
+func (p *Parser) badOperand(start tokens.Token) *ast.BadExpression {
+	for !p.peekTokenEndsLine() && !p.peekTokenIs(tokens.COMMA) && !p.peekTokenIs(tokens.PREPOSITION) && !p.peekTokenIsKeyword("IF") {
+		p.nextToken()
+	}
+	return &ast.BadExpression{Token: start, EndToken: p.badEnd(start), Text: strings.TrimSpace(spanText(start, p.peekToken))}
+}
+
+// badStatement returns a BadStatement spanning the line from start up to the current token
+func (p *Parser) badStatement(start tokens.Token) *ast.BadStatement {
+	return &ast.BadStatement{Token: start, EndToken: p.badEnd(start), Text: spanText(start, p.peekToken)}
+}
+
+// badEnd returns the last token of a bad node starting at start.
+// A parse function that failed may have stopped on the end of the line, which is not part of the node.
+func (p *Parser) badEnd(start tokens.Token) tokens.Token {
+	if !p.curTokenIs(tokens.NEWLINE) && !p.curTokenIs(tokens.EOF) {
+		return p.curToken
+	}
+	if p.prevToken.Line == start.Line && p.prevToken.Col >= start.Col {
+		return p.prevToken
+	}
+	return start
+}
+
+// spanText returns the source text from the start token up to, but excluding, the next token.
+// If the next token is on another line, the rest of the line is returned.
+func spanText(start, next tokens.Token) string {
+	line := strings.TrimRight(start.LineTxt, "\r\n")
+	from := start.Col - 1
+	if from < 0 || from > len(line) {
+		return ""
+	}
+	to := len(line)
+	if next.Line == start.Line && next.FileName == start.FileName && next.Col-1 >= from && next.Col-1 < to {
+		to = next.Col - 1
+	}
+	return strings.TrimRight(line[from:to], " \t")
+}
+
+// isBad returns true if expr is a BadExpression
+func isBad(expr ast.Expression) bool {
+	_, ok := expr.(*ast.BadExpression)
+	return ok
+}
@@ -0,0 +1,277 @@
+package parser
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/tokens"
+	"fmt"
+	"strings"
+)
+
+// blockClosers maps the verbs continuing or closing a structured block to the verb opening that block
+var blockClosers = map[string]string{
+	"ELSEIF":    "IF",
+	"ELSE":      "IF",
+	"ENDIF":     "IF",
+	"REPEAT":    "LOOP or FOR",
+	"CASE":      "SWITCH",
+	"DEFAULT":   "SWITCH",
+	"ENDSWITCH": "SWITCH",
+}
+
+// lineVerb returns the upper-cased verb of the line starting at the current token,
+// or an empty string if the current token does not start an unlabelled verb line
+func (p *Parser) lineVerb() string {
+	if p.curTokenIs(tokens.WHITESPACE) && p.peekTokenIs(tokens.IDENT) {
+		return strings.ToUpper(p.peekToken.Literal)
+	}
+	return ""
+}
+
+// location formats the position of a token for use in error messages
+func location(tok tokens.Token) string {
+	return fmt.Sprintf("%s %d:%d", tok.FileName, tok.Line, tok.Col)
+}
+
+// parseBlock parses the statements of a block until a line whose verb is one of closers.
+// The current token has to be the last token of the line opening the block, clause is the verb
+// token of that line and opener the verb token of the enclosing structured statement.
+// After returning, the current token is the first token of the closing line.
+//
+// A closing verb belonging to an enclosing block, or the end of the file, leaves the block unterminated.
+// The partial block is returned and the parser resumes at the start of that line,
+// so the enclosing block can still be closed by it.
+func (p *Parser) parseBlock(clause, opener tokens.Token, closers ...string) *ast.BlockStatement {
+	block := &ast.BlockStatement{Token: clause, Statements: []ast.Statement{}}
+	openVerb := strings.ToUpper(opener.Literal)
+	expected := strings.Join(closers, " or ")
+
+	p.openBlocks = append(p.openBlocks, closers)
+	defer func() { p.openBlocks = p.openBlocks[:len(p.openBlocks)-1] }()
+
+	p.nextToken()
+	for {
+		if p.curTokenIs(tokens.EOF) {
+			p.addError(plbErrors.ErrUnterminatedBlock, fmt.Sprintf("%s opened at %s is not closed, expected %s before the end of the file",
+				openVerb, location(opener), expected))
+			p.resume = true
+			return block
+		}
+		if verb := p.lineVerb(); verb != "" {
+			if contains(closers, verb) {
+				return block
+			}
+			if _, ok := blockClosers[verb]; ok {
+				if p.closesEnclosingBlock(verb) {
+					p.addErrorAt(p.peekToken, plbErrors.ErrUnterminatedBlock, fmt.Sprintf("%s opened at %s is not closed, expected %s before %s",
+						openVerb, location(opener), expected, verb))
+					p.resume = true
+					return block
+				}
+				start := p.curToken
+				p.nextToken()
+				p.addError(plbErrors.ErrUnmatchedBlock, fmt.Sprintf("%s does not match %s opened at %s, expected %s",
+					verb, openVerb, location(opener), expected))
+				p.skipLine()
+				block.Statements = append(block.Statements, p.badStatement(start))
+				p.nextLine()
+				continue
+			}
+		}
+
+		stmt := p.parseStatement()
+		if stmt != nil {
+			block.Statements = append(block.Statements, stmt)
+		}
+		p.nextLine()
+	}
+}
+
+// closesEnclosingBlock returns true if the verb continues or closes one of the blocks enclosing the innermost one
+func (p *Parser) closesEnclosingBlock(verb string) bool {
+	for _, closers := range p.openBlocks[:len(p.openBlocks)-1] {
+		if contains(closers, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// finishLine checks that the line of a block verb ends after its operands, ok tells whether the operands were valid.
+// A malformed line is skipped, so the block below it is still parsed and its closing verb does not cause further errors.
+func (p *Parser) finishLine(ok bool) {
+	if !ok || !p.expectLineEnd() {
+		p.skipLine()
+	}
+}
+
+// parseBlockCondition parses the condition of IF, ELSEIF, WHILE or UNTIL and finishes the line.
+// A condition that cannot be parsed becomes a BadExpression, so the block is still kept.
+func (p *Parser) parseBlockCondition() ast.Expression {
+	start := p.peekToken
+	cond := p.parseCondition()
+	if cond == nil {
+		cond = p.badExpression(start)
+	}
+	p.finishLine(!isBad(cond))
+	return cond
+}
+
+// parseIfStatement parses an IF block with its ELSEIF and ELSE clauses, the current token has to be the IF
+func (p *Parser) parseIfStatement(label *ast.Identifier) ast.Statement {
+	stmt := &ast.IfStatement{Token: p.curToken, Label: label}
+	stmt.Condition = p.parseBlockCondition()
+	stmt.Consequence = p.parseBlock(stmt.Token, stmt.Token, "ELSEIF", "ELSE", "ENDIF")
+
+	for !p.resume {
+		p.nextToken()
+		switch strings.ToUpper(p.curToken.Literal) {
+		case "ELSEIF":
+			clause := &ast.ElseIfClause{Token: p.curToken}
+			clause.Condition = p.parseBlockCondition()
+			clause.Body = p.parseBlock(clause.Token, stmt.Token, "ELSEIF", "ELSE", "ENDIF")
+			stmt.ElseIfs = append(stmt.ElseIfs, clause)
+		case "ELSE":
+			clause := p.curToken
+			p.finishLine(true)
+			stmt.Alternative = p.parseBlock(clause, stmt.Token, "ENDIF")
+		default:
+			stmt.EndToken = p.curToken
+			p.finishLine(true)
+			return stmt
+		}
+	}
+	return stmt
+}
+
+// parseLoopStatement parses a LOOP ... REPEAT block, the current token has to be the LOOP
+func (p *Parser) parseLoopStatement(label *ast.Identifier) ast.Statement {
+	stmt := &ast.LoopStatement{Token: p.curToken, Label: label}
+	p.finishLine(true)
+	stmt.Body = p.parseLoopBody(stmt.Token)
+	if !p.resume {
+		p.nextToken()
+		stmt.EndToken = p.curToken
+		p.finishLine(true)
+	}
+	return stmt
+}
+
+// parseForStatement parses a FOR ... REPEAT block, the current token has to be the FOR.
+// The counter, start, end and optional step are separated either by FROM, TO and BY or by commas.
+func (p *Parser) parseForStatement(label *ast.Identifier) ast.Statement {
+	stmt := &ast.ForStatement{Token: p.curToken, Label: label}
+	start := p.peekToken
+	if !p.parseForHeader(stmt) {
+		// keep the loop body, the whole header becomes the counter
+		stmt.Variable, stmt.From, stmt.To, stmt.By = p.badExpression(start), nil, nil, nil
+	}
+	p.finishLine(!isBad(stmt.Variable))
+	stmt.Body = p.parseLoopBody(stmt.Token)
+	if !p.resume {
+		p.nextToken()
+		stmt.EndToken = p.curToken
+		p.finishLine(true)
+	}
+	return stmt
+}
+
+// parseForHeader parses the counter, start, end and step of a FOR
+func (p *Parser) parseForHeader(stmt *ast.ForStatement) bool {
+	var operands []ast.Expression
+	var seps []tokens.Token
+	if !p.parseOperands(&operands, &seps, false) {
+		return false
+	}
+	if len(operands) != 3 && len(operands) != 4 {
+		p.addErrorAt(stmt.Token, plbErrors.ErrOperandCount, fmt.Sprintf("FOR expects a counter, a start, an end and an optional step, got %d operands", len(operands)))
+		return false
+	}
+	for i, want := range []string{"FROM", "TO", "BY"}[:len(seps)] {
+		sep := seps[i]
+		if sep.Type == tokens.PREPOSITION && strings.ToUpper(sep.Literal) != want {
+			p.addErrorAt(sep, plbErrors.ErrUnexpectedToken, fmt.Sprintf("expected %s in FOR, got %s instead", want, strings.ToUpper(sep.Literal)))
+			return false
+		}
+	}
+	stmt.Variable, stmt.From, stmt.To = operands[0], operands[1], operands[2]
+	if len(operands) == 4 {
+		stmt.By = operands[3]
+	}
+	return true
+}
+
+// parseLoopBody parses the body of a LOOP or FOR up to its REPEAT
+func (p *Parser) parseLoopBody(opener tokens.Token) *ast.BlockStatement {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+	return p.parseBlock(opener, opener, "REPEAT")
+}
+
+// parseLoopConditionStatement parses a WHILE or UNTIL inside a loop body, the current token has to be the verb
+func (p *Parser) parseLoopConditionStatement(label *ast.Identifier) ast.Statement {
+	stmt := &ast.LoopConditionStatement{Token: p.curToken, Label: label, Verb: strings.ToUpper(p.curToken.Literal)}
+	if p.loopDepth == 0 {
+		p.addError(plbErrors.ErrMisplacedStatement, fmt.Sprintf("%s outside of a LOOP or FOR", stmt.Verb))
+		return nil
+	}
+	stmt.Condition = p.parseBlockCondition()
+	return stmt
+}
+
+// parseSwitchStatement parses a SWITCH block with its CASE and DEFAULT clauses, the current token has to be the SWITCH
+func (p *Parser) parseSwitchStatement(label *ast.Identifier) ast.Statement {
+	stmt := &ast.SwitchStatement{Token: p.curToken, Label: label}
+	start := p.peekToken
+	if p.peekTokenEndsLine() {
+		p.addError(plbErrors.ErrOperandCount, "SWITCH expects a value to compare")
+	} else {
+		p.nextToken()
+		stmt.Subject = p.parseExpression(LOWEST)
+	}
+	if stmt.Subject == nil {
+		stmt.Subject = p.badExpression(start)
+	}
+	p.finishLine(!isBad(stmt.Subject))
+
+	// only blank lines and comments may appear before the first CASE
+	preamble := p.parseBlock(stmt.Token, stmt.Token, "CASE", "DEFAULT", "ENDSWITCH")
+	if len(preamble.Statements) > 0 {
+		p.addErrorAt(stmt.Token, plbErrors.ErrMisplacedStatement, "statements between SWITCH and its first CASE")
+	}
+
+	for !p.resume {
+		p.nextToken()
+		switch strings.ToUpper(p.curToken.Literal) {
+		case "CASE":
+			clause := &ast.CaseClause{Token: p.curToken}
+			var seps []tokens.Token
+			ok := p.parseOperands(&clause.Values, &seps, false)
+			if ok && len(clause.Values) == 0 {
+				p.addError(plbErrors.ErrOperandCount, "CASE expects at least one value")
+				ok = false
+			}
+			p.finishLine(ok)
+			clause.Body = p.parseBlock(clause.Token, stmt.Token, "CASE", "DEFAULT", "ENDSWITCH")
+			stmt.Cases = append(stmt.Cases, clause)
+		case "DEFAULT":
+			clause := p.curToken
+			p.finishLine(true)
+			stmt.Default = p.parseBlock(clause, stmt.Token, "ENDSWITCH")
+		default:
+			stmt.EndToken = p.curToken
+			p.finishLine(true)
+			return stmt
+		}
+	}
+	return stmt
+}
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/tokens"
+	"fmt"
+	"strings"
+)
+
+// Dialect is a flavour of PL/B, it decides which verbs the parser accepts
+type Dialect string
+
+const (
+	Sunbelt Dialect = "SUNBELT" // Sunbelt PL/B with its extensions, the default
+	ANSI    Dialect = "ANSI"    // the ANSI standard without vendor extensions
+)
+
+// Dialects are the dialects known to the parser
+var Dialects = []Dialect{Sunbelt, ANSI}
+
+// LookupDialect returns the dialect with the given name, case-insensitive
+func LookupDialect(name string) (Dialect, bool) {
+	for _, d := range Dialects {
+		if strings.EqualFold(name, string(d)) {
+			return d, true
+		}
+	}
+	return "", false
+}
+
+// extensions are the verbs that are not part of the ANSI standard
+var extensions = map[string]bool{
+	"LOADMOD":   true,
+	"LROUTINE":  true,
+	"MOVEADR":   true,
+	"MOVEPTR":   true,
+	"RECORD":    true,
+	"RECORDEND": true,
+	"TYPE":      true,
+}
+
+// allowedInDialect records an error if feature, a verb or declaration, is not part of the dialect of the parser
+func (p *Parser) allowedInDialect(tok tokens.Token, feature string) bool {
+	if p.dialect != ANSI {
+		return true
+	}
+	p.addErrorAt(tok, plbErrors.ErrDialect, fmt.Sprintf("%s is not part of the %s dialect", feature, p.dialect))
+	return false
+}
@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/tokens"
+	"fmt"
+	"strings"
+)
+
+const (
+	_ int = iota
+	LOWEST
+	LOGICALOR   // OR
+	LOGICALAND  // AND
+	EQUALS      // = or <>
+	LESSGREATER // <, >, <= or >=
+	SUM         // + or -
+	PRODUCT     // * or /
+	EXPONENT    // **
+	PREFIX      // -X or NOT X
+	INDEX       // ARR(X)
+)
+
+var precedences = map[tokens.TokenType]int{
+	tokens.OR:       LOGICALOR,
+	tokens.AND:      LOGICALAND,
+	tokens.EQ:       EQUALS,
+	tokens.NEQ:      EQUALS,
+	tokens.LT:       LESSGREATER,
+	tokens.GT:       LESSGREATER,
+	tokens.LEQ:      LESSGREATER,
+	tokens.GEQ:      LESSGREATER,
+	tokens.PLUS:     SUM,
+	tokens.MINUS:    SUM,
+	tokens.ASTERISK: PRODUCT,
+	tokens.SLASH:    PRODUCT,
+	tokens.POWER:    EXPONENT,
+	tokens.LPAREN:   INDEX,
+}
+
+func (p *Parser) peekPrecedence() int {
+	if p, ok := precedences[p.peekToken.Type]; ok {
+		return p
+	}
+	return LOWEST
+}
+
+func (p *Parser) curPrecedence() int {
+	if p, ok := precedences[p.curToken.Type]; ok {
+		return p
+	}
+	return LOWEST
+}
+
+// parseExpression is the entry point of the Pratt parser, the current token has to be the first token of the expression.
+// After returning, the current token is the last token of the expression.
+func (p *Parser) parseExpression(precedence int) ast.Expression {
+	prefix := p.prefixParseFns[p.curToken.Type]
+	if prefix == nil {
+		if p.curTokenIs(tokens.NEWLINE) || p.curTokenIs(tokens.EOF) {
+			p.addError(plbErrors.ErrInvalidExpression, "missing operand at the end of the line")
+		} else {
+			p.addError(plbErrors.ErrInvalidExpression, fmt.Sprintf("unexpected %s %q, expected an operand", p.curToken.Type, p.curToken.Literal))
+		}
+		return nil
+	}
+	leftExp := prefix()
+	if leftExp == nil {
+		return nil
+	}
+
+	for !p.peekTokenEndsLine() && precedence < p.peekPrecedence() {
+		infix := p.infixParseFns[p.peekToken.Type]
+		if infix == nil {
+			return leftExp
+		}
+		p.nextToken()
+		leftExp = infix(leftExp)
+		if leftExp == nil {
+			return nil
+		}
+	}
+
+	return leftExp
+}
+
+func (p *Parser) parseIdentifier() ast.Expression {
+	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+func (p *Parser) parseNumberLiteral() ast.Expression {
+	return &ast.NumberLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+func (p *Parser) parsePrefixExpression() ast.Expression {
+	expression := &ast.PrefixExpression{Token: p.curToken, Operator: strings.ToUpper(p.curToken.Literal)}
+	p.nextToken()
+	expression.Right = p.parseExpression(PREFIX)
+	if expression.Right == nil {
+		return nil
+	}
+	return expression
+}
+
+func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
+	expression := &ast.InfixExpression{
+		Token:    p.curToken,
+		Operator: strings.ToUpper(p.curToken.Literal),
+		Left:     left,
+	}
+	precedence := p.curPrecedence()
+	p.nextToken()
+	expression.Right = p.parseExpression(precedence)
+	if expression.Right == nil {
+		return nil
+	}
+	return expression
+}
+
+func (p *Parser) parseGroupedExpression() ast.Expression {
+	group := &ast.GroupedExpression{Token: p.curToken}
+	p.nextToken()
+	group.Expression = p.parseExpression(LOWEST)
+	if group.Expression == nil {
+		return nil
+	}
+	if !p.expectPeek(tokens.RPAREN) {
+		return nil
+	}
+	group.EndToken = p.curToken
+	return group
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	expression := &ast.IndexExpression{Token: p.curToken, Left: left}
+	p.nextToken()
+	expression.Index = p.parseExpression(LOWEST)
+	if expression.Index == nil {
+		return nil
+	}
+	if !p.expectPeek(tokens.RPAREN) {
+		return nil
+	}
+	expression.EndToken = p.curToken
+	return expression
+}
@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/tokens"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// fileKinds lists the file variable declarations and whether they are accessed by key
+var fileKinds = map[string]bool{
+	"FILE":  false,
+	"IFILE": true,
+	"AFILE": true,
+	"PFILE": false,
+}
+
+// fileAttributes maps every file attribute to the group of attributes it excludes,
+// e.g. FIXED= and VAR= both set the record length and cannot be given together
+var fileAttributes = map[string]string{
+	"FIXED":      "record length",
+	"VAR":        "record length",
+	"KEYLEN":     "key length",
+	"DUP":        "duplicate keys",
+	"NODUP":      "duplicate keys",
+	"TEXT":       "record format",
+	"BINARY":     "record format",
+	"COMP":       "compression",
+	"COMPRESSED": "compression",
+	"UNCOMP":     "compression",
+}
+
+// valuedFileAttributes are the attributes taking a length, e.g. FIXED=128
+var valuedFileAttributes = map[string]bool{"FIXED": true, "VAR": true, "KEYLEN": true}
+
+// buildFileDeclaration decodes the attributes of a FILE, IFILE, AFILE or PFILE declaration.
+// An attribute is either a flag like DUP or a length like KEYLEN=10, which the operand parser reads as a comparison.
+func (p *Parser) buildFileDeclaration(verb tokens.Token, label *ast.Identifier, operands []ast.Expression) ast.Statement {
+	kind := strings.ToUpper(verb.Literal)
+	decl := &ast.FileDeclaration{Token: verb, Name: label, Kind: kind, EndToken: p.curToken}
+	if kind == "PFILE" && len(operands) > 0 {
+		p.addErrorAt(verb, plbErrors.ErrInvalidAttribute, "PFILE takes no attributes")
+		return nil
+	}
+
+	given := map[string]string{} // attribute group to the attribute setting it
+	for _, op := range operands {
+		if isBad(op) {
+			// the error was reported while parsing the operand
+			return nil
+		}
+		name, value, tok, ok := p.fileAttribute(verb, op)
+		if !ok {
+			return nil
+		}
+		group, known := fileAttributes[name]
+		if !known {
+			p.addErrorAt(tok, plbErrors.ErrInvalidAttribute, fmt.Sprintf("unknown %s attribute %q", kind, tok.Literal))
+			return nil
+		}
+		if group == "key length" || group == "duplicate keys" {
+			if !fileKinds[kind] {
+				p.addErrorAt(tok, plbErrors.ErrInvalidAttribute, fmt.Sprintf("%s is only allowed on an IFILE or AFILE", name))
+				return nil
+			}
+		}
+		if prev, ok := given[group]; ok {
+			if prev == name {
+				p.addErrorAt(tok, plbErrors.ErrInvalidAttribute, fmt.Sprintf("%s is given twice", name))
+			} else {
+				p.addErrorAt(tok, plbErrors.ErrInvalidAttribute, fmt.Sprintf("%s conflicts with %s, both set the %s", name, prev, group))
+			}
+			return nil
+		}
+		given[group] = name
+
+		switch name {
+		case "FIXED":
+			decl.Fixed = true
+			decl.RecordLength = value
+		case "VAR":
+			decl.RecordLength = value
+		case "KEYLEN":
+			decl.KeyLength = value
+		case "DUP":
+			decl.Dup = true
+		case "BINARY":
+			decl.Binary = true
+		case "COMP", "COMPRESSED":
+			decl.Compressed = true
+		}
+	}
+	return decl
+}
+
+// fileAttribute returns the upper-cased name and the length of a single file attribute operand,
+// recording an error if the operand is neither a flag nor NAME=length.
+// Errors without a better place are reported at the verb.
+func (p *Parser) fileAttribute(verb tokens.Token, op ast.Expression) (string, int, tokens.Token, bool) {
+	switch e := op.(type) {
+	case *ast.Identifier:
+		name := strings.ToUpper(e.Value)
+		if valuedFileAttributes[name] {
+			p.addErrorAt(e.Token, plbErrors.ErrInvalidAttribute, fmt.Sprintf("%s needs a length, e.g. %s=128", name, name))
+			return "", 0, e.Token, false
+		}
+		return name, 0, e.Token, true
+	case *ast.InfixExpression:
+		ident, ok := e.Left.(*ast.Identifier)
+		if !ok || e.Operator != "=" {
+			break
+		}
+		name := strings.ToUpper(ident.Value)
+		if _, known := fileAttributes[name]; known && !valuedFileAttributes[name] {
+			p.addErrorAt(ident.Token, plbErrors.ErrInvalidAttribute, fmt.Sprintf("%s does not take a value", name))
+			return "", 0, ident.Token, false
+		}
+		num, ok := e.Right.(*ast.NumberLiteral)
+		if !ok || num.Token.Type != tokens.DNUM {
+			p.addErrorAt(e.Token, plbErrors.ErrInvalidAttribute, fmt.Sprintf("the length of %s must be a decimal number, got %q", name, e.Right))
+			return "", 0, ident.Token, false
+		}
+		value, err := strconv.Atoi(num.Value)
+		if err != nil || value <= 0 {
+			p.addErrorAt(num.Token, plbErrors.ErrInvalidAttribute, fmt.Sprintf("the length of %s must be positive, got %s", name, num.Value))
+			return "", 0, ident.Token, false
+		}
+		return name, value, ident.Token, true
+	}
+	p.addErrorAt(verb, plbErrors.ErrInvalidAttribute, fmt.Sprintf("invalid %s attribute %q", strings.ToUpper(verb.Literal), op))
+	return "", 0, verb, false
+}
@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/symbols"
+	"fmt"
+	"strings"
+)
+
+// parseIncludeStatement parses an INCLUDE or INC, the current token has to be the verb.
+// The file name is taken verbatim from the rest of the line, since it is not made of PL/B tokens, e.g. common.pls.
+func (p *Parser) parseIncludeStatement(label *ast.Identifier) ast.Statement {
+	stmt := &ast.IncludeStatement{Token: p.curToken, Label: label}
+	line := strings.TrimRight(p.curToken.LineTxt, "\r\n")
+	if start := p.curToken.Col - 1 + len(p.curToken.Literal); start < len(line) {
+		stmt.Path = strings.Trim(strings.TrimSpace(line[start:]), `"`)
+	}
+	for !p.peekTokenEndsLine() {
+		p.nextToken()
+	}
+	if stmt.Path == "" {
+		p.addErrorAt(stmt.Token, plbErrors.ErrOperandCount, fmt.Sprintf("%s expects a file name", strings.ToUpper(stmt.Token.Literal)))
+		return nil
+	}
+
+	if p.include != nil {
+		prog, file, err := p.include(stmt.Path)
+		if err != nil {
+			p.addErrorAt(stmt.Token, plbErrors.ErrInclude, err.Error())
+			return stmt
+		}
+		stmt.File = file
+		stmt.Body = &ast.BlockStatement{Token: stmt.Token, Statements: prog.Statements}
+	}
+	return stmt
+}
+
+// declareSymbols records the global declarations of prog in the symbol environment of the parser, if there is one.
+// Data declared in an LROUTINE is local to it and not recorded.
+func (p *Parser) declareSymbols(prog *ast.Program) {
+	if p.symbols != nil {
+		declareStatements(p.symbols, prog.Statements)
+	}
+}
+
+func declareStatements(env *symbols.Environment, stmts []ast.Statement) {
+	var label *ast.Identifier // a label on a line of its own, naming the next statement
+	for _, stmt := range stmts {
+		if l, ok := stmt.(*ast.LabelStatement); ok {
+			if label != nil {
+				env.Declare(&symbols.Symbol{Name: label.Value, Kind: symbols.Label, Token: label.Token, Node: label})
+			}
+			label = l.Name
+			continue
+		}
+
+		switch s := stmt.(type) {
+		case *ast.DataDeclaration:
+			declareNamed(env, s.Name, label, symbols.Data, s)
+		case *ast.FileDeclaration:
+			declareNamed(env, s.Name, label, symbols.File, s)
+		case *ast.PointerDeclaration:
+			declareNamed(env, s.Name, label, symbols.Pointer, s)
+		case *ast.RoutineStatement:
+			env.Declare(&symbols.Symbol{Name: s.Name.Value, Kind: symbols.Routine, Token: s.Name.Token, Node: s})
+			if !s.IsLocal() {
+				declareStatements(env, s.Body.Statements)
+			}
+		default:
+			if label != nil {
+				env.Declare(&symbols.Symbol{Name: label.Value, Kind: symbols.Label, Token: label.Token, Node: label})
+			}
+			if l := ast.Label(stmt); l != nil {
+				env.Declare(&symbols.Symbol{Name: l.Value, Kind: symbols.Label, Token: l.Token, Node: stmt})
+			}
+			ast.Blocks(stmt, func(body *ast.BlockStatement) {
+				declareStatements(env, body.Statements)
+			})
+		}
+		label = nil
+	}
+	if label != nil {
+		env.Declare(&symbols.Symbol{Name: label.Value, Kind: symbols.Label, Token: label.Token, Node: label})
+	}
+}
+
+// declareNamed declares a data, file or pointer variable named on its own line or on the line before it
+func declareNamed(env *symbols.Environment, name, label *ast.Identifier, kind symbols.Kind, node ast.Node) {
+	if name == nil {
+		name = label
+	}
+	if name != nil {
+		env.Declare(&symbols.Symbol{Name: name.Value, Kind: kind, Token: name.Token, Node: node})
+	}
+}
@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/tokens"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// listControl describes a control that may appear in the list of a DISPLAY, KEYIN or PRINT
+type listControl struct {
+	kind ast.ListItemKind
+	args int // number of numeric arguments, e.g. 2 for *P=10:2
+}
+
+// screenControls are the controls shared by DISPLAY and KEYIN
+var screenControls = map[string]listControl{
+	"P":        {kind: ast.PositionItem, args: 2}, // cursor to column and line
+	"H":        {kind: ast.PositionItem, args: 1}, // cursor to column
+	"V":        {kind: ast.PositionItem, args: 1}, // cursor to line
+	"N":        {kind: ast.PositionItem},          // cursor to the start of the next line
+	"C":        {kind: ast.PositionItem},          // cursor to the start of the line
+	"ES":       {kind: ast.AttributeItem},         // erase the screen
+	"EF":       {kind: ast.AttributeItem},         // erase to the end of the screen
+	"EL":       {kind: ast.AttributeItem},         // erase to the end of the line
+	"B":        {kind: ast.AttributeItem},         // beep
+	"HON":      {kind: ast.AttributeItem},         // highlight on
+	"HOFF":     {kind: ast.AttributeItem},         // highlight off
+	"REVON":    {kind: ast.AttributeItem},         // reverse video on
+	"REVOFF":   {kind: ast.AttributeItem},         // reverse video off
+	"BLINKON":  {kind: ast.AttributeItem},         // blinking on
+	"BLINKOFF": {kind: ast.AttributeItem},         // blinking off
+	"PLAIN":    {kind: ast.AttributeItem},         // every attribute off
+}
+
+// keyinControls are the controls only KEYIN allows
+var keyinControls = map[string]listControl{
+	"T":    {kind: ast.AttributeItem, args: 1}, // time out after the given number of seconds
+	"IT":   {kind: ast.AttributeItem},          // invisible typing, e.g. for passwords
+	"EON":  {kind: ast.AttributeItem},          // echo on
+	"EOFF": {kind: ast.AttributeItem},          // echo off
+	"CL":   {kind: ast.AttributeItem},          // clear the keyboard buffer
+	"DE":   {kind: ast.AttributeItem},          // digit entry only
+	"JR":   {kind: ast.AttributeItem},          // justify right
+	"JL":   {kind: ast.AttributeItem},          // justify left
+	"ZF":   {kind: ast.AttributeItem},          // zero fill
+}
+
+// printControls are the controls of PRINT
+var printControls = map[string]listControl{
+	"H":  {kind: ast.PositionItem, args: 1}, // print head to column
+	"N":  {kind: ast.PositionItem},          // new line
+	"C":  {kind: ast.PositionItem},          // carriage return
+	"L":  {kind: ast.PositionItem},          // line feed
+	"F":  {kind: ast.PositionItem},          // form feed
+	"JR": {kind: ast.AttributeItem},         // justify right
+	"JL": {kind: ast.AttributeItem},         // justify left
+	"ZF": {kind: ast.AttributeItem},         // zero fill
+}
+
+// listControls are the controls each list verb allows
+var listControls = map[string]map[string]listControl{
+	"DISPLAY": screenControls,
+	"KEYIN":   merge(screenControls, keyinControls),
+	"PRINT":   printControls,
+}
+
+// ControlArg are the operand classes of a control argument, e.g. the column of *P=COL:10
+var ControlArg = Operand{tokens.NVAR, tokens.DNUM}
+
+func merge(sets ...map[string]listControl) map[string]listControl {
+	merged := map[string]listControl{}
+	for _, set := range sets {
+		for name, control := range set {
+			merged[name] = control
+		}
+	}
+	return merged
+}
+
+// parseListStatement parses the items of a DISPLAY, KEYIN or PRINT, the current token has to be the verb.
+// Items are separated by commas, a trailing ! or ; suppresses the new line at the end of the statement.
+func (p *Parser) parseListStatement(label *ast.Identifier) ast.Statement {
+	stmt := &ast.ListStatement{Token: p.curToken, Label: label, Verb: strings.ToUpper(p.curToken.Literal)}
+	if p.peekTokenEndsLine() {
+		return stmt
+	}
+
+	for {
+		p.nextToken()
+		item := p.parseListItem(stmt.Verb)
+		if item == nil {
+			return nil
+		}
+		stmt.Items = append(stmt.Items, item)
+
+		if p.peekTokenIs(tokens.EXCLAMATION) || p.peekTokenIs(tokens.SEMICOLON) {
+			p.nextToken()
+			stmt.Items = append(stmt.Items, &ast.ListItem{Token: p.curToken, Kind: ast.SuppressItem, EndToken: p.curToken})
+			break
+		}
+		if !p.peekTokenIs(tokens.COMMA) || p.peekToken.Literal != "," {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectLineEnd() {
+		return nil
+	}
+	return stmt
+}
+
+// parseListItem parses a variable, a literal or a control, the current token has to be the start of the item
+func (p *Parser) parseListItem(verb string) *ast.ListItem {
+	if p.curTokenIs(tokens.ASTERISK) {
+		return p.parseListControl(verb)
+	}
+
+	item := &ast.ListItem{Token: p.curToken}
+	item.Value = p.parseExpression(LOWEST)
+	switch item.Value.(type) {
+	case nil:
+		return nil
+	case *ast.Identifier, *ast.IndexExpression:
+		item.Kind = ast.VariableItem
+	case *ast.StringLiteral, *ast.NumberLiteral:
+		item.Kind = ast.LiteralItem
+	default:
+		p.addErrorAt(item.Token, plbErrors.ErrOperandClass, fmt.Sprintf("%s item must be a variable, a literal or a list control, got %q", verb, item.Value))
+		return nil
+	}
+	item.EndToken = p.curToken
+	return item
+}
+
+// parseListControl parses a control like *ES, *P=10:2 or the short form *P10:2, the current token has to be the *
+func (p *Parser) parseListControl(verb string) *ast.ListItem {
+	item := &ast.ListItem{Token: p.curToken}
+	if !p.expectPeek(tokens.IDENT) {
+		return nil
+	}
+
+	// in the short form the first argument is part of the identifier, e.g. P10 of *P10:2
+	item.Control = strings.ToUpper(p.curToken.Literal)
+	digits := strings.IndexFunc(item.Control, unicode.IsDigit)
+	if digits > 0 && strings.TrimLeftFunc(item.Control[digits:], unicode.IsDigit) == "" {
+		arg := p.curToken
+		arg.Type, arg.Literal, arg.Col = tokens.DNUM, item.Control[digits:], arg.Col+digits
+		item.Args = append(item.Args, &ast.NumberLiteral{Token: arg, Value: arg.Literal})
+		item.Control = item.Control[:digits]
+	} else if p.peekTokenIs(tokens.EQ) {
+		p.nextToken()
+		if !p.parseControlArg(item) {
+			return nil
+		}
+	}
+	for len(item.Args) > 0 && p.peekTokenIs(tokens.COMMA) && p.peekToken.Literal == ":" {
+		p.nextToken()
+		if !p.parseControlArg(item) {
+			return nil
+		}
+	}
+
+	control, ok := listControls[verb][item.Control]
+	if !ok {
+		p.addErrorAt(item.Token, plbErrors.ErrListControl, fmt.Sprintf("*%s is not a %s list control", item.Control, verb))
+		return nil
+	}
+	if len(item.Args) != control.args {
+		p.addErrorAt(item.Token, plbErrors.ErrListControl, fmt.Sprintf("*%s of %s takes %d arguments, got %d", item.Control, verb, control.args, len(item.Args)))
+		return nil
+	}
+	item.Kind = control.kind
+	item.EndToken = p.curToken
+	return item
+}
+
+// parseControlArg parses a single numeric argument of a control and adds it to the item,
+// the current token has to be the = or : before it
+func (p *Parser) parseControlArg(item *ast.ListItem) bool {
+	p.nextToken()
+	arg := p.parseExpression(PREFIX)
+	if arg == nil {
+		return false
+	}
+	if !ControlArg.Accepts(arg) {
+		p.addErrorAt(item.Token, plbErrors.ErrListControl, fmt.Sprintf("argument of *%s must be %s, got %q", item.Control, ControlArg, arg))
+		return false
+	}
+	item.Args = append(item.Args, arg)
+	return true
+}
@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/symbols"
+)
+
+// Option configures a Parser, see New
+type Option func(*Parser)
+
+// WithDiagnostics reports every error found by the lexer and the parser to d, in addition to Errors.
+// Trace events are only reported if tracing is enabled with WithTrace.
+func WithDiagnostics(d plbErrors.Diagnostics) Option {
+	return func(p *Parser) {
+		p.diag = d
+	}
+}
+
+// WithTrace enables trace events for every parsed statement and every resynchronisation after an error
+func WithTrace() Option {
+	return func(p *Parser) {
+		p.trace = true
+	}
+}
+
+// WithDialect restricts the parser to the verbs of the given dialect, Sunbelt is used by default
+func WithDialect(d Dialect) Option {
+	return func(p *Parser) {
+		p.dialect = d
+	}
+}
+
+// WithSymbols records the global declarations of the parsed program in env.
+// Pointers declared in env by an earlier parse are dereferenced like pointers declared in the program itself.
+func WithSymbols(env *symbols.Environment) Option {
+	return func(p *Parser) {
+		p.symbols = env
+	}
+}
+
+// IncludeFunc parses the file named by an INCLUDE.
+// It returns the parsed program and the path the file was read from.
+type IncludeFunc func(name string) (*ast.Program, string, error)
+
+// WithIncludes parses INCLUDE files with fn, without it INCLUDE statements are kept without a body
+func WithIncludes(fn IncludeFunc) Option {
+	return func(p *Parser) {
+		p.include = fn
+	}
+}
@@ -0,0 +1,146 @@
+package parser
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/lexer"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/symbols"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Options configures ParseFile, ParseString and ParseReader.
+// The Defines are added to Symbols, a program referring to them is resolved by giving the same environment
+// to the resolver, see resolver.WithEnvironment.
+type Options struct {
+	Dialect      string               // name of the PL/B dialect, see Dialects, Sunbelt if empty
+	IncludePaths []string             // directories searched for INCLUDE files after the directory of the including file
+	Defines      map[string]string    // constants visible to every file, like an EQU declared outside of the source
+	Trace        bool                 // record trace events in the returned diagnostics
+	Symbols      *symbols.Environment // environment shared with other parses, a new one is used if nil
+}
+
+// ParseFile parses the PL/B source file at path and every file it includes.
+// Problems are returned as diagnostics, including a file that cannot be read.
+func ParseFile(path string, opts Options) (*ast.Program, *plbErrors.Collector) {
+	s := newSession(opts)
+	prog, err := s.parseFile(path)
+	if err != nil {
+		s.diag.Error(plbErrors.NewFileError(plbErrors.ErrCannotRead, err.Error(), path))
+		return &ast.Program{Statements: []ast.Statement{}}, s.diag
+	}
+	return prog, s.diag
+}
+
+// ParseString parses PL/B source given as a string, INCLUDE files are searched relative to the working directory
+func ParseString(src string, opts Options) (*ast.Program, *plbErrors.Collector) {
+	return ParseReader(strings.NewReader(src), "", opts)
+}
+
+// ParseReader parses PL/B source read from r, name is the file name used in diagnostics.
+// INCLUDE files are searched relative to the directory of name.
+func ParseReader(r io.Reader, name string, opts Options) (*ast.Program, *plbErrors.Collector) {
+	s := newSession(opts)
+	return s.parse(r, name), s.diag
+}
+
+// session holds the state shared by a file and the files it includes
+type session struct {
+	opts    Options
+	dialect Dialect
+	diag    *plbErrors.Collector
+	files   []string // absolute paths of the files being parsed, the outermost first
+}
+
+func newSession(opts Options) *session {
+	s := &session{opts: opts, dialect: Sunbelt, diag: &plbErrors.Collector{}}
+	if s.opts.Symbols == nil {
+		s.opts.Symbols = symbols.NewEnvironment()
+	}
+	for name, value := range opts.Defines {
+		s.opts.Symbols.Define(name, value)
+	}
+	if opts.Dialect != "" {
+		d, ok := LookupDialect(opts.Dialect)
+		if !ok {
+			s.diag.Error(plbErrors.NewFileError(plbErrors.ErrDialect, fmt.Sprintf("unknown dialect %q, using %s", opts.Dialect, Sunbelt), ""))
+		} else {
+			s.dialect = d
+		}
+	}
+	return s
+}
+
+// parseFile opens and parses a single file, the caller reports an error
+func (s *session) parseFile(path string) (*ast.Program, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	s.files = append(s.files, abs)
+	defer func() { s.files = s.files[:len(s.files)-1] }()
+
+	return s.parse(file, path), nil
+}
+
+// parse parses the source read from r, INCLUDE files are parsed within the same session
+func (s *session) parse(r io.Reader, name string) *ast.Program {
+	opts := []Option{
+		WithDiagnostics(s.diag),
+		WithDialect(s.dialect),
+		WithSymbols(s.opts.Symbols),
+		WithIncludes(func(include string) (*ast.Program, string, error) {
+			return s.include(name, include)
+		}),
+	}
+	if s.opts.Trace {
+		opts = append(opts, WithTrace())
+	}
+	return New(lexer.New(bufio.NewReader(r), name), opts...).ParseProgram()
+}
+
+// include finds and parses the file named by an INCLUDE in the file from
+func (s *session) include(from, name string) (*ast.Program, string, error) {
+	path, ok := s.find(from, name)
+	if !ok {
+		return nil, "", fmt.Errorf("cannot find the INCLUDE file %q", name)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		for _, open := range s.files {
+			if open == abs {
+				return nil, "", fmt.Errorf("%s includes itself", path)
+			}
+		}
+	}
+	prog, err := s.parseFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return prog, path, nil
+}
+
+// find returns the path of an INCLUDE file, searched in the directory of the including file and then in the include paths
+func (s *session) find(from, name string) (string, bool) {
+	if filepath.IsAbs(name) {
+		_, err := os.Stat(name)
+		return name, err == nil
+	}
+	dirs := append([]string{filepath.Dir(from)}, s.opts.IncludePaths...)
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
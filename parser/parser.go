@@ -4,8 +4,10 @@ import (
 	"PLB-Interpreter/ast"
 	"PLB-Interpreter/lexer"
 	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/symbols"
 	"PLB-Interpreter/tokens"
 	"fmt"
+	"strings"
 )
 
 type prefixParseFn func() ast.Expression
@@ -16,28 +18,82 @@ type Parser struct {
 
 	errors []error
 
+	prevToken  tokens.Token // the token before curToken, see badEnd
 	curToken   tokens.Token
 	peekToken  tokens.Token
 	peekToken2 tokens.Token
 
 	prefixParseFns map[tokens.TokenType]prefixParseFn
 	infixParseFns  map[tokens.TokenType]infixParseFn
+
+	loopDepth  int        // number of LOOP and FOR blocks enclosing the current statement
+	openBlocks [][]string // closing verbs of every block enclosing the current statement, innermost last
+	resume     bool       // the current token starts a line that still has to be parsed
+
+	diag    plbErrors.Diagnostics // receives errors and trace events, see WithDiagnostics
+	trace   bool                  // whether trace events are reported, see WithTrace
+	dialect Dialect               // the accepted dialect, see WithDialect
+	symbols *symbols.Environment  // receives the global declarations, see WithSymbols
+	include IncludeFunc           // parses INCLUDE files, see WithIncludes
 }
 
 // Advances the parser by one token, setting the current token to the peek token
 func (p *Parser) nextToken() {
+	p.prevToken = p.curToken
 	p.curToken = p.peekToken
-	peek2, err := p.l.NextToken()
-	if err != nil {
-		p.errors = append(p.errors, err)
-		return
-	}
 	p.peekToken = p.peekToken2
-	p.peekToken2 = peek2
+	p.peekToken2 = p.readToken()
+}
+
+// readToken fetches the next significant token from the lexer.
+// Whitespace is only significant at the start of a line, where it tells a verb apart from a label,
+// so all other WHITESPACE tokens are dropped here.
+func (p *Parser) readToken() tokens.Token {
+	for {
+		tok, err := p.l.NextToken()
+		if err != nil {
+			p.errors = append(p.errors, err)
+			if plbErr, ok := err.(*plbErrors.PLBError); ok {
+				p.diag.Error(plbErr)
+			}
+		}
+		if tok.Type == tokens.WHITESPACE && tok.Col != 1 {
+			continue
+		}
+		return tok
+	}
 }
 
-func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l}
+// New creates a parser reading from the given lexer.
+// The parser never prints, problems are available through Errors or reported to the Diagnostics given by WithDiagnostics.
+func New(l *lexer.Lexer, opts ...Option) *Parser {
+	p := &Parser{l: l, diag: plbErrors.Discard, dialect: Sunbelt}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	p.prefixParseFns = make(map[tokens.TokenType]prefixParseFn)
+	p.registerPrefix(tokens.IDENT, p.parseIdentifier)
+	p.registerPrefix(tokens.LITERAL, p.parseStringLiteral)
+	p.registerPrefix(tokens.NUMERICLITERAL, p.parseNumberLiteral)
+	p.registerPrefix(tokens.DNUM, p.parseNumberLiteral)
+	p.registerPrefix(tokens.NUMERICCONSTANT, p.parseNumberLiteral)
+	p.registerPrefix(tokens.ONUM, p.parseNumberLiteral)
+	p.registerPrefix(tokens.XNUM, p.parseNumberLiteral)
+	p.registerPrefix(tokens.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(tokens.NOT, p.parsePrefixExpression)
+	p.registerPrefix(tokens.LPAREN, p.parseGroupedExpression)
+
+	p.infixParseFns = make(map[tokens.TokenType]infixParseFn)
+	for _, tt := range []tokens.TokenType{
+		tokens.PLUS, tokens.MINUS, tokens.ASTERISK, tokens.SLASH, tokens.POWER,
+		tokens.EQ, tokens.NEQ, tokens.LT, tokens.GT, tokens.LEQ, tokens.GEQ,
+		tokens.AND, tokens.OR,
+	} {
+		p.registerInfix(tt, p.parseInfixExpression)
+	}
+	p.registerInfix(tokens.LPAREN, p.parseIndexExpression)
+
 	p.nextToken()
 	p.nextToken()
 	p.nextToken()
@@ -52,82 +108,97 @@ func (p *Parser) Errors() (bool, []error) {
 }
 
 func (p *Parser) addError(code, msg string) {
+	p.addErrorAt(p.curToken, code, msg)
+}
+
+// addErrorAt records an error located at the given token.
+// ILLEGAL tokens were already reported by the lexer, so no further error is recorded for them.
+func (p *Parser) addErrorAt(tok tokens.Token, code, msg string) {
+	if tok.Type == tokens.ILLEGAL {
+		return
+	}
 	newErr := plbErrors.NewPLBError(
 		code,
 		msg,
-		p.curToken.FileName,
-		p.curToken.Line,
-		p.curToken.Col,
-		p.curToken.LineTxt,
+		tok.FileName,
+		tok.Line,
+		tok.Col,
+		tok.LineTxt,
 	)
 	p.errors = append(p.errors, newErr)
+	p.diag.Error(newErr)
 }
 
-func (p *Parser) ParseProgram() *ast.Program {
-	program := &ast.Program{}
-	program.Statements = []ast.Statement{}
-
-	for p.curToken.Type != tokens.EOF {
-		stmt, err := p.parseStatement()
-		if err != nil {
-			return program
-		}
-		if stmt != nil {
-			program.Statements = append(program.Statements, stmt)
-		}
-		p.nextToken()
+// traceAt reports a trace event located at the given token, if tracing is enabled
+func (p *Parser) traceAt(tok tokens.Token, msg string) {
+	if !p.trace {
+		return
 	}
+	p.diag.Trace(plbErrors.TraceEvent{
+		Stage:   "parser",
+		Message: msg,
+		File:    tok.FileName,
+		Line:    tok.Line,
+		Column:  tok.Col,
+	})
+}
 
-	return program
+func (p *Parser) registerPrefix(tokenType tokens.TokenType, fn prefixParseFn) {
+	p.prefixParseFns[tokenType] = fn
 }
 
-func (p *Parser) parseStatement() (ast.Statement, error) {
-	if p.isValidStatement() {
-		fmt.Printf("Line: %d  is a statement line: %s", p.curToken.Line, p.curToken.LineTxt)
-		err := p.consumeTillNewline()
-		if err != nil {
-			return nil, err
-		}
-	} else if p.isValidLabel() {
-		fmt.Printf("Line: %d  is a label line: %s", p.curToken.Line, p.curToken.LineTxt)
-		err := p.consumeTillNewline()
-		if err != nil {
-			return nil, err
-		}
-	}
-	return nil, nil
+func (p *Parser) registerInfix(tokenType tokens.TokenType, fn infixParseFn) {
+	p.infixParseFns[tokenType] = fn
 }
 
-func (p *Parser) isValidStatement() bool {
-	if (p.curToken.Type == tokens.IDENT && p.peekToken.Type == tokens.WHITESPACE && p.peekToken2.Type == tokens.IDENT) ||
-		(p.curToken.Type == tokens.WHITESPACE && p.peekToken.Type == tokens.IDENT) {
-		// This is a statement line
-		return true
-	}
-	return false
+func (p *Parser) curTokenIs(t tokens.TokenType) bool {
+	return p.curToken.Type == t
+}
+
+func (p *Parser) peekTokenIs(t tokens.TokenType) bool {
+	return p.peekToken.Type == t
+}
+
+// peekTokenIsKeyword returns true if the peek token is an IDENT spelling the given keyword, case-insensitive
+func (p *Parser) peekTokenIsKeyword(keyword string) bool {
+	return p.peekToken.Type == tokens.IDENT && strings.ToUpper(p.peekToken.Literal) == keyword
+}
+
+// peekTokenEndsLine returns true if the peek token terminates the current logical line
+func (p *Parser) peekTokenEndsLine() bool {
+	return p.peekTokenIs(tokens.NEWLINE) || p.peekTokenIs(tokens.EOF)
 }
 
-func (p *Parser) isValidLabel() bool {
-	if (p.curToken.Type == tokens.IDENT && p.peekToken.Type == tokens.NEWLINE) ||
-		(p.curToken.Type == tokens.IDENT && p.peekToken.Type == tokens.WHITESPACE && p.peekToken2.Type == tokens.NEWLINE) {
-		// This is a label line
+// expectPeek advances the parser if the peek token has the given type, otherwise an error is recorded
+func (p *Parser) expectPeek(t tokens.TokenType) bool {
+	if p.peekTokenIs(t) {
+		p.nextToken()
 		return true
 	}
+	p.peekError(t)
 	return false
 }
 
-func (p *Parser) consumeTillNewline() error {
-	for p.curToken.Type != tokens.NEWLINE {
-		if has, errs := p.Errors(); has {
-			for _, err := range errs {
-				fmt.Println(err)
-			}
-			return fmt.Errorf("errors found")
-		}
-		if p.curToken.Type == tokens.EOF {
-			return nil
+func (p *Parser) peekError(t tokens.TokenType) {
+	p.nextToken()
+	p.addError(plbErrors.ErrUnexpectedToken, fmt.Sprintf("expected next token to be %s, got %s instead", t, p.curToken.Type))
+}
+
+// ParseProgram parses the whole input. Parsing continues after errors, every problem found
+// is recorded and can be retrieved with Errors.
+func (p *Parser) ParseProgram() *ast.Program {
+	program := &ast.Program{}
+	program.Statements = []ast.Statement{}
+
+	for p.curToken.Type != tokens.EOF {
+		stmt := p.parseStatement()
+		if stmt != nil {
+			program.Statements = append(program.Statements, stmt)
 		}
-		p.nextToken()
+		p.nextLine()
 	}
-	return nil
+	p.declareSymbols(program)
+	p.resolvePointers(program)
+
+	return program
 }
@@ -0,0 +1,1475 @@
+package parser
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/lexer"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/symbols"
+	"PLB-Interpreter/tokens"
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parse runs the parser on the given input and fails the test on any parser error
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	l := lexer.New(bufio.NewReader(strings.NewReader(input)), "test")
+	p := New(l)
+	prog := p.ParseProgram()
+	if has, errs := p.Errors(); has {
+		for _, err := range errs {
+			t.Errorf("parser error: %s", err)
+		}
+		t.FailNow()
+	}
+	return prog
+}
+
+func TestParser_ConditionalSuffix(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		verb      string
+		operands  int
+		condition string
+	}{
+		{
+			name:      "expression",
+			input:     "    GOTO TOP IF (VARTHREE <= 25)\n",
+			verb:      "GOTO",
+			operands:  1,
+			condition: "(VARTHREE <= 25)",
+		},
+		{
+			name:      "flag without operands",
+			input:     "    RETURN IF OVER\n",
+			verb:      "RETURN",
+			operands:  0,
+			condition: "OVER",
+		},
+		{
+			name:      "negated flag",
+			input:     "    GOTO DONE IF NOT EQUAL",
+			verb:      "GOTO",
+			operands:  1,
+			condition: "NOT EQUAL",
+		},
+		{
+			name:      "lower case flag",
+			input:     "    goto sub if eos\n",
+			verb:      "GOTO",
+			operands:  1,
+			condition: "EOS",
+		},
+		{
+			name:      "compound expression",
+			input:     "    STOP IF (A = 1 AND B <> \"X\")\n",
+			verb:      "STOP",
+			operands:  0,
+			condition: "(A = 1 AND B <> \"X\")",
+		},
+		{
+			name:     "unconditional",
+			input:    "    MOVE \"HELLO\" TO VARONE\n",
+			verb:     "MOVE",
+			operands: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog := parse(t, tt.input)
+			if len(prog.Statements) != 1 {
+				t.Fatalf("got %d statements, want 1", len(prog.Statements))
+			}
+			stmt, ok := prog.Statements[0].(*ast.VerbStatement)
+			if !ok {
+				t.Fatalf("got %T, want *ast.VerbStatement", prog.Statements[0])
+			}
+			if stmt.Verb != tt.verb {
+				t.Errorf("got verb %q, want %q", stmt.Verb, tt.verb)
+			}
+			if len(stmt.Operands) != tt.operands {
+				t.Errorf("got %d operands, want %d", len(stmt.Operands), tt.operands)
+			}
+			if tt.condition == "" {
+				if stmt.Condition != nil {
+					t.Errorf("got condition %q, want none", stmt.Condition)
+				}
+				return
+			}
+			if stmt.Condition == nil {
+				t.Fatalf("got no condition, want %q", tt.condition)
+			}
+			if stmt.Condition.String() != tt.condition {
+				t.Errorf("got condition %q, want %q", stmt.Condition, tt.condition)
+			}
+		})
+	}
+}
+
+func TestParser_ConditionalSuffix_Error(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "verb does not allow a condition",
+			input: "    MOVE A TO B IF EQUAL\n",
+		},
+		{
+			name:  "unknown flag",
+			input: "    GOTO TOP IF GREATER\n",
+		},
+		{
+			name:  "missing condition",
+			input: "    RETURN IF\n",
+		},
+		{
+			name:  "unterminated expression",
+			input: "    GOTO TOP IF (A = 1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := lexer.New(bufio.NewReader(strings.NewReader(tt.input)), "test")
+			p := New(l)
+			p.ParseProgram()
+			if has, _ := p.Errors(); !has {
+				t.Errorf("got no errors, want at least one")
+			}
+		})
+	}
+}
+
+func TestParser_Blocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "if elseif else",
+			input: `    IF (A = 1)
+    MOVE "ONE" TO B
+    ELSEIF EQUAL
+    MOVE "TWO" TO B
+    ELSE
+. comment inside a block
+    MOVE "OTHER" TO B
+    ENDIF
+`,
+			want: " IF (A = 1)\n MOVE \"ONE\" TO B\n ELSEIF EQUAL\n MOVE \"TWO\" TO B\n ELSE\n MOVE \"OTHER\" TO B\n ENDIF\n",
+		},
+		{
+			name: "loop with exits",
+			input: `    LOOP
+    ADD "1" TO A
+    WHILE (A < 10)
+    BREAK IF OVER
+    CONTINUE IF NOT ZERO
+    UNTIL EOS
+    REPEAT
+`,
+			want: " LOOP\n ADD \"1\" TO A\n WHILE (A < 10)\n BREAK IF OVER\n CONTINUE IF NOT ZERO\n UNTIL EOS\n REPEAT\n",
+		},
+		{
+			name: "for with step",
+			input: `    FOR I FROM 1 TO 10 BY 2
+    DISPLAY I
+    REPEAT
+`,
+			want: " FOR I FROM 1 TO 10 BY 2\n DISPLAY I\n REPEAT\n",
+		},
+		{
+			name: "for with commas",
+			input: `    FOR I,1,10
+    REPEAT
+`,
+			want: " FOR I FROM 1 TO 10\n REPEAT\n",
+		},
+		{
+			name: "switch",
+			input: `    SWITCH A
+
+    CASE "X","Y"
+    MOVE "1" TO B
+    CASE "Z"
+    DEFAULT
+    MOVE "0" TO B
+    ENDSWITCH
+`,
+			want: " SWITCH A\n CASE \"X\",\"Y\"\n MOVE \"1\" TO B\n CASE \"Z\"\n DEFAULT\n MOVE \"0\" TO B\n ENDSWITCH\n",
+		},
+		{
+			name: "nested",
+			input: `TOP LOOP
+    IF (A = 1)
+    FOR I FROM 1 TO 3
+    BREAK
+    REPEAT
+    ENDIF
+    REPEAT
+`,
+			want: "TOP LOOP\n IF (A = 1)\n FOR I FROM 1 TO 3\n BREAK\n REPEAT\n ENDIF\n REPEAT\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog := parse(t, tt.input)
+			if len(prog.Statements) != 1 {
+				t.Fatalf("got %d statements, want 1", len(prog.Statements))
+			}
+			if got := prog.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_Blocks_Error(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "unterminated if",
+			input: "    IF EQUAL\n    STOP\n",
+			want:  []string{"IF opened at test 1:5 is not closed", "Location: test 3:"},
+		},
+		{
+			name:  "closer of the enclosing block",
+			input: "    LOOP\n    IF EQUAL\n    REPEAT\n    ENDIF\n",
+			want:  []string{"IF opened at test 2:5 is not closed, expected ELSEIF or ELSE or ENDIF before REPEAT", "Location: test 3:5"},
+		},
+		{
+			name:  "closer without opener",
+			input: "    ENDSWITCH\n",
+			want:  []string{"ENDSWITCH without a matching SWITCH"},
+		},
+		{
+			name:  "else after else",
+			input: "    IF EQUAL\n    ELSE\n    ELSE\n    ENDIF\n",
+			want:  []string{"ELSE does not match IF opened at test 1:5", "Location: test 3:5"},
+		},
+		{
+			name:  "break outside of loop",
+			input: "    BREAK\n",
+			want:  []string{"BREAK outside of a LOOP or FOR"},
+		},
+		{
+			name:  "statement before first case",
+			input: "    SWITCH A\n    STOP\n    CASE 1\n    ENDSWITCH\n",
+			want:  []string{"statements between SWITCH and its first CASE"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := lexer.New(bufio.NewReader(strings.NewReader(tt.input)), "test")
+			p := New(l)
+			p.ParseProgram()
+			has, errs := p.Errors()
+			if !has {
+				t.Fatalf("got no errors, want %q", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(errs[0].Error(), want) {
+					t.Errorf("got %q, want it to contain %q", errs[0], want)
+				}
+			}
+		})
+	}
+}
+
+func TestVerbs_WellFormed(t *testing.T) {
+	for verb, spec := range Verbs {
+		if verb != strings.ToUpper(verb) {
+			t.Errorf("%s: verbs have to be upper case", verb)
+		}
+		for i, op := range spec.Operands {
+			isSep := len(op) == 1 && op[0] == tokens.PREP
+			if isSep != (i%2 == 1) {
+				t.Errorf("%s: operand positions and separators have to alternate, position %d is %v", verb, i, op)
+			}
+		}
+		if len(spec.Operands) > 0 && len(spec.Operands)%2 == 0 {
+			t.Errorf("%s: operand list must not end in a separator", verb)
+		}
+		if spec.Optional > len(spec.positions()) {
+			t.Errorf("%s: %d optional operands, but only %d positions", verb, spec.Optional, len(spec.positions()))
+		}
+		if spec.Variadic && len(spec.Operands) == 0 {
+			t.Errorf("%s: variadic verb without operands", verb)
+		}
+	}
+}
+
+func TestParser_VerbOperands(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "move literal",
+			input: "    MOVE \"HELLO\" TO VARONE\n",
+			want:  " MOVE \"HELLO\" TO VARONE\n",
+		},
+		{
+			name:  "move array element",
+			input: "    MOVE ARR(2) TO VARONE\n",
+			want:  " MOVE ARR(2) TO VARONE\n",
+		},
+		{
+			name:  "add numeric literal",
+			input: "    ADD \"1\" TO VARTHREE\n",
+			want:  " ADD \"1\" TO VARTHREE\n",
+		},
+		{
+			name:  "add bare integer",
+			input: "    ADD 1 TO VARTHREE\n",
+			want:  " ADD 1 TO VARTHREE\n",
+		},
+		{
+			name:  "subtract bare integer",
+			input: "    SUBTRACT 2 FROM VARTHREE\n",
+			want:  " SUBTRACT 2 FROM VARTHREE\n",
+		},
+		{
+			name:  "move bare integer",
+			input: "    MOVE 5 TO VARTHREE\n",
+			want:  " MOVE 5 TO VARTHREE\n",
+		},
+		{
+			name:  "read key and list",
+			input: "    READ F,K;A,B\n",
+			want:  " READ F,K;A,B\n",
+		},
+		{
+			name:  "write key and list",
+			input: "    WRITE F,\"KEY\";A,B\n",
+			want:  " WRITE F,\"KEY\";A,B\n",
+		},
+		{
+			name:  "update list",
+			input: "    UPDATE F;A,B\n",
+			want:  " UPDATE F;A,B\n",
+		},
+		{
+			name:  "optional operand omitted",
+			input: "    RESET VARONE\n",
+			want:  " RESET VARONE\n",
+		},
+		{
+			name:  "signed operand",
+			input: "    BUMP VARONE BY -1\n",
+			want:  " BUMP VARONE BY -1\n",
+		},
+		{
+			name:  "variadic",
+			input: "    BRANCH IDX OF ONE,TWO,THREE\n",
+			want:  " BRANCH IDX OF ONE,TWO,THREE\n",
+		},
+		{
+			name:  "lower case verb",
+			input: "    display varone\n",
+			want:  " DISPLAY varone\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog := parse(t, tt.input)
+			if got := prog.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_DataDeclarations(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		kind     string
+		label    string
+		operands []string
+	}{
+		{name: "dim", input: "VARONE DIM 10\n", kind: "DIM", label: "VARONE", operands: []string{"10"}},
+		{name: "form with decimals", input: "AMOUNT FORM 5.2\n", kind: "FORM", label: "AMOUNT", operands: []string{"5.2"}},
+		{name: "form with initial value", input: "VARFOUR form \"-10\"\n", kind: "FORM", label: "VARFOUR", operands: []string{"\"-10\""}},
+		{name: "init", input: "VARTWO INIT \"SECOND VAR\",0x0D\n", kind: "INIT", label: "VARTWO", operands: []string{"\"SECOND VAR\"", "0x0D"}},
+		{name: "unlabelled", input: "    DIM 10\n", kind: "DIM", operands: []string{"10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog := parse(t, tt.input)
+			if len(prog.Statements) != 1 {
+				t.Fatalf("got %d statements, want 1", len(prog.Statements))
+			}
+			decl, ok := prog.Statements[0].(*ast.DataDeclaration)
+			if !ok {
+				t.Fatalf("got %T, want *ast.DataDeclaration", prog.Statements[0])
+			}
+			if decl.Kind != tt.kind {
+				t.Errorf("got kind %q, want %q", decl.Kind, tt.kind)
+			}
+			if (decl.Name == nil && tt.label != "") || (decl.Name != nil && decl.Name.Value != tt.label) {
+				t.Errorf("got label %v, want %q", decl.Name, tt.label)
+			}
+			if len(decl.Operands) != len(tt.operands) {
+				t.Fatalf("got %d operands, want %d", len(decl.Operands), len(tt.operands))
+			}
+			for i, want := range tt.operands {
+				if got := decl.Operands[i].String(); got != want {
+					t.Errorf("operand %d: got %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParser_VerbOperands_Error(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "unknown verb",
+			input: "    FROBNICATE A\n",
+			want:  `unknown verb "FROBNICATE"`,
+		},
+		{
+			name:  "missing operand",
+			input: "    MOVE A\n",
+			want:  "MOVE expects at least 2 operands, got 1",
+		},
+		{
+			name:  "too many operands",
+			input: "    GOTO A,B\n",
+			want:  "GOTO expects at most 1 operands, got 2",
+		},
+		{
+			name:  "operand missing at the end of the line",
+			input: "    MOVE A TO\n",
+			want:  "missing operand at the end of the line",
+		},
+		{
+			name:  "token that cannot start an operand",
+			input: "    MOVE ) TO A\n",
+			want:  `unexpected RPAREN ")", expected an operand`,
+		},
+		{
+			name:  "semicolon outside of the file verbs",
+			input: "    MOVE A;B\n",
+			want:  `unexpected SEMICOLON ";", expected the end of the line`,
+		},
+		{
+			name:  "literal destination",
+			input: "    MOVE A TO \"B\"\n",
+			want:  "operand 2 of MOVE must be CVAR or NVAR",
+		},
+		{
+			name:  "character literal in numeric operation",
+			input: "    ADD \"ONE\" TO A\n",
+			want:  "operand 1 of ADD must be NVAR or NUMERICLITERAL",
+		},
+		{
+			name:  "dim without size",
+			input: "A DIM\n",
+			want:  "DIM expects at least 1 operands, got 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := lexer.New(bufio.NewReader(strings.NewReader(tt.input)), "test")
+			p := New(l)
+			p.ParseProgram()
+			has, errs := p.Errors()
+			if !has {
+				t.Fatalf("got no errors, want %q", tt.want)
+			}
+			if !strings.Contains(errs[0].Error(), tt.want) {
+				t.Errorf("got %q, want it to contain %q", errs[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ErrorRecovery(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		codes      []string // expected error codes in order
+		lines      []int    // expected error lines in order
+		statements int      // number of top level statements, including the bad ones
+		bad        int      // number of top level BadStatements
+	}{
+		{
+			name: "errors on separate lines",
+			input: `    MOVE "A" TO VARONE
+    FROBNICATE VARONE
+    MOVE "B" TO VARONE
+    ADD "X" TO VARONE
+    GOTO TOP IF GREATER
+    DISPLAY VARONE
+`,
+			codes:      []string{plbErrors.ErrUnknownVerb, plbErrors.ErrOperandClass, plbErrors.ErrInvalidCondition},
+			lines:      []int{2, 4, 5},
+			statements: 6,
+			bad:        2,
+		},
+		{
+			name: "broken expression",
+			input: `    GOTO TOP IF (A = )
+    GOTO TOP IF (A = 1
+    STOP
+`,
+			codes:      []string{plbErrors.ErrInvalidExpression, plbErrors.ErrUnexpectedToken},
+			lines:      []int{1, 2},
+			statements: 3,
+			bad:        1,
+		},
+		{
+			name: "errors inside a block keep the block",
+			input: `    LOOP
+    MOVE A
+    BREAK IF OVER
+    ADD
+    REPEAT
+    STOP
+`,
+			codes:      []string{plbErrors.ErrOperandCount, plbErrors.ErrOperandCount},
+			lines:      []int{2, 4},
+			statements: 2,
+		},
+		{
+			name: "broken block header still parses the block",
+			input: `    IF (A = )
+    STOP
+    ENDIF
+    STOP
+`,
+			codes:      []string{plbErrors.ErrInvalidExpression},
+			lines:      []int{1},
+			statements: 2,
+		},
+		{
+			name: "stray closer inside a block",
+			input: `    LOOP
+    ENDSWITCH
+    STOP
+    REPEAT
+`,
+			codes:      []string{plbErrors.ErrUnmatchedBlock},
+			lines:      []int{2},
+			statements: 1,
+		},
+		{
+			name: "unterminated blocks",
+			input: `    LOOP
+    IF EQUAL
+    STOP
+`,
+			codes:      []string{plbErrors.ErrUnterminatedBlock, plbErrors.ErrUnterminatedBlock},
+			lines:      []int{4, 4},
+			statements: 1,
+		},
+		{
+			name: "lexer errors are reported once",
+			input: `    MOVE ~ TO A
+    STOP
+`,
+			codes:      []string{plbErrors.ErrIllegalCharacter},
+			lines:      []int{1},
+			statements: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := lexer.New(bufio.NewReader(strings.NewReader(tt.input)), "test")
+			p := New(l)
+			prog := p.ParseProgram()
+			_, errs := p.Errors()
+			if len(errs) != len(tt.codes) {
+				for _, err := range errs {
+					t.Log(err)
+				}
+				t.Fatalf("got %d errors, want %d", len(errs), len(tt.codes))
+			}
+			for i, err := range errs {
+				plbErr, ok := err.(*plbErrors.PLBError)
+				if !ok {
+					t.Fatalf("error %d: got %T, want *plbErrors.PLBError", i, err)
+				}
+				if plbErr.ErrorCode != tt.codes[i] {
+					t.Errorf("error %d: got code %s, want %s (%s)", i, plbErr.ErrorCode, tt.codes[i], plbErr.Message)
+				}
+				if plbErr.LineNumber != tt.lines[i] {
+					t.Errorf("error %d: got line %d, want %d (%s)", i, plbErr.LineNumber, tt.lines[i], plbErr.Message)
+				}
+			}
+			if len(prog.Statements) != tt.statements {
+				t.Errorf("got %d statements, want %d:\n%s", len(prog.Statements), tt.statements, prog)
+			}
+			bad := 0
+			for _, stmt := range prog.Statements {
+				if _, ok := stmt.(*ast.BadStatement); ok {
+					bad++
+				}
+			}
+			if bad != tt.bad {
+				t.Errorf("got %d bad statements, want %d:\n%s", bad, tt.bad, prog)
+			}
+		})
+	}
+}
+
+func TestParser_BadNodes(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // the program, bad nodes print their source text
+		bad   string // text of the bad node in the first statement
+		end   int    // column of the last token spanned by the bad node
+	}{
+		{"    FROBNICATE X, Y\n    STOP\n", "    FROBNICATE X, Y\n STOP\n", "    FROBNICATE X, Y", 19},
+		{"    CALL )\n", "    CALL )\n", "    CALL )", 10},
+		{"    MOVE ~ TO A\n    STOP\n", " MOVE ~ TO A\n STOP\n", "~", 10},
+		{"    MOVE ) ) TO A\n", " MOVE ) ) TO A\n", ") )", 12},
+		{"    GOTO TOP IF (A = )\n", " GOTO TOP IF (A = )\n", "(A = )", 22},
+		{"    IF (A = )\n    STOP\n    ENDIF\n", " IF (A = )\n STOP\n ENDIF\n", "(A = )", 13},
+		{"    FOR I FROM\n    STOP\n    REPEAT\n", " FOR I FROM\n STOP\n REPEAT\n", "I FROM", 11},
+		{"    SWITCH )\n    CASE 1\n    STOP\n    ENDSWITCH\n", " SWITCH )\n CASE 1\n STOP\n ENDSWITCH\n", ")", 12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			prog, diag := ParseString(tt.input, Options{})
+			if len(diag.Errors) == 0 {
+				t.Fatal("got no errors, want at least one")
+			}
+			if got := prog.String(); got != tt.want {
+				t.Errorf("got program %q, want %q", got, tt.want)
+			}
+
+			var text string
+			var end tokens.Token
+			switch s := prog.Statements[0].(type) {
+			case *ast.BadStatement:
+				text, end = s.Text, s.EndToken
+			case *ast.VerbStatement:
+				exprs := append([]ast.Expression{s.Condition}, s.Operands...)
+				for _, expr := range exprs {
+					if bad, ok := expr.(*ast.BadExpression); ok {
+						text, end = bad.Text, bad.EndToken
+					}
+				}
+			case *ast.IfStatement:
+				if bad, ok := s.Condition.(*ast.BadExpression); ok {
+					text, end = bad.Text, bad.EndToken
+				}
+			case *ast.ForStatement:
+				if bad, ok := s.Variable.(*ast.BadExpression); ok {
+					text, end = bad.Text, bad.EndToken
+				}
+			case *ast.SwitchStatement:
+				if bad, ok := s.Subject.(*ast.BadExpression); ok {
+					text, end = bad.Text, bad.EndToken
+				}
+			}
+			if text != tt.bad {
+				t.Errorf("got bad node %q, want %q", text, tt.bad)
+			}
+			if end.Col != tt.end {
+				t.Errorf("got bad node ending at column %d, want %d", end.Col, tt.end)
+			}
+		})
+	}
+}
+
+func TestParser_Diagnostics(t *testing.T) {
+	input := `TOP
+    MOVE "A" TO B
+    FROBNICATE B
+    STOP
+`
+	t.Run("collect", func(t *testing.T) {
+		diag := &plbErrors.Collector{}
+		l := lexer.New(bufio.NewReader(strings.NewReader(input)), "test")
+		p := New(l, WithDiagnostics(diag))
+		p.ParseProgram()
+		if len(diag.Errors) != 1 || diag.Errors[0].ErrorCode != plbErrors.ErrUnknownVerb {
+			t.Errorf("got errors %v, want a single %s", diag.Errors, plbErrors.ErrUnknownVerb)
+		}
+		if len(diag.Traces) != 0 {
+			t.Errorf("got %d trace events without WithTrace, want none", len(diag.Traces))
+		}
+		if _, errs := p.Errors(); len(errs) != 1 {
+			t.Errorf("got %d errors from Errors, want 1", len(errs))
+		}
+		want := "Error E203: unknown verb \"FROBNICATE\"\nLocation: test 3:5\n    FROBNICATE B\n    ^\n"
+		if len(diag.Errors) == 1 && diag.Errors[0].Error() != want {
+			t.Errorf("got rendered error %q, want %q", diag.Errors[0].Error(), want)
+		}
+	})
+
+	t.Run("trace", func(t *testing.T) {
+		diag := &plbErrors.Collector{}
+		l := lexer.New(bufio.NewReader(strings.NewReader(input)), "test")
+		p := New(l, WithDiagnostics(diag), WithTrace())
+		p.ParseProgram()
+		want := []string{
+			`parsed LabelStatement "TOP"`,
+			`parsed VerbStatement "MOVE"`,
+			`skipped the line after an error`,
+			`parsed VerbStatement "STOP"`,
+		}
+		if len(diag.Traces) != len(want) {
+			t.Fatalf("got %d trace events, want %d: %v", len(diag.Traces), len(want), diag.Traces)
+		}
+		for i, event := range diag.Traces {
+			if event.Message != want[i] || event.Line != i+1 || event.Stage != "parser" {
+				t.Errorf("event %d: got %+v, want %q on line %d", i, event, want[i], i+1)
+			}
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		diag := &plbErrors.Collector{}
+		keep := func(err *plbErrors.PLBError) bool { return err.ErrorCode != plbErrors.ErrUnknownVerb }
+		l := lexer.New(bufio.NewReader(strings.NewReader(input)), "test")
+		p := New(l, WithDiagnostics(plbErrors.Filter(diag, keep)))
+		p.ParseProgram()
+		if len(diag.Errors) != 0 {
+			t.Errorf("got errors %v, want them filtered", diag.Errors)
+		}
+	})
+}
+
+func TestParser_Routines(t *testing.T) {
+	input := `    CALL SUB USING A,"B"
+    CALL TOP IF EQUAL
+    STOP
+SUB ROUTINE X,Y
+    MOVE X TO Y
+    RETURN
+LOCAL LROUTINE
+TMP DIM 10
+    MOVE "A" TO TMP
+    RETURN
+`
+	prog := parse(t, input)
+	if len(prog.Statements) != 5 {
+		t.Fatalf("got %d statements, want 5:\n%s", len(prog.Statements), prog)
+	}
+
+	call, ok := prog.Statements[0].(*ast.CallStatement)
+	if !ok {
+		t.Fatalf("statement 0: got %T, want *ast.CallStatement", prog.Statements[0])
+	}
+	if call.Target.Value != "SUB" || len(call.Args) != 2 || call.Args[1].String() != `"B"` {
+		t.Errorf("statement 0: got %q, want a call of SUB with 2 arguments", call)
+	}
+	if call, ok := prog.Statements[1].(*ast.CallStatement); !ok || call.Condition == nil || len(call.Args) != 0 {
+		t.Errorf("statement 1: got %q, want a conditional call without arguments", prog.Statements[1])
+	}
+
+	sub, ok := prog.Statements[3].(*ast.RoutineStatement)
+	if !ok {
+		t.Fatalf("statement 3: got %T, want *ast.RoutineStatement", prog.Statements[3])
+	}
+	if sub.Name.Value != "SUB" || sub.Kind != "ROUTINE" || len(sub.Params) != 2 || len(sub.Body.Statements) != 2 {
+		t.Errorf("statement 3: got %q, want ROUTINE SUB with 2 parameters and 2 statements", sub)
+	}
+
+	local, ok := prog.Statements[4].(*ast.RoutineStatement)
+	if !ok {
+		t.Fatalf("statement 4: got %T, want *ast.RoutineStatement", prog.Statements[4])
+	}
+	if !local.IsLocal() || len(local.Params) != 0 || len(local.Body.Statements) != 3 {
+		t.Errorf("statement 4: got %q, want LROUTINE LOCAL without parameters and 3 statements", local)
+	}
+	if _, ok := local.Body.Statements[0].(*ast.DataDeclaration); !ok {
+		t.Errorf("got %T as first statement of LOCAL, want its local *ast.DataDeclaration", local.Body.Statements[0])
+	}
+
+	t.Run("several exits", func(t *testing.T) {
+		prog := parse(t, "SUB LROUTINE\nT DIM 1\n    GOTO X IF EQUAL\n    RETURN\nX\n    MOVE \"1\" TO T\n    DISPLAY T\n    RETURN\nNEXT ROUTINE\n    RETURN\n")
+		if len(prog.Statements) != 2 {
+			t.Fatalf("got %d statements, want the two routines:\n%s", len(prog.Statements), prog)
+		}
+		sub := prog.Statements[0].(*ast.RoutineStatement)
+		if len(sub.Body.Statements) != 7 {
+			t.Fatalf("got %d statements in SUB, want 7 up to the next routine:\n%s", len(sub.Body.Statements), sub)
+		}
+		if label, ok := sub.Body.Statements[3].(*ast.LabelStatement); !ok || label.Name.Value != "X" {
+			t.Errorf("statement 3 of SUB: got %v, want the label X between the RETURNs", sub.Body.Statements[3])
+		}
+		if next := prog.Statements[1].(*ast.RoutineStatement); next.Name.Value != "NEXT" {
+			t.Errorf("got routine %s, want NEXT", next.Name.Value)
+		}
+	})
+}
+
+func TestParser_Routines_Error(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		code  string
+	}{
+		{name: "unlabelled routine", input: "    ROUTINE A\n", code: plbErrors.ErrMissingLabel},
+		{name: "routine inside a block", input: "    LOOP\nSUB ROUTINE\n    REPEAT\n", code: plbErrors.ErrMisplacedStatement},
+		{name: "literal parameter", input: "SUB ROUTINE \"A\"\n", code: plbErrors.ErrOperandClass},
+		{name: "call without USING", input: "    CALL SUB,A\n", code: plbErrors.ErrUnexpectedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := lexer.New(bufio.NewReader(strings.NewReader(tt.input)), "test")
+			p := New(l)
+			p.ParseProgram()
+			_, errs := p.Errors()
+			if len(errs) == 0 {
+				t.Fatalf("got no errors, want %s", tt.code)
+			}
+			if code := errs[0].(*plbErrors.PLBError).ErrorCode; code != tt.code {
+				t.Errorf("got %s (%s), want %s", code, errs[0], tt.code)
+			}
+		})
+	}
+}
+
+func TestParser_FileDeclarations(t *testing.T) {
+	tests := []struct {
+		input string
+		want  ast.FileDeclaration
+	}{
+		{input: "LOG FILE\n", want: ast.FileDeclaration{Kind: "FILE"}},
+		{input: "LOG file VAR=80,TEXT,UNCOMP\n", want: ast.FileDeclaration{Kind: "FILE", RecordLength: 80}},
+		{input: "DATA FILE FIXED=256,BINARY,COMP\n", want: ast.FileDeclaration{Kind: "FILE", Fixed: true, RecordLength: 256, Binary: true, Compressed: true}},
+		{input: "CUST IFILE KEYLEN=10,FIXED=128,dup\n", want: ast.FileDeclaration{Kind: "IFILE", Fixed: true, RecordLength: 128, KeyLength: 10, Dup: true}},
+		{input: "NAMES AFILE NODUP,COMPRESSED\n", want: ast.FileDeclaration{Kind: "AFILE", Compressed: true}},
+		{input: "PRT PFILE\n", want: ast.FileDeclaration{Kind: "PFILE"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			prog := parse(t, tt.input)
+			if len(prog.Statements) != 1 {
+				t.Fatalf("got %d statements, want 1", len(prog.Statements))
+			}
+			decl, ok := prog.Statements[0].(*ast.FileDeclaration)
+			if !ok {
+				t.Fatalf("got %T, want *ast.FileDeclaration", prog.Statements[0])
+			}
+			got := *decl
+			got.Token, got.Name, got.EndToken = tokens.Token{}, nil, tokens.Token{}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if decl.Name == nil || decl.Name.Value != strings.Fields(tt.input)[0] {
+				t.Errorf("got name %v, want %q", decl.Name, strings.Fields(tt.input)[0])
+			}
+			// the canonical form has to parse to the same declaration
+			again := parse(t, decl.String()).Statements[0].(*ast.FileDeclaration)
+			again.Token, again.Name, again.EndToken = tokens.Token{}, nil, tokens.Token{}
+			if *again != got {
+				t.Errorf("%q parsed to %+v, want %+v", decl, *again, got)
+			}
+		})
+	}
+}
+
+func TestParser_FileDeclarations_Error(t *testing.T) {
+	tests := []struct {
+		input string
+		msg   string
+	}{
+		{input: "F FILE FIXED=10,VAR=20\n", msg: "VAR conflicts with FIXED"},
+		{input: "F IFILE DUP,DUP\n", msg: "DUP is given twice"},
+		{input: "F IFILE DUP,NODUP\n", msg: "NODUP conflicts with DUP"},
+		{input: "F FILE KEYLEN=10\n", msg: "only allowed on an IFILE or AFILE"},
+		{input: "F FILE FIXED\n", msg: "FIXED needs a length"},
+		{input: "F FILE TEXT=1\n", msg: "TEXT does not take a value"},
+		{input: "F FILE FIXED=0\n", msg: "must be positive"},
+		{input: "F FILE FIXED=\"10\"\n", msg: "must be a decimal number"},
+		{input: "F FILE SHARED\n", msg: "unknown FILE attribute"},
+		{input: "F FILE \"TEXT\"\n", msg: "invalid FILE attribute"},
+		{input: "P PFILE TEXT\n", msg: "PFILE takes no attributes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			l := lexer.New(bufio.NewReader(strings.NewReader(tt.input)), "test")
+			p := New(l)
+			prog := p.ParseProgram()
+			_, errs := p.Errors()
+			if len(errs) != 1 {
+				t.Fatalf("got %d errors %v, want 1", len(errs), errs)
+			}
+			err := errs[0].(*plbErrors.PLBError)
+			if err.ErrorCode != plbErrors.ErrInvalidAttribute || !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("got %s, want %s containing %q", err, plbErrors.ErrInvalidAttribute, tt.msg)
+			}
+			if len(prog.Statements) != 1 {
+				t.Fatalf("got %d statements, want 1", len(prog.Statements))
+			}
+			if _, ok := prog.Statements[0].(*ast.BadStatement); !ok {
+				t.Errorf("got %T, want *ast.BadStatement", prog.Statements[0])
+			}
+		})
+	}
+}
+
+func TestParser_Pointers(t *testing.T) {
+	input := `NAME DIM 10
+PTR DIM ^
+NPTR FORM ^
+ANY
+    VAR @
+    MOVEADR NAME TO PTR
+    MOVEPTR PTR TO ANY
+    MOVE "X" TO PTR
+    TYPE ANY
+    GOTO DONE IF (PTR = "X")
+    TRAP BAD IF range
+`
+	prog := parse(t, input)
+	if len(prog.Statements) != 11 {
+		t.Fatalf("got %d statements, want 11:\n%s", len(prog.Statements), prog)
+	}
+
+	kinds := []string{"DIM", "FORM", "VAR"}
+	for i, stmt := range []ast.Statement{prog.Statements[1], prog.Statements[2], prog.Statements[4]} {
+		decl, ok := stmt.(*ast.PointerDeclaration)
+		if !ok {
+			t.Fatalf("got %T, want *ast.PointerDeclaration", stmt)
+		}
+		if decl.Kind != kinds[i] {
+			t.Errorf("got pointer to %s, want %s", decl.Kind, kinds[i])
+		}
+	}
+
+	// the pointer itself is the operand of MOVEADR, MOVEPTR and TYPE, everywhere else it refers to its target
+	derefs := []struct {
+		stmt     int
+		operands []bool
+	}{
+		{stmt: 5, operands: []bool{false, false}},
+		{stmt: 6, operands: []bool{false, false}},
+		{stmt: 7, operands: []bool{false, true}},
+		{stmt: 8, operands: []bool{false}},
+	}
+	for _, tt := range derefs {
+		stmt := prog.Statements[tt.stmt].(*ast.VerbStatement)
+		for i, want := range tt.operands {
+			if _, got := stmt.Operands[i].(*ast.DerefExpression); got != want {
+				t.Errorf("%s: operand %d got %T, dereferenced should be %t", strings.TrimSpace(stmt.String()), i+1, stmt.Operands[i], want)
+			}
+		}
+	}
+	cond := prog.Statements[9].(*ast.VerbStatement).Condition.(*ast.GroupedExpression).Expression.(*ast.InfixExpression)
+	if _, ok := cond.Left.(*ast.DerefExpression); !ok {
+		t.Errorf("got %T in the condition, want *ast.DerefExpression", cond.Left)
+	}
+	if trap := prog.Statements[10].(*ast.VerbStatement); trap.Condition.String() != "RANGE" {
+		t.Errorf("got TRAP event %q, want RANGE", trap.Condition)
+	}
+}
+
+func TestParser_Pointers_Error(t *testing.T) {
+	tests := []struct {
+		input string
+		code  string
+	}{
+		{input: "P DIM @\n", code: plbErrors.ErrUnexpectedToken},
+		{input: "P VAR ^\n", code: plbErrors.ErrUnexpectedToken},
+		{input: "P VAR\n", code: plbErrors.ErrUnexpectedToken},
+		{input: "P INIT ^\n", code: plbErrors.ErrUnexpectedToken},
+		{input: "P DIM ^ 10\n", code: plbErrors.ErrUnexpectedToken},
+		{input: "    MOVEADR \"X\" TO P\n", code: plbErrors.ErrOperandClass},
+		{input: "    TRAP BAD\n", code: plbErrors.ErrInvalidCondition},
+		{input: "    TRAP BAD IF EQUAL\n", code: plbErrors.ErrInvalidCondition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			l := lexer.New(bufio.NewReader(strings.NewReader(tt.input)), "test")
+			p := New(l)
+			p.ParseProgram()
+			_, errs := p.Errors()
+			if len(errs) == 0 {
+				t.Fatalf("got no errors, want %s", tt.code)
+			}
+			if code := errs[0].(*plbErrors.PLBError).ErrorCode; code != tt.code {
+				t.Errorf("got %s (%s), want %s", code, errs[0], tt.code)
+			}
+		})
+	}
+}
+
+func TestParser_ListStatements(t *testing.T) {
+	tests := []struct {
+		input string
+		kinds []ast.ListItemKind
+		want  string
+	}{
+		{
+			input: "    DISPLAY *ES,VARONE\n",
+			kinds: []ast.ListItemKind{ast.AttributeItem, ast.VariableItem},
+			want:  " DISPLAY *ES,VARONE\n",
+		},
+		{
+			input: "    DISPLAY *P=10:2,VARTWO\n",
+			kinds: []ast.ListItemKind{ast.PositionItem, ast.VariableItem},
+			want:  " DISPLAY *P=10:2,VARTWO\n",
+		},
+		{
+			input: "    DISPLAY *P=10:4,VARFOUR!\n",
+			kinds: []ast.ListItemKind{ast.PositionItem, ast.VariableItem, ast.SuppressItem},
+			want:  " DISPLAY *P=10:4,VARFOUR!\n",
+		},
+		{
+			input: "    display *p10:row,\"NAME: \",name(2);\n",
+			kinds: []ast.ListItemKind{ast.PositionItem, ast.LiteralItem, ast.VariableItem, ast.SuppressItem},
+			want:  " DISPLAY *P=10:row,\"NAME: \",name(2);\n",
+		},
+		{
+			input: "    KEYIN *P=1:24,\"PASSWORD? \",*IT,*T=30,PASS\n",
+			kinds: []ast.ListItemKind{ast.PositionItem, ast.LiteralItem, ast.AttributeItem, ast.AttributeItem, ast.VariableItem},
+			want:  " KEYIN *P=1:24,\"PASSWORD? \",*IT,*T=30,PASS\n",
+		},
+		{
+			input: "    PRINT *F,\"TOTAL\",*H=40,SUM,*N\n",
+			kinds: []ast.ListItemKind{ast.PositionItem, ast.LiteralItem, ast.PositionItem, ast.VariableItem, ast.PositionItem},
+			want:  " PRINT *F,\"TOTAL\",*H=40,SUM,*N\n",
+		},
+		{
+			input: "    DISPLAY\n",
+			want:  " DISPLAY\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			prog := parse(t, tt.input)
+			stmt, ok := prog.Statements[0].(*ast.ListStatement)
+			if !ok {
+				t.Fatalf("got %T, want *ast.ListStatement", prog.Statements[0])
+			}
+			if len(stmt.Items) != len(tt.kinds) {
+				t.Fatalf("got %d items, want %d", len(stmt.Items), len(tt.kinds))
+			}
+			for i, item := range stmt.Items {
+				if item.Kind != tt.kinds[i] {
+					t.Errorf("item %d: got %s, want %s", i, item.Kind, tt.kinds[i])
+				}
+			}
+			if got := stmt.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ListStatements_Error(t *testing.T) {
+	tests := []struct {
+		input string
+		code  string
+	}{
+		{input: "    DISPLAY *F\n", code: plbErrors.ErrListControl},
+		{input: "    PRINT *ES\n", code: plbErrors.ErrListControl},
+		{input: "    DISPLAY *IT,NAME\n", code: plbErrors.ErrListControl},
+		{input: "    DISPLAY *P=10\n", code: plbErrors.ErrListControl},
+		{input: "    DISPLAY *ES=1\n", code: plbErrors.ErrListControl},
+		{input: "    DISPLAY *P=\"10\":2\n", code: plbErrors.ErrListControl},
+		{input: "    DISPLAY A!,B\n", code: plbErrors.ErrUnexpectedToken},
+		{input: "    DISPLAY A+B\n", code: plbErrors.ErrOperandClass},
+		{input: "    DISPLAY A TO B\n", code: plbErrors.ErrUnexpectedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			l := lexer.New(bufio.NewReader(strings.NewReader(tt.input)), "test")
+			p := New(l)
+			p.ParseProgram()
+			_, errs := p.Errors()
+			if len(errs) == 0 {
+				t.Fatalf("got no errors, want %s", tt.code)
+			}
+			if code := errs[0].(*plbErrors.PLBError).ErrorCode; code != tt.code {
+				t.Errorf("got %s (%s), want %s", code, errs[0], tt.code)
+			}
+		})
+	}
+}
+
+func TestParser_Example(t *testing.T) {
+	file, err := os.Open("../examples/test.plb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	p := New(lexer.New(bufio.NewReader(file), "test.plb"))
+	p.ParseProgram()
+	if _, errs := p.Errors(); len(errs) > 0 {
+		for _, err := range errs {
+			t.Error(err)
+		}
+	}
+}
+
+func TestParser_Positions(t *testing.T) {
+	tests := []struct {
+		input string
+		node  string // type of the first node of interest in traversal order
+		want  string // its span as start-end in line:column
+	}{
+		{"    MOVE \"A\"\"B\" TO X\n", "*ast.VerbStatement", "1:5-1:21"},
+		{"    MOVE \"A\"\"B\" TO X\n", "*ast.StringLiteral", "1:10-1:16"},
+		{"LBL GOTO X IF NOT EQUAL\n", "*ast.VerbStatement", "1:1-1:24"},
+		{"LBL GOTO X IF NOT EQUAL\n", "*ast.PrefixExpression", "1:15-1:24"},
+		{"LBL MOVE A(2) TO X\n", "*ast.IndexExpression", "1:10-1:14"},
+		{"    IF (A = 1)\n    STOP\n    ENDIF\n", "*ast.IfStatement", "1:5-3:10"},
+		{"    IF (A = 1)\n    STOP\n    ENDIF\n", "*ast.GroupedExpression", "1:8-1:15"},
+		{"    IF (A = 1)\n    STOP\n    ENDIF\n", "*ast.InfixExpression", "1:9-1:14"},
+		{"    IF (A = 1)\n    STOP\n    ENDIF\n", "*ast.BlockStatement", "2:5-2:9"},
+		{"    IF EQUAL\n    STOP\n", "*ast.IfStatement", "1:5-2:9"},
+		{"    DISPLAY *P=10:2,X;\n", "*ast.ListItem", "1:13-1:20"},
+		{"    DISPLAY *P=10:2,X;\n", "*ast.ListStatement", "1:5-1:23"},
+		{"F   IFILE KEYLEN=10,FIXED=128\n", "*ast.FileDeclaration", "1:1-1:30"},
+		{"P   DIM ^\n", "*ast.PointerDeclaration", "1:1-1:10"},
+		{"    INCLUDE common.pls\n", "*ast.IncludeStatement", "1:5-1:23"},
+		{"X   ROUTINE A,B\n    RETURN\n", "*ast.RoutineStatement", "1:1-2:11"},
+		{"    MOVE ) TO A\n", "*ast.BadExpression", "1:10-1:11"},
+		{"    FROBNICATE X\n", "*ast.BadStatement", "1:1-1:17"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.node+" of "+tt.input, func(t *testing.T) {
+			prog, _ := ParseString(tt.input, Options{})
+			var found ast.Node
+			ast.Inspect(prog, func(n ast.Node) bool {
+				if found == nil && n != nil && fmt.Sprintf("%T", n) == tt.node {
+					found = n
+				}
+				return found == nil
+			})
+			if found == nil {
+				t.Fatalf("no %s in %q", tt.node, prog)
+			}
+			pos, end := found.Pos(), found.End()
+			if got := fmt.Sprintf("%d:%d-%d:%d", pos.Line, pos.Col, end.Line, end.Col); got != tt.want {
+				t.Errorf("got span %s of %q, want %s", got, found, tt.want)
+			}
+		})
+	}
+}
+
+// TestParser_PositionsNest checks that every node of the example lies within its parent
+func TestParser_PositionsNest(t *testing.T) {
+	prog, _ := ParseFile("../examples/test.plb", Options{})
+	var parents []ast.Node
+	ast.Inspect(prog, func(n ast.Node) bool {
+		if n == nil {
+			parents = parents[:len(parents)-1]
+			return false
+		}
+		pos, end := n.Pos(), n.End()
+		if !pos.IsValid() || !end.IsValid() || end.Before(pos) {
+			t.Errorf("%T %q has the span %s to %s", n, n, pos, end)
+		}
+		if len(parents) > 0 {
+			parent := parents[len(parents)-1]
+			if pos.Before(parent.Pos()) || parent.End().Before(end) {
+				t.Errorf("%T %q at %s to %s is outside of its parent %T at %s to %s", n, n, pos, end, parent, parent.Pos(), parent.End())
+			}
+		}
+		parents = append(parents, n)
+		return true
+	})
+}
+
+func TestParser_JSONRoundTrip(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.plb": `P   DIM ^
+F   IFILE KEYLEN=10,FIXED=128
+    INCLUDE common.pls
+X   ROUTINE A,B
+    MOVE "A""B" TO A(2)
+    DISPLAY *P=10:2,A,"B";
+    IF (A = 1)
+    CALL X USING A,B IF NOT EQUAL
+    ELSE
+    FROBNICATE
+    ENDIF
+    RETURN
+`,
+		"common.pls": "C   FORM 5.2\n",
+	})
+	prog, _ := ParseFile(filepath.Join(dir, "main.plb"), Options{})
+	example, _ := ParseFile("../examples/test.plb", Options{})
+
+	for _, prog := range []*ast.Program{prog, example} {
+		var out bytes.Buffer
+		if err := ast.EncodeJSON(&out, prog); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ast.DecodeJSON(&out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, prog) {
+			t.Errorf("got %q after the round trip, want %q", got, prog)
+		}
+	}
+}
+
+func TestParser_PrintRoundTrip(t *testing.T) {
+	paths, err := filepath.Glob("../examples/*.plb")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no examples: %v", err)
+	}
+	paths = append(paths, "") // the literal program below
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			var prog *ast.Program
+			var diag *plbErrors.Collector
+			if path == "" {
+				prog, diag = ParseString(`Q   INIT "A ""B"" C",""""`+"\n    DISPLAY Q,\"\"\"\"\n", Options{})
+			} else {
+				prog, diag = ParseFile(path, Options{})
+			}
+			if len(diag.Errors) > 0 {
+				t.Fatalf("cannot parse the example: %v", diag.Errors[0])
+			}
+
+			printed := prog.String()
+			again, diag := ParseString(printed, Options{})
+			for _, err := range diag.Errors {
+				t.Errorf("printed program does not parse: %v", err)
+			}
+			if diff := treeDiff(reflect.ValueOf(prog), reflect.ValueOf(again), "program"); diff != "" {
+				t.Errorf("printed program parses to a different tree at %s:\n%s", diff, printed)
+			}
+		})
+	}
+}
+
+// treeDiff compares two trees ignoring the tokens that only locate a node, e.g. the verb token of a statement.
+// Of separator tokens only the type and the case-insensitive literal count.
+// It returns the path of the first difference, or an empty string if the trees are the same.
+func treeDiff(a, b reflect.Value, path string) string {
+	if a.Type() != b.Type() {
+		return fmt.Sprintf("%s: %s and %s", path, a.Type(), b.Type())
+	}
+	if tok, ok := a.Interface().(tokens.Token); ok {
+		other := b.Interface().(tokens.Token)
+		if tok.Type != other.Type || !strings.EqualFold(tok.Literal, other.Literal) {
+			return fmt.Sprintf("%s: %s and %s", path, tok, other)
+		}
+		return ""
+	}
+	switch a.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				return path + ": nil and not nil"
+			}
+			return ""
+		}
+		return treeDiff(a.Elem(), b.Elem(), path)
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if a.Field(i).Type() == reflect.TypeOf(tokens.Token{}) {
+				continue
+			}
+			if diff := treeDiff(a.Field(i), b.Field(i), path+"."+a.Type().Field(i).Name); diff != "" {
+				return diff
+			}
+		}
+		return ""
+	case reflect.Slice:
+		if a.Len() != b.Len() {
+			return fmt.Sprintf("%s: %d and %d elements", path, a.Len(), b.Len())
+		}
+		for i := 0; i < a.Len(); i++ {
+			if diff := treeDiff(a.Index(i), b.Index(i), fmt.Sprintf("%s[%d]", path, i)); diff != "" {
+				return diff
+			}
+		}
+		return ""
+	}
+	if a.Interface() != b.Interface() {
+		return fmt.Sprintf("%s: %v and %v", path, a, b)
+	}
+	return ""
+}
+
+// writeFiles creates the given files in a temporary directory and returns the directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// codes returns the error codes collected by diag
+func codes(diag *plbErrors.Collector) []string {
+	var codes []string
+	for _, err := range diag.Errors {
+		codes = append(codes, err.ErrorCode)
+	}
+	return codes
+}
+
+func TestParseFile_Includes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.pls":       "    INCLUDE common.pls\n    INC \"lib.pls\"\n    MOVEADR NAME TO PTR\n    MOVE \"X\" TO PTR\n",
+		"common.pls":     "NAME DIM 10\n",
+		"shared/lib.pls": "PTR DIM ^\n",
+	})
+
+	prog, diag := ParseFile(filepath.Join(dir, "main.pls"), Options{IncludePaths: []string{filepath.Join(dir, "shared")}})
+	if len(diag.Errors) > 0 {
+		t.Fatalf("got errors %v", diag.Errors)
+	}
+	if len(prog.Statements) != 4 {
+		t.Fatalf("got %d statements, want 4", len(prog.Statements))
+	}
+	for i, want := range []string{"common.pls", filepath.Join("shared", "lib.pls")} {
+		inc, ok := prog.Statements[i].(*ast.IncludeStatement)
+		if !ok {
+			t.Fatalf("statement %d: got %T, want *ast.IncludeStatement", i, prog.Statements[i])
+		}
+		if inc.Body == nil || len(inc.Body.Statements) != 1 || !strings.HasSuffix(inc.File, want) {
+			t.Errorf("statement %d: got %q from %q, want one statement from %s", i, inc.Path, inc.File, want)
+		}
+	}
+	// the pointer of the included file is dereferenced in the including one
+	move := prog.Statements[3].(*ast.VerbStatement)
+	if _, ok := move.Operands[1].(*ast.DerefExpression); !ok {
+		t.Errorf("got %T, want *ast.DerefExpression", move.Operands[1])
+	}
+}
+
+func TestParseFile_IncludeErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"missing.pls": "    INCLUDE nothere.pls\n    STOP\n",
+		"self.pls":    "    INCLUDE other.pls\n",
+		"other.pls":   "    INCLUDE self.pls\n",
+		"empty.pls":   "    INCLUDE\n",
+	})
+	tests := []struct {
+		file  string
+		codes []string
+	}{
+		{file: "missing.pls", codes: []string{plbErrors.ErrInclude}},
+		{file: "self.pls", codes: []string{plbErrors.ErrInclude}},
+		{file: "empty.pls", codes: []string{plbErrors.ErrOperandCount}},
+		{file: "unknown.pls", codes: []string{plbErrors.ErrCannotRead}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			_, diag := ParseFile(filepath.Join(dir, tt.file), Options{})
+			if got := strings.Join(codes(diag), ","); got != strings.Join(tt.codes, ",") {
+				t.Errorf("got %s (%v), want %s", got, diag.Errors, strings.Join(tt.codes, ","))
+			}
+		})
+	}
+}
+
+// TestParseFile_FileErrors renders the errors about a whole file, which have no line to show
+func TestParseFile_FileErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "unknown.pls")
+	_, diag := ParseFile(path, Options{Dialect: "COBOL"})
+	if len(diag.Errors) != 2 {
+		t.Fatalf("got errors %v, want an unknown dialect and an unreadable file", diag.Errors)
+	}
+	want := []string{
+		"Error E215: unknown dialect \"COBOL\", using SUNBELT\n",
+		"Error E214: " + diag.Errors[1].Message + "\nLocation: " + path + "\n",
+	}
+	for i, err := range diag.Errors {
+		if got := err.Error(); got != want[i] {
+			t.Errorf("error %d: got %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestParseString_Options(t *testing.T) {
+	t.Run("shared symbols", func(t *testing.T) {
+		env := symbols.NewEnvironment()
+		if _, diag := ParseString("PTR VAR @\nTOP\n", Options{Symbols: env}); len(diag.Errors) > 0 {
+			t.Fatalf("got errors %v", diag.Errors)
+		}
+		prog, diag := ParseString("    TYPE PTR\n    MOVE PTR TO NAME\n", Options{Symbols: env, Defines: map[string]string{"DEBUG": "1"}})
+		if len(diag.Errors) > 0 {
+			t.Fatalf("got errors %v", diag.Errors)
+		}
+		if _, ok := prog.Statements[1].(*ast.VerbStatement).Operands[0].(*ast.DerefExpression); !ok {
+			t.Errorf("pointer of the first parse is not dereferenced in the second one")
+		}
+		want := map[string]symbols.Kind{"PTR": symbols.Pointer, "TOP": symbols.Label, "DEBUG": symbols.Define}
+		for name, kind := range want {
+			sym, ok := env.Lookup(name)
+			if !ok || sym.Kind != kind {
+				t.Errorf("%s: got %v, want a %s symbol", name, sym, kind)
+			}
+		}
+	})
+
+	t.Run("dialect", func(t *testing.T) {
+		src := "PTR DIM ^\nSUB LROUTINE\n    RETURN\n"
+		if _, diag := ParseString(src, Options{Dialect: "sunbelt"}); len(diag.Errors) > 0 {
+			t.Errorf("got errors %v for Sunbelt", diag.Errors)
+		}
+		_, diag := ParseString(src, Options{Dialect: "ANSI"})
+		if got := strings.Join(codes(diag), ","); got != plbErrors.ErrDialect+","+plbErrors.ErrDialect {
+			t.Errorf("got %s for ANSI, want two %s", got, plbErrors.ErrDialect)
+		}
+		_, diag = ParseString("R RECORD\nF DIM 2\n    RECORDEND\n", Options{Dialect: "ANSI"})
+		if got := strings.Join(codes(diag), ","); got != plbErrors.ErrDialect+","+plbErrors.ErrDialect {
+			t.Errorf("got %s for a RECORD in ANSI, want two %s", got, plbErrors.ErrDialect)
+		}
+		_, diag = ParseString("", Options{Dialect: "COBOL"})
+		if got := strings.Join(codes(diag), ","); got != plbErrors.ErrDialect {
+			t.Errorf("got %s for an unknown dialect, want %s", got, plbErrors.ErrDialect)
+		}
+	})
+
+	t.Run("trace", func(t *testing.T) {
+		_, diag := ParseReader(strings.NewReader("    STOP\n"), "trace.pls", Options{Trace: true})
+		if len(diag.Traces) != 1 || diag.Traces[0].File != "trace.pls" {
+			t.Errorf("got traces %v, want one for trace.pls", diag.Traces)
+		}
+		if _, diag := ParseString("    STOP\n", Options{}); len(diag.Traces) != 0 {
+			t.Errorf("got traces %v without tracing", diag.Traces)
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package parser
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/symbols"
+	"PLB-Interpreter/tokens"
+	"fmt"
+	"strings"
+)
+
+// parsePointerDeclaration parses DIM ^, FORM ^ or VAR @, the current token has to be the verb
+func (p *Parser) parsePointerDeclaration(label *ast.Identifier) ast.Statement {
+	decl := &ast.PointerDeclaration{Token: p.curToken, Name: label, Kind: strings.ToUpper(p.curToken.Literal)}
+	marker := tokens.TokenType(tokens.CARET)
+	switch decl.Kind {
+	case "DIM", "FORM":
+	case "VAR":
+		marker = tokens.AT
+	default:
+		p.nextToken()
+		p.addError(plbErrors.ErrUnexpectedToken, fmt.Sprintf("%s cannot declare a pointer", decl.Kind))
+		return nil
+	}
+	if !p.peekTokenIs(marker) {
+		p.nextToken()
+		p.addError(plbErrors.ErrUnexpectedToken, fmt.Sprintf("unexpected %s %q, expected %s %s", p.curToken.Type, p.curToken.Literal, decl.Kind, decl.Marker()))
+		return nil
+	}
+	if !p.allowedInDialect(decl.Token, decl.Kind+" "+decl.Marker()) {
+		p.nextToken()
+		return nil
+	}
+	p.nextToken()
+	decl.EndToken = p.curToken
+	if !p.expectLineEnd() {
+		return nil
+	}
+	return decl
+}
+
+// parseTrapEvent parses the event following the IF of a TRAP, the current token has to be the IF
+func (p *Parser) parseTrapEvent() ast.Expression {
+	p.nextToken()
+	event := strings.ToUpper(p.curToken.Literal)
+	if !p.curTokenIs(tokens.IDENT) || !ast.TrapEvents[event] {
+		p.addError(plbErrors.ErrInvalidCondition, fmt.Sprintf("%q is not a trappable event", p.curToken.Literal))
+		return nil
+	}
+	return &ast.EventExpression{Token: p.curToken, Event: event}
+}
+
+// resolvePointers wraps every operand naming a pointer in a DerefExpression,
+// unless the operand position of the verb takes the pointer itself, e.g. the destination of MOVEADR.
+// Pointers are global to the program, so this runs after the whole program has been parsed.
+// Pointers of the symbol environment, e.g. declared by another file, are dereferenced as well.
+func (p *Parser) resolvePointers(prog *ast.Program) {
+	pointers := map[string]bool{}
+	collectPointers(prog.Statements, pointers)
+	if p.symbols != nil {
+		for _, sym := range p.symbols.Symbols() {
+			if sym.Kind == symbols.Pointer {
+				pointers[strings.ToUpper(sym.Name)] = true
+			}
+		}
+	}
+	if len(pointers) == 0 {
+		return
+	}
+	derefStatements(prog.Statements, pointers)
+}
+
+// collectPointers records the upper-cased names of the pointers declared in stmts
+func collectPointers(stmts []ast.Statement, pointers map[string]bool) {
+	var label *ast.Identifier // a label on a line of its own, naming the next declaration
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.LabelStatement:
+			label = s.Name
+			continue
+		case *ast.PointerDeclaration:
+			name := s.Name
+			if name == nil {
+				name = label
+			}
+			if name != nil {
+				pointers[strings.ToUpper(name.Value)] = true
+			}
+		default:
+			ast.Blocks(stmt, func(body *ast.BlockStatement) {
+				collectPointers(body.Statements, pointers)
+			})
+		}
+		label = nil
+	}
+}
+
+// derefStatements rewrites the operands and conditions of stmts, see resolvePointers
+func derefStatements(stmts []ast.Statement, pointers map[string]bool) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.VerbStatement:
+			spec, _ := LookupVerb(s.Verb)
+			for i, op := range s.Operands {
+				if !acceptsPointer(spec.OperandAt(i)) {
+					s.Operands[i] = deref(op, pointers)
+				}
+			}
+			s.Condition = deref(s.Condition, pointers)
+		case *ast.ListStatement:
+			for _, item := range s.Items {
+				item.Value = deref(item.Value, pointers)
+				for i, arg := range item.Args {
+					item.Args[i] = deref(arg, pointers)
+				}
+			}
+		case *ast.CallStatement:
+			for i, arg := range s.Args {
+				s.Args[i] = deref(arg, pointers)
+			}
+			s.Condition = deref(s.Condition, pointers)
+		case *ast.IfStatement:
+			s.Condition = deref(s.Condition, pointers)
+			for _, clause := range s.ElseIfs {
+				clause.Condition = deref(clause.Condition, pointers)
+			}
+		case *ast.LoopConditionStatement:
+			s.Condition = deref(s.Condition, pointers)
+		case *ast.ForStatement:
+			s.Variable = deref(s.Variable, pointers)
+			s.From = deref(s.From, pointers)
+			s.To = deref(s.To, pointers)
+			s.By = deref(s.By, pointers)
+		case *ast.SwitchStatement:
+			s.Subject = deref(s.Subject, pointers)
+			for _, clause := range s.Cases {
+				for i, v := range clause.Values {
+					clause.Values[i] = deref(v, pointers)
+				}
+			}
+		}
+		ast.Blocks(stmt, func(body *ast.BlockStatement) {
+			derefStatements(body.Statements, pointers)
+		})
+	}
+}
+
+// acceptsPointer returns true if the operand position takes a pointer itself
+func acceptsPointer(pos Operand) bool {
+	for _, class := range pos {
+		if class == tokens.POINTERVAR {
+			return true
+		}
+	}
+	return false
+}
+
+// deref returns expr with every identifier naming a pointer replaced by a DerefExpression
+func deref(expr ast.Expression, pointers map[string]bool) ast.Expression {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		if pointers[strings.ToUpper(e.Value)] {
+			return &ast.DerefExpression{Token: e.Token, Pointer: e}
+		}
+	case *ast.PrefixExpression:
+		e.Right = deref(e.Right, pointers)
+	case *ast.InfixExpression:
+		e.Left = deref(e.Left, pointers)
+		e.Right = deref(e.Right, pointers)
+	case *ast.GroupedExpression:
+		e.Expression = deref(e.Expression, pointers)
+	case *ast.IndexExpression:
+		e.Index = deref(e.Index, pointers)
+	}
+	return expr
+}
@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/tokens"
+	"fmt"
+	"strings"
+)
+
+// atRoutineHeader returns true if the line starting at the current token defines a ROUTINE or LROUTINE
+func (p *Parser) atRoutineHeader() bool {
+	if !p.curTokenIs(tokens.IDENT) || !p.peekTokenIs(tokens.IDENT) {
+		return false
+	}
+	verb := strings.ToUpper(p.peekToken.Literal)
+	return verb == "ROUTINE" || verb == "LROUTINE"
+}
+
+// parseRoutineStatement parses a ROUTINE or LROUTINE with its parameters and its body,
+// the current token has to be the verb. The body extends up to the next routine or the end of the file,
+// a RETURN does not end it, as a routine may have several exits with labelled code between them.
+// A routine with malformed parameters is kept with the parameters that could be parsed.
+func (p *Parser) parseRoutineStatement(label *ast.Identifier) ast.Statement {
+	stmt := &ast.RoutineStatement{Token: p.curToken, Name: label, Kind: strings.ToUpper(p.curToken.Literal)}
+	if len(p.openBlocks) > 0 {
+		p.addError(plbErrors.ErrMisplacedStatement, fmt.Sprintf("%s inside of a block", stmt.Kind))
+		return nil
+	}
+	if label == nil {
+		p.addError(plbErrors.ErrMissingLabel, fmt.Sprintf("%s needs a label naming it", stmt.Kind))
+		return nil
+	}
+
+	spec, _ := LookupVerb(stmt.Kind)
+	var params []ast.Expression
+	var seps []tokens.Token
+	valid := p.parseOperands(&params, &seps, false) && p.checkOperands(stmt.Token, spec, params)
+	for _, param := range params {
+		if ident, ok := param.(*ast.Identifier); ok {
+			stmt.Params = append(stmt.Params, ident)
+		}
+	}
+	p.finishLine(valid)
+
+	stmt.Body = &ast.BlockStatement{Token: stmt.Token, Statements: []ast.Statement{}}
+	p.nextToken()
+	for !p.curTokenIs(tokens.EOF) && !p.atRoutineHeader() {
+		s := p.parseStatement()
+		if s != nil {
+			stmt.Body.Statements = append(stmt.Body.Statements, s)
+		}
+		p.nextLine()
+	}
+	// the next routine still has to be parsed
+	p.resume = true
+	return stmt
+}
+
+// buildCallStatement turns the checked operands of a CALL into a CallStatement, the arguments have to follow USING
+func (p *Parser) buildCallStatement(verb tokens.Token, label *ast.Identifier, operands []ast.Expression, seps []tokens.Token, condition ast.Expression) ast.Statement {
+	if isBad(operands[0]) {
+		// the error was reported while parsing the operand
+		return nil
+	}
+	target, ok := operands[0].(*ast.Identifier)
+	if !ok {
+		p.addErrorAt(verb, plbErrors.ErrOperandClass, fmt.Sprintf("CALL expects a label to call, got %q", operands[0].String()))
+		return nil
+	}
+	if len(seps) > 0 && !(seps[0].Type == tokens.PREPOSITION && strings.ToUpper(seps[0].Literal) == "USING") {
+		p.addErrorAt(seps[0], plbErrors.ErrUnexpectedToken, fmt.Sprintf("expected USING in front of the arguments of CALL, got %q", seps[0].Literal))
+		return nil
+	}
+	return &ast.CallStatement{Token: verb, Label: label, Target: target, Args: operands[1:], Condition: condition}
+}
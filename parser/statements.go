@@ -0,0 +1,273 @@
+package parser
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/tokens"
+	"fmt"
+	"strings"
+)
+
+// parseStatement parses a single logical line.
+// A line starting in column 1 carries a label, a line starting with whitespace carries a verb only.
+// Blank lines and comments produce no statement.
+// If the line cannot be parsed, the error is recorded and the rest of the line is skipped into a BadStatement.
+func (p *Parser) parseStatement() ast.Statement {
+	start := p.curToken
+	var stmt ast.Statement
+	switch p.curToken.Type {
+	case tokens.WHITESPACE:
+		if p.peekTokenIs(tokens.COMMENT) || p.peekTokenIs(tokens.NULLLINE) || p.peekTokenEndsLine() {
+			// whitespace in front of a comment or an empty line
+			return nil
+		}
+		if p.peekTokenIs(tokens.IDENT) {
+			p.nextToken()
+			stmt = p.parseVerbLine(nil)
+		} else {
+			p.peekError(tokens.IDENT)
+		}
+	case tokens.IDENT:
+		label := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if p.peekTokenEndsLine() {
+			stmt = &ast.LabelStatement{Token: p.curToken, Name: label}
+		} else {
+			p.nextToken()
+			stmt = p.parseVerbLine(label)
+		}
+	case tokens.NEWLINE, tokens.NULLLINE, tokens.COMMENT:
+		return nil
+	default:
+		p.addError(plbErrors.ErrUnexpectedToken, fmt.Sprintf("unexpected %s at the start of a line", p.curToken.Type))
+	}
+
+	if stmt == nil {
+		p.traceAt(start, "skipped the line after an error")
+		if p.resume {
+			// the line was left already, nothing was skipped after start
+			return &ast.BadStatement{Token: start, EndToken: start, Text: spanText(start, tokens.Token{})}
+		}
+		p.skipLine()
+		return p.badStatement(start)
+	}
+	p.traceAt(start, fmt.Sprintf("parsed %s %q", strings.TrimPrefix(fmt.Sprintf("%T", stmt), "*ast."), stmt.TokenLiteral()))
+	return stmt
+}
+
+// parseVerbLine dispatches on the verb of a line, the current token has to be the verb.
+// Verbs opening a structured block are parsed together with their whole block.
+func (p *Parser) parseVerbLine(label *ast.Identifier) ast.Statement {
+	if !p.curTokenIs(tokens.IDENT) {
+		p.addError(plbErrors.ErrUnexpectedToken, fmt.Sprintf("expected a verb, got %s instead", p.curToken.Type))
+		return nil
+	}
+
+	verb := strings.ToUpper(p.curToken.Literal)
+	if extensions[verb] && !p.allowedInDialect(p.curToken, verb) {
+		return nil
+	}
+	switch verb {
+	case "IF":
+		return p.parseIfStatement(label)
+	case "LOOP":
+		return p.parseLoopStatement(label)
+	case "FOR":
+		return p.parseForStatement(label)
+	case "SWITCH":
+		return p.parseSwitchStatement(label)
+	case "WHILE", "UNTIL":
+		return p.parseLoopConditionStatement(label)
+	case "ROUTINE", "LROUTINE":
+		return p.parseRoutineStatement(label)
+	case "INCLUDE", "INC":
+		return p.parseIncludeStatement(label)
+	case "BREAK", "CONTINUE":
+		if p.loopDepth == 0 {
+			p.addError(plbErrors.ErrMisplacedStatement, fmt.Sprintf("%s outside of a LOOP or FOR", verb))
+			return nil
+		}
+	}
+	if opener, ok := blockClosers[verb]; ok {
+		p.addError(plbErrors.ErrUnmatchedBlock, fmt.Sprintf("%s without a matching %s", verb, opener))
+		return nil
+	}
+	return p.parseVerbStatement(label)
+}
+
+// parseVerbStatement parses a verb, its operands and an optional trailing IF condition.
+// The operands are checked against the verb's entry in Verbs, data definition verbs produce a DataDeclaration
+// and file variable definitions a FileDeclaration.
+// The current token has to be the verb.
+func (p *Parser) parseVerbStatement(label *ast.Identifier) ast.Statement {
+	verb := p.curToken
+	spec, ok := LookupVerb(verb.Literal)
+	if !ok {
+		p.addError(plbErrors.ErrUnknownVerb, fmt.Sprintf("unknown verb %q", verb.Literal))
+		return nil
+	}
+	name := strings.ToUpper(verb.Literal)
+	if name == "VAR" || p.peekTokenIs(tokens.CARET) || p.peekTokenIs(tokens.AT) {
+		return p.parsePointerDeclaration(label)
+	}
+	if _, ok := listControls[name]; ok {
+		return p.parseListStatement(label)
+	}
+
+	var operands []ast.Expression
+	var seps []tokens.Token
+	if !p.parseOperands(&operands, &seps, spec.Semicolon) {
+		return nil
+	}
+
+	var condition ast.Expression
+	if p.peekTokenIsKeyword("IF") {
+		if !spec.Conditional {
+			p.nextToken()
+			p.addError(plbErrors.ErrInvalidCondition, fmt.Sprintf("%s cannot be conditional", name))
+			return nil
+		}
+		p.nextToken()
+		start := p.peekToken
+		if name == "TRAP" {
+			condition = p.parseTrapEvent()
+		} else {
+			condition = p.parseCondition()
+		}
+		if condition == nil {
+			if p.curTokenIs(tokens.NEWLINE) || p.curTokenIs(tokens.EOF) {
+				return nil
+			}
+			condition = p.badExpression(start)
+		}
+	} else if name == "TRAP" {
+		p.addErrorAt(verb, plbErrors.ErrInvalidCondition, "TRAP needs the event to trap, e.g. TRAP label IF RANGE")
+		return nil
+	}
+
+	if !p.expectLineEnd() {
+		return nil
+	}
+	if _, ok := fileKinds[name]; ok {
+		return p.buildFileDeclaration(verb, label, operands)
+	}
+	if !p.checkOperands(verb, spec, operands) {
+		return nil
+	}
+
+	if spec.Declaration {
+		return &ast.DataDeclaration{Token: verb, Name: label, Kind: name, Operands: operands}
+	}
+	if name == "CALL" {
+		return p.buildCallStatement(verb, label, operands, seps, condition)
+	}
+	return &ast.VerbStatement{
+		Token:     verb,
+		Label:     label,
+		Verb:      name,
+		Operands:  operands,
+		Seps:      seps,
+		Condition: condition,
+	}
+}
+
+// parseOperands parses the comma or preposition separated operand list following the current token,
+// semicolon also accepts a SEMICOLON as a separator. Parsing stops at the end of the line or at a trailing IF.
+// It returns false if the line ended in the middle of an operand.
+func (p *Parser) parseOperands(operands *[]ast.Expression, seps *[]tokens.Token, semicolon bool) bool {
+	if p.peekTokenEndsLine() || p.peekTokenIsKeyword("IF") {
+		return true
+	}
+	p.nextToken()
+	op := p.parseOperand()
+	if op == nil {
+		return false
+	}
+	*operands = append(*operands, op)
+
+	for p.peekTokenIs(tokens.COMMA) || p.peekTokenIs(tokens.PREPOSITION) || (semicolon && p.peekTokenIs(tokens.SEMICOLON)) {
+		p.nextToken()
+		*seps = append(*seps, p.curToken)
+		p.nextToken()
+		op := p.parseOperand()
+		if op == nil {
+			return false
+		}
+		*operands = append(*operands, op)
+	}
+	return true
+}
+
+// parseOperand parses a single operand, the current token has to be its first token.
+// An operand that cannot be parsed becomes a BadExpression, unless the line ended within it.
+func (p *Parser) parseOperand() ast.Expression {
+	start := p.curToken
+	op := p.parseExpression(LOWEST)
+	if op != nil {
+		return op
+	}
+	if p.curTokenIs(tokens.NEWLINE) || p.curTokenIs(tokens.EOF) {
+		return nil
+	}
+	return p.badOperand(start)
+}
+
+// skipLine advances to the last token of the current line, it is used to resynchronise after an error
+func (p *Parser) skipLine() {
+	for !p.curTokenIs(tokens.NEWLINE) && !p.curTokenIs(tokens.EOF) && !p.peekTokenEndsLine() {
+		p.nextToken()
+	}
+}
+
+// nextLine moves past the statement that was just parsed.
+// If parsing stopped at the start of a line that still has to be parsed, the parser stays on that line.
+func (p *Parser) nextLine() {
+	if p.resume {
+		p.resume = false
+		return
+	}
+	p.nextToken()
+}
+
+// expectLineEnd records an error if the peek token does not end the current line
+func (p *Parser) expectLineEnd() bool {
+	if p.peekTokenEndsLine() {
+		return true
+	}
+	p.nextToken()
+	p.addError(plbErrors.ErrUnexpectedToken, fmt.Sprintf("unexpected %s %q, expected the end of the line", p.curToken.Type, p.curToken.Literal))
+	return false
+}
+
+// parseCondition parses the condition following an IF, ELSEIF, WHILE or UNTIL, the current token has to be that keyword.
+// A condition is either a flag (OVER, LESS, EQUAL, ZERO, EOS), optionally negated by NOT,
+// or an expression enclosed in parentheses.
+func (p *Parser) parseCondition() ast.Expression {
+	keyword := strings.ToUpper(p.curToken.Literal)
+	p.nextToken()
+	switch {
+	case p.curTokenIs(tokens.NOT):
+		not := p.curToken
+		p.nextToken()
+		flag := p.parseFlag()
+		if flag == nil {
+			return nil
+		}
+		return &ast.PrefixExpression{Token: not, Operator: "NOT", Right: flag}
+	case p.curTokenIs(tokens.IDENT):
+		return p.parseFlag()
+	case p.curTokenIs(tokens.LPAREN):
+		return p.parseGroupedExpression()
+	}
+	p.addError(plbErrors.ErrInvalidCondition, fmt.Sprintf("expected a condition flag or a parenthesised expression after %s, got %s instead", keyword, p.curToken.Type))
+	return nil
+}
+
+// parseFlag parses a condition flag, the current token has to be the flag
+func (p *Parser) parseFlag() ast.Expression {
+	flag := strings.ToUpper(p.curToken.Literal)
+	if !p.curTokenIs(tokens.IDENT) || !ast.ConditionFlags[flag] {
+		p.addError(plbErrors.ErrInvalidCondition, fmt.Sprintf("%q is not a condition flag", p.curToken.Literal))
+		return nil
+	}
+	return &ast.FlagExpression{Token: p.curToken, Flag: flag}
+}
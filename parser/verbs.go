@@ -0,0 +1,255 @@
+package parser
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/tokens"
+	"fmt"
+	"strings"
+)
+
+// Operand is one position in the operand list of a verb, it lists the operand classes accepted at that position.
+// A position consisting of the single class tokens.PREP stands for a separator, i.e. a COMMA or a PREPOSITION.
+type Operand []tokens.TokenType
+
+// VerbSpec describes the operand shapes of a verb
+type VerbSpec struct {
+	Operands    []Operand // operand positions in source order, separators included
+	Optional    int       // number of trailing operand positions that may be omitted
+	Variadic    bool      // the last operand position may be repeated
+	Conditional bool      // the verb accepts a trailing IF condition
+	Declaration bool      // the verb defines data, e.g. DIM or FORM
+	Semicolon   bool      // a SEMICOLON may separate operands, e.g. the key and the items of READ F,K;A,B
+}
+
+var (
+	prep       = Operand{tokens.PREP}
+	execLabel  = Operand{tokens.EXECUTIONLABEL}
+	cvar       = Operand{tokens.CVAR}
+	nvar       = Operand{tokens.NVAR}
+	anyVar     = Operand{tokens.CVAR, tokens.NVAR}
+	charValue  = Operand{tokens.CVAR, tokens.LITERAL}
+	numValue   = Operand{tokens.NVAR, tokens.NUMERICLITERAL, tokens.DNUM}
+	anyValue   = Operand{tokens.CVAR, tokens.NVAR, tokens.LITERAL, tokens.NUMERICLITERAL, tokens.DNUM}
+	fileVar    = Operand{tokens.FILE, tokens.IFILE, tokens.AFILE}
+	anyFileVar = Operand{tokens.FILE, tokens.IFILE, tokens.AFILE, tokens.PFILE}
+	recordItem = Operand{tokens.CVAR, tokens.NVAR, tokens.LISTVAR}
+	recordKey  = Operand{tokens.CVAR, tokens.NVAR, tokens.LITERAL, tokens.NUMERICLITERAL, tokens.DNUM}
+	fileAttr   = Operand{tokens.IDENT} // a flag like DUP or a length like FIXED=128
+	pointer    = Operand{tokens.POINTERVAR}
+	addressed  = Operand{tokens.CVAR, tokens.NVAR, tokens.LISTVAR} // a variable a pointer can refer to
+)
+
+// Verbs is the operand grammar of every verb the parser knows.
+// Verbs opening or closing a structured block (IF, LOOP, FOR, SWITCH and their clauses) are parsed separately,
+// as are the bodies of ROUTINE and LROUTINE.
+var Verbs = map[string]VerbSpec{
+	// data definition
+	"DIM":       {Operands: []Operand{{tokens.DNUM}}, Declaration: true},
+	"FORM":      {Operands: []Operand{{tokens.DNUM, tokens.NUMERICCONSTANT, tokens.NUMERICLITERAL}}, Declaration: true},
+	"INIT":      {Operands: []Operand{{tokens.LITERAL, tokens.NUMERICLITERAL, tokens.DOXNUM}}, Variadic: true, Declaration: true},
+	"EQU":       {Operands: []Operand{{tokens.DOXNUM}}, Declaration: true},
+	"EQUATE":    {Operands: []Operand{{tokens.DOXNUM}}, Declaration: true},
+	"LIST":      {Declaration: true},
+	"LISTEND":   {Declaration: true},
+	"RECORD":    {Declaration: true},
+	"RECORDEND": {Declaration: true},
+
+	// file variables, the attributes are decoded by buildFileDeclaration
+	"FILE":  {Operands: []Operand{fileAttr}, Optional: 1, Variadic: true, Declaration: true},
+	"IFILE": {Operands: []Operand{fileAttr}, Optional: 1, Variadic: true, Declaration: true},
+	"AFILE": {Operands: []Operand{fileAttr}, Optional: 1, Variadic: true, Declaration: true},
+	"PFILE": {Declaration: true},
+
+	// source inclusion, the file name is read by parseIncludeStatement
+	"INCLUDE": {},
+	"INC":     {},
+
+	// pointer variables, only VAR @, DIM ^ and FORM ^ are parsed by parsePointerDeclaration
+	"VAR": {Declaration: true},
+
+	// routine entry points, the body is parsed by parseRoutineStatement
+	"ROUTINE":  {Operands: []Operand{{tokens.VARLABEL}}, Optional: 1, Variadic: true},
+	"LROUTINE": {Operands: []Operand{{tokens.VARLABEL}}, Optional: 1, Variadic: true},
+
+	// character and numeric operations
+	"MOVE":     {Operands: []Operand{anyValue, prep, anyVar}},
+	"APPEND":   {Operands: []Operand{anyValue, prep, cvar}},
+	"MATCH":    {Operands: []Operand{charValue, prep, cvar}},
+	"CMATCH":   {Operands: []Operand{charValue, prep, charValue}},
+	"CLEAR":    {Operands: []Operand{cvar}, Variadic: true},
+	"RESET":    {Operands: []Operand{cvar, prep, numValue}, Optional: 1},
+	"BUMP":     {Operands: []Operand{cvar, prep, {tokens.SIGNEDDNUM}}, Optional: 1},
+	"LENSET":   {Operands: []Operand{cvar}},
+	"ENDSET":   {Operands: []Operand{cvar}},
+	"ADD":      {Operands: []Operand{numValue, prep, nvar}},
+	"SUBTRACT": {Operands: []Operand{numValue, prep, nvar}},
+	"SUB":      {Operands: []Operand{numValue, prep, nvar}},
+	"MULTIPLY": {Operands: []Operand{numValue, prep, nvar}},
+	"MULT":     {Operands: []Operand{numValue, prep, nvar}},
+	"DIVIDE":   {Operands: []Operand{numValue, prep, nvar}},
+	"DIV":      {Operands: []Operand{numValue, prep, nvar}},
+	"COMPARE":  {Operands: []Operand{numValue, prep, nvar}},
+
+	// pointers
+	"MOVEADR": {Operands: []Operand{addressed, prep, pointer}},
+	"MOVEPTR": {Operands: []Operand{pointer, prep, pointer}},
+	"TYPE":    {Operands: []Operand{{tokens.POINTERVAR, tokens.CVAR, tokens.NVAR}}},
+
+	// program flow
+	"GOTO":     {Operands: []Operand{execLabel}, Conditional: true},
+	"BRANCH":   {Operands: []Operand{numValue, prep, execLabel}, Variadic: true},
+	"CALL":     {Operands: []Operand{execLabel, prep, anyValue}, Optional: 1, Variadic: true, Conditional: true},
+	"RETURN":   {Conditional: true},
+	"TRAP":     {Operands: []Operand{execLabel}, Conditional: true},
+	"NORETURN": {},
+	"STOP":     {Conditional: true},
+	"CHAIN":    {Operands: []Operand{charValue}, Conditional: true},
+	"LOADMOD":  {Operands: []Operand{charValue}, Conditional: true},
+	"BREAK":    {Conditional: true},
+	"CONTINUE": {Conditional: true},
+	"PAUSE":    {Operands: []Operand{{tokens.NVAR, tokens.NUMERICLITERAL, tokens.DNUM}}},
+
+	// interactive input and output
+	// the items of DISPLAY, KEYIN and PRINT are parsed by parseListStatement
+	"DISPLAY": {Operands: []Operand{anyValue}, Variadic: true},
+	"KEYIN":   {Operands: []Operand{anyValue}, Variadic: true},
+	"PRINT":   {Operands: []Operand{anyValue}, Variadic: true},
+	"BEEP":    {},
+
+	// file input and output
+	"OPEN":    {Operands: []Operand{fileVar, prep, charValue}},
+	"PREPARE": {Operands: []Operand{fileVar, prep, charValue}},
+	"CLOSE":   {Operands: []Operand{anyFileVar}},
+	"READ":    {Operands: []Operand{fileVar, prep, recordKey, prep, recordItem}, Variadic: true, Semicolon: true},
+	"WRITE":   {Operands: []Operand{fileVar, prep, recordKey, prep, recordItem}, Variadic: true, Semicolon: true},
+	"UPDATE":  {Operands: []Operand{fileVar, prep, recordItem}, Variadic: true, Semicolon: true},
+	"DELETE":  {Operands: []Operand{fileVar, prep, recordKey}, Optional: 1},
+}
+
+// LookupVerb returns the operand grammar of the given verb, case-insensitive
+func LookupVerb(verb string) (VerbSpec, bool) {
+	spec, ok := Verbs[strings.ToUpper(verb)]
+	return spec, ok
+}
+
+// positions returns the operand positions of the spec without the separators
+func (vs VerbSpec) positions() []Operand {
+	var positions []Operand
+	for _, op := range vs.Operands {
+		if len(op) == 1 && op[0] == tokens.PREP {
+			continue
+		}
+		positions = append(positions, op)
+	}
+	return positions
+}
+
+// OperandAt returns the operand position of the i-th operand, separators not counted.
+// Operands beyond the last position of a variadic verb repeat the last position.
+// It returns nil for a verb without operands.
+func (vs VerbSpec) OperandAt(i int) Operand {
+	positions := vs.positions()
+	if i < len(positions) {
+		return positions[i]
+	}
+	if len(positions) == 0 {
+		return nil
+	}
+	return positions[len(positions)-1]
+}
+
+// Accepts returns true if the given expression has the syntactic form of one of the classes of the operand position.
+// Only the form is checked here, e.g. CVAR and NVAR both accept any variable reference.
+func (o Operand) Accepts(expr ast.Expression) bool {
+	for _, class := range o {
+		if classAccepts(class, expr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o Operand) String() string {
+	classes := make([]string, len(o))
+	for i, class := range o {
+		classes[i] = string(class)
+	}
+	return strings.Join(classes, " or ")
+}
+
+// classAccepts returns true if the given expression has the syntactic form of the operand class
+func classAccepts(class tokens.TokenType, expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		switch class {
+		case tokens.LITERAL, tokens.SINGLECHARLITERAL, tokens.NUMERICLITERAL, tokens.NUMERICCONSTANT:
+			return false
+		}
+		// every other class is either a label or may be given by a label pointing to the value
+		return true
+	case *ast.IndexExpression:
+		switch class {
+		case tokens.CVAR, tokens.NVAR:
+			return true
+		}
+	case *ast.StringLiteral:
+		switch class {
+		case tokens.LITERAL:
+			return true
+		case tokens.SINGLECHARLITERAL:
+			return len(e.Value) <= 1
+		}
+	case *ast.NumberLiteral:
+		switch class {
+		case tokens.NUMERICLITERAL:
+			return e.Token.Type == tokens.NUMERICLITERAL
+		case tokens.LITERAL:
+			// a quoted number is still a quoted literal
+			return e.Token.Type == tokens.NUMERICLITERAL
+		case tokens.DNUM:
+			return e.Token.Type == tokens.DNUM
+		case tokens.NUMERICCONSTANT:
+			return e.Token.Type == tokens.DNUM || e.Token.Type == tokens.NUMERICCONSTANT
+		case tokens.SIGNEDDNUM:
+			return e.Token.Type == tokens.DNUM
+		case tokens.ONUM:
+			return e.Token.Type == tokens.ONUM
+		case tokens.XNUM:
+			return e.Token.Type == tokens.XNUM
+		case tokens.DOXNUM:
+			return e.Token.Type == tokens.DNUM || e.Token.Type == tokens.ONUM || e.Token.Type == tokens.XNUM
+		}
+	case *ast.PrefixExpression:
+		// a negative decimal number
+		if class == tokens.SIGNEDDNUM && e.Operator == "-" {
+			num, ok := e.Right.(*ast.NumberLiteral)
+			return ok && num.Token.Type == tokens.DNUM
+		}
+	}
+	return false
+}
+
+// checkOperands verifies the operands of a verb against its spec, recording an error on the first mismatch
+func (p *Parser) checkOperands(verb tokens.Token, spec VerbSpec, operands []ast.Expression) bool {
+	name := strings.ToUpper(verb.Literal)
+	positions := spec.positions()
+	minOperands := len(positions) - spec.Optional
+
+	if len(operands) < minOperands {
+		p.addErrorAt(verb, plbErrors.ErrOperandCount, fmt.Sprintf("%s expects at least %d operands, got %d", name, minOperands, len(operands)))
+		return false
+	}
+	if !spec.Variadic && len(operands) > len(positions) {
+		p.addErrorAt(verb, plbErrors.ErrOperandCount, fmt.Sprintf("%s expects at most %d operands, got %d", name, len(positions), len(operands)))
+		return false
+	}
+	for i, op := range operands {
+		pos := spec.OperandAt(i)
+		if !isBad(op) && !pos.Accepts(op) {
+			p.addErrorAt(verb, plbErrors.ErrOperandClass, fmt.Sprintf("operand %d of %s must be %s, got %q", i+1, name, pos, op.String()))
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package plbErrors
+
+// Error codes reported by the lexer and the parser. The codes are stable and can be relied upon by tools and tests,
+// the messages are meant for humans and may change.
+const (
+	ErrUnexpectedToken    = "E201" // a token that cannot appear at this place of a line
+	ErrInvalidExpression  = "E202" // an operand or condition that is not a valid expression
+	ErrUnknownVerb        = "E203" // a verb that is not in the verb table
+	ErrOperandCount       = "E204" // too few or too many operands for a verb
+	ErrOperandClass       = "E205" // an operand of the wrong class, e.g. a literal as a destination
+	ErrInvalidCondition   = "E206" // a malformed IF condition, or a condition on a verb that does not allow one
+	ErrUnmatchedBlock     = "E207" // a block verb without its opener, or a block closed by the wrong verb
+	ErrUnterminatedBlock  = "E208" // a block that is never closed
+	ErrMisplacedStatement = "E209" // a statement outside of the block it belongs to, e.g. BREAK outside of a loop
+	ErrMissingLabel       = "E210" // a verb that has to be labelled, e.g. ROUTINE, without a label
+	ErrInvalidAttribute   = "E211" // an unknown, repeated or conflicting attribute of a declaration, e.g. FIXED= and VAR=
+	ErrListControl        = "E212" // a list control the verb does not allow, or with the wrong arguments
+	ErrInclude            = "E213" // an INCLUDE file that cannot be found or read, or that includes itself
+	ErrCannotRead         = "E214" // a source file that cannot be read
+	ErrDialect            = "E215" // a verb or declaration that is not part of the selected dialect, or an unknown dialect
+	ErrIllegalCharacter   = "E216" // a character outside of literals and comments that is not part of PL/B, e.g. ~
+)
+
+// Error codes reported by the checker
+const (
+	ErrArgumentCount        = "E301" // a CALL whose arguments do not match the parameters of the routine
+	ErrDuplicateDeclaration = "E302" // a data or execution label declared twice in the same scope
+	ErrUndeclared           = "E303" // a data or execution label that is referred to but never declared
+	ErrLabelSpace           = "E304" // a name declared as both a data and an execution label, or used as the other one
+	ErrNumericOperand       = "E305" // a character or other non-numeric operand where a verb needs a number, e.g. a DIM added to
+	ErrCharacterOperand     = "E306" // a numeric or other non-character operand where a verb needs characters, e.g. APPEND to a FORM
+	ErrFileOperand          = "E307" // an operand that is not a file variable, or the wrong kind of file, e.g. READ from a PFILE
+	ErrPointerOperand       = "E308" // an operand that is not a pointer, or a pointer to the wrong kind of variable
+	ErrConstantOperand      = "E309" // a constant, e.g. an EQU, where a verb needs a variable
+	ErrOperandKind          = "E310" // any other operand of the wrong kind, e.g. a file variable moved to a DIM
+	ErrConversion           = "E311" // a MOVE whose value cannot be converted to the destination, e.g. "ABC" to a FORM
+)
+
+// Error codes reported at runtime
+const (
+	ErrNullPointer  = "E401" // a pointer used before MOVEADR or MOVEPTR made it refer to a variable
+	ErrPointerType  = "E402" // a pointer made to refer to a variable of the wrong kind, e.g. a FORM through DIM ^
+	ErrUndefined    = "E403" // a variable or label that is not defined in the program
+	ErrNotSupported = "E404" // a statement the interpreter cannot execute yet
+)
+
+// Warning codes reported by the linter. A warning does not make the program invalid and can be suppressed,
+// see the lint package.
+const (
+	WarnUnreachable   = "W501" // a statement that can never run, e.g. one after an unconditional GOTO without a label
+	WarnMissingReturn = "W502" // a ROUTINE or LROUTINE that can reach its end without RETURN
+	WarnUnusedLabel   = "W503" // an execution label that no GOTO, BRANCH or CALL refers to
+	WarnUninitialised = "W504" // a variable read before any value is assigned to it on some path
+	WarnNeverRead     = "W505" // a variable assigned but never read
+	WarnUnusedData    = "W506" // a data, file or pointer declaration no statement refers to
+)
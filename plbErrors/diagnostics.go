@@ -0,0 +1,78 @@
+package plbErrors
+
+// Severity tells how serious a reported problem is
+type Severity int
+
+const (
+	SeverityError   Severity = iota // the program is invalid
+	SeverityWarning                 // the program is valid, but likely not doing what was intended
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "Warning"
+	}
+	return "Error"
+}
+
+// TraceEvent is an informational event emitted while processing a program, e.g. a parsed statement
+type TraceEvent struct {
+	Stage   string // the emitting stage, e.g. parser
+	Message string
+	File    string
+	Line    int
+	Column  int
+}
+
+// Diagnostics receives the problems and trace events found while processing PL/B source.
+// Library code never prints, it reports to a Diagnostics instead, so the embedding program decides what to show.
+type Diagnostics interface {
+	Error(err *PLBError)
+	Warning(err *PLBError)
+	Trace(event TraceEvent)
+}
+
+// Discard is a Diagnostics ignoring everything reported to it
+var Discard Diagnostics = discard{}
+
+type discard struct{}
+
+func (discard) Error(*PLBError)   {}
+func (discard) Warning(*PLBError) {}
+func (discard) Trace(TraceEvent)  {}
+
+// Collector is a Diagnostics keeping everything reported to it in order
+type Collector struct {
+	Errors   []*PLBError
+	Warnings []*PLBError
+	Traces   []TraceEvent
+}
+
+func (c *Collector) Error(err *PLBError)    { c.Errors = append(c.Errors, err) }
+func (c *Collector) Warning(err *PLBError)  { c.Warnings = append(c.Warnings, err) }
+func (c *Collector) Trace(event TraceEvent) { c.Traces = append(c.Traces, event) }
+
+// Filter returns a Diagnostics forwarding only the errors and warnings for which keep returns true to d.
+// Trace events are always forwarded.
+func Filter(d Diagnostics, keep func(err *PLBError) bool) Diagnostics {
+	return filter{next: d, keep: keep}
+}
+
+type filter struct {
+	next Diagnostics
+	keep func(err *PLBError) bool
+}
+
+func (f filter) Error(err *PLBError) {
+	if f.keep(err) {
+		f.next.Error(err)
+	}
+}
+
+func (f filter) Warning(err *PLBError) {
+	if f.keep(err) {
+		f.next.Warning(err)
+	}
+}
+
+func (f filter) Trace(event TraceEvent) { f.next.Trace(event) }
@@ -3,19 +3,21 @@ package plbErrors
 import (
 	"bytes"
 	"fmt"
+	"strings"
 )
 
 // PLBError is a custom error type for PLB errors
 type PLBError struct {
-	ErrorCode  string // Error code, e.g. E501
-	Message    string // Error message, e.g. Invalid token type
-	File       string // Filename where the error occurred
-	LineNumber int    // LineNumber where the error occurred
-	Column     int    // Column (number of character in the line) where the error occurred
-	LineText   string // Literal line contents where the error occurred to display to the user
+	ErrorCode  string   // Error code, e.g. E501
+	Message    string   // Error message, e.g. Invalid token type
+	File       string   // Filename where the error occurred
+	LineNumber int      // LineNumber where the error occurred
+	Column     int      // Column (number of character in the line) where the error occurred
+	LineText   string   // Literal line contents where the error occurred to display to the user
+	Severity   Severity // Severity of the problem, errors unless created by NewPLBWarning
 }
 
-// NewPLBError creates a new PLBError
+// NewPLBError creates a new PLBError, the line end of lineText is removed
 func NewPLBError(errorCode string, message string, file string, line int, column int, lineText string) *PLBError {
 	return &PLBError{
 		ErrorCode:  errorCode,
@@ -23,14 +25,35 @@ func NewPLBError(errorCode string, message string, file string, line int, column
 		File:       file,
 		LineNumber: line,
 		Column:     column,
-		LineText:   lineText,
+		LineText:   strings.TrimRight(lineText, "\r\n"),
 	}
 }
 
-// Error returns a string representation of the plbErrors
+// NewFileError creates a PLBError about a whole file rather than a place in it, e.g. a file that cannot be read.
+// It has no line and column, file is empty for an error about no file at all, e.g. an unknown dialect.
+func NewFileError(errorCode string, message string, file string) *PLBError {
+	return NewPLBError(errorCode, message, file, 0, 0, "")
+}
+
+// NewPLBWarning creates a new PLBError with warning severity
+func NewPLBWarning(errorCode string, message string, file string, line int, column int, lineText string) *PLBError {
+	err := NewPLBError(errorCode, message, file, line, column, lineText)
+	err.Severity = SeverityWarning
+	return err
+}
+
+// Error returns a string representation of the plbErrors.
+// An error about a whole file, e.g. one that cannot be read, has no line and is shown without the source line,
+// an error without a file, e.g. an unknown dialect, without a location.
 func (e *PLBError) Error() string {
 	var buffer bytes.Buffer
-	buffer.WriteString(fmt.Sprintf("Error %s: %s\n", e.ErrorCode, e.Message))
+	buffer.WriteString(fmt.Sprintf("%s %s: %s\n", e.Severity, e.ErrorCode, e.Message))
+	if e.LineNumber < 1 || e.Column < 1 {
+		if e.File != "" {
+			buffer.WriteString(fmt.Sprintf("Location: %s\n", e.File))
+		}
+		return buffer.String()
+	}
 	buffer.WriteString(fmt.Sprintf("Location: %s %d:%d\n", e.File, e.LineNumber, e.Column))
 	buffer.WriteString(fmt.Sprintf("%s\n", e.LineText))
 	buffer.WriteString(fmt.Sprintf("%s^\n", bytes.Repeat([]byte(" "), e.Column-1)))
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/parser"
+	"PLB-Interpreter/record"
+	"PLB-Interpreter/resolver"
+	"PLB-Interpreter/symbols"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// recordCommand prints the layout of the LISTs and RECORDs of a file, or only of those named after the file,
+// as a table, as JSON with -json or as a COBOL copybook with -copybook.
+// With -file the length of the named records is checked against the record length of a file variable.
+func recordCommand(args []string) int {
+	flags := flag.NewFlagSet("record", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the layouts as JSON instead of a table")
+	copybook := flags.Bool("copybook", false, "print the layouts as a COBOL copybook instead of a table")
+	fileName := flags.String("file", "", "check the length of the named records against the file variable with this name")
+	dialect := flags.String("dialect", "", "PL/B dialect, SUNBELT or ANSI")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *fileName != "" && flags.NArg() < 2 {
+		fmt.Fprintln(os.Stderr, "-file needs the names of the records to check after the source file")
+		return 2
+	}
+
+	prog, diag := parser.ParseFile(sourcePath(flags.Args()), parser.Options{Dialect: *dialect})
+	for _, err := range diag.Errors {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	status := 0
+	if len(diag.Errors) > 0 {
+		status = 1
+	}
+
+	records := record.Layouts(prog)
+	if flags.NArg() > 1 {
+		var named []*record.Field
+		for _, name := range flags.Args()[1:] {
+			r, ok := record.Find(records, name)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "no LIST or RECORD %s\n", name)
+				return 1
+			}
+			named = append(named, r)
+		}
+		records = named
+	}
+
+	write := record.WriteText
+	switch {
+	case *asJSON:
+		write = record.WriteJSON
+	case *copybook:
+		write = record.WriteCopybook
+	}
+	if err := write(os.Stdout, records); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *fileName != "" {
+		table := resolver.New().Resolve(prog)
+		sym, ok := table.Global.Lookup(symbols.DataSpace, *fileName)
+		file, isFile := (*ast.FileDeclaration)(nil), false
+		if ok {
+			file, isFile = sym.Node.(*ast.FileDeclaration)
+		}
+		if !isFile {
+			fmt.Fprintf(os.Stderr, "no file variable %s\n", *fileName)
+			return 1
+		}
+		for _, r := range records {
+			if err := record.Check(r, file); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				status = 1
+			}
+		}
+	}
+	return status
+}
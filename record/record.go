@@ -0,0 +1,153 @@
+// Package record computes the layout of the records defined by LIST and RECORD declarations: the offset, length
+// and type of every field as it is read and written, so the length of a record can be checked against the
+// FIXED= or VAR= length of a file. A DIM or INIT field takes its characters without the header it has in memory,
+// a FORM field its digits and decimal point, see storage.Width. A LIST nested in another one is a group of fields.
+package record
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/storage"
+	"fmt"
+	"strings"
+)
+
+// Field is a field of a record, or a record itself
+type Field struct {
+	Name   string               // the name of the field, empty if it has none
+	Decl   *ast.DataDeclaration // the declaration of the field, a LIST or RECORD for a group
+	Offset int                  // the first byte of the field, counted from the start of the record
+	Length int                  // the bytes of the field, the sum of its fields for a group
+	Fields []*Field             // the fields of a group in order
+}
+
+// IsGroup returns true for a LIST or RECORD
+func (f *Field) IsGroup() bool {
+	return opens(f.Decl)
+}
+
+// Type returns the type of the field: character for a DIM or INIT, numeric for a FORM, group for a LIST or RECORD
+func (f *Field) Type() string {
+	switch f.Decl.Kind {
+	case "FORM":
+		return "numeric"
+	case "DIM", "INIT":
+		return "character"
+	}
+	return "group"
+}
+
+// Layouts returns the records of prog in source order, including those nested in another one.
+// Declarations in a record without storage of their own, e.g. EQU, are not fields.
+func Layouts(prog *ast.Program) []*Field {
+	stmts, names := declarations(prog)
+	var records []*Field
+	for i, stmt := range stmts {
+		if opens(stmt) {
+			record, _ := group(stmts, names, i, 0)
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// Find returns the record with the given name, case-insensitive
+func Find(records []*Field, name string) (*Field, bool) {
+	for _, record := range records {
+		if strings.EqualFold(record.Name, name) {
+			return record, true
+		}
+	}
+	return nil, false
+}
+
+// Check returns an error if the record does not fit the records of file: it has to be as long as a FIXED= length,
+// and not longer than a VAR= length. A file without a record length fits any record.
+func Check(record *Field, file *ast.FileDeclaration) error {
+	if file.RecordLength == 0 {
+		return nil
+	}
+	attr := "VAR"
+	if file.Fixed {
+		attr = "FIXED"
+	}
+	if record.Length > file.RecordLength || file.Fixed && record.Length != file.RecordLength {
+		return fmt.Errorf("%s: %s %s is %d bytes long, %s %s at %s has %s=%d", record.Decl.Pos(), record.Decl.Kind, record.Name,
+			record.Length, file.Kind, fileName(file), file.Pos(), attr, file.RecordLength)
+	}
+	return nil
+}
+
+func fileName(file *ast.FileDeclaration) string {
+	if file.Name == nil {
+		return ""
+	}
+	return file.Name.Value
+}
+
+// declarations returns the declarations of prog in source order, with the names given to them by a label
+// on a line of its own
+func declarations(prog *ast.Program) ([]ast.Statement, map[ast.Statement]string) {
+	var stmts []ast.Statement
+	names := map[ast.Statement]string{}
+	var label *ast.Identifier
+	ast.Inspect(prog, func(node ast.Node) bool {
+		stmt, ok := node.(ast.Statement)
+		if !ok {
+			return true
+		}
+		switch s := stmt.(type) {
+		case *ast.LabelStatement:
+			label = s.Name
+			return false
+		case *ast.DataDeclaration:
+			stmts = append(stmts, s)
+			if s.Name != nil {
+				names[s] = s.Name.Value
+			} else if label != nil {
+				names[s] = label.Value
+			}
+		}
+		label = nil
+		return true
+	})
+	return stmts, names
+}
+
+// group returns the record opened by stmts[i] at offset, and the index of the statement closing it,
+// len(stmts) if it is never closed
+func group(stmts []ast.Statement, names map[ast.Statement]string, i, offset int) (*Field, int) {
+	decl := stmts[i].(*ast.DataDeclaration)
+	f := &Field{Name: names[decl], Decl: decl, Offset: offset}
+	j := i + 1
+	for ; j < len(stmts); j++ {
+		member := stmts[j].(*ast.DataDeclaration)
+		if closes(member) {
+			break
+		}
+		if opens(member) {
+			var sub *Field
+			sub, j = group(stmts, names, j, offset)
+			f.Fields = append(f.Fields, sub)
+			offset += sub.Length
+			continue
+		}
+		if width, ok := storage.Width(member); ok {
+			f.Fields = append(f.Fields, &Field{Name: names[member], Decl: member, Offset: offset, Length: width})
+			offset += width
+		}
+	}
+	f.Length = offset - f.Offset
+	return f, j
+}
+
+// opens returns true for a LIST or RECORD declaration
+func opens(stmt ast.Statement) bool {
+	decl, ok := stmt.(*ast.DataDeclaration)
+	return ok && (decl.Kind == "LIST" || decl.Kind == "RECORD")
+}
+
+// closes returns true for a LISTEND or RECORDEND declaration
+func closes(stmt ast.Statement) bool {
+	decl, ok := stmt.(*ast.DataDeclaration)
+	return ok && (decl.Kind == "LISTEND" || decl.Kind == "RECORDEND")
+}
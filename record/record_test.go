@@ -0,0 +1,191 @@
+package record
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/parser"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// layouts parses input and returns its records, parser errors fail the test
+func layouts(t *testing.T, input string) ([]*Field, *ast.Program) {
+	t.Helper()
+	prog, diag := parser.ParseString(input, parser.Options{})
+	if len(diag.Errors) > 0 {
+		t.Fatalf("parser errors: %v", diag.Errors)
+	}
+	return Layouts(prog), prog
+}
+
+// describe returns the fields of a record as NAME@OFFSET+LENGTH, with the fields of a group in parentheses
+func describe(fields []*Field) string {
+	var parts []string
+	for _, f := range fields {
+		part := fmt.Sprintf("%s@%d+%d", f.Name, f.Offset, f.Length)
+		if f.IsGroup() {
+			part += "(" + describe(f.Fields) + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+const customer = `CUSTFILE IFILE   KEYLEN=5,FIXED=60
+CUST     LIST
+ID       FORM    5
+NAME     DIM     30
+ADDR     RECORD
+STREET   DIM     10
+ZIP      FORM    "00000"
+         RECORDEND
+BAL      FORM    7.2
+MAX      EQU     10
+         LISTEND
+`
+
+func TestLayouts(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string // the records, NAME+LENGTH: fields
+	}{
+		{"L LIST\nA DIM 10\nN FORM 3.2\nI INIT \"AB\",\"C\"\n    LISTEND\n", []string{"L+19: A@0+10 N@10+6 I@16+3"}},
+		{"R RECORD\nP DIM ^\nF FILE\nX EQU 2\nA DIM 2\n    RECORDEND\nB DIM 5\n", []string{"R+2: A@0+2"}},
+		{"L\n    LIST\nA\n    DIM 4\n    DIM 1\n    LISTEND\n", []string{"L+5: A@0+4 @4+1"}},
+		{"L LIST\nA DIM 3\n", []string{"L+3: A@0+3"}},
+		{"L LIST\nN FORM \"-10\"\nM FORM \"-1.5\"\n    LISTEND\n", []string{"L+7: N@0+3 M@3+4"}},
+		{customer, []string{
+			"CUST+60: ID@0+5 NAME@5+30 ADDR@35+15(STREET@35+10 ZIP@45+5) BAL@50+10",
+			"ADDR+15: STREET@0+10 ZIP@10+5",
+		}},
+	}
+	for _, tt := range tests {
+		records, _ := layouts(t, tt.input)
+		var got []string
+		for _, r := range records {
+			got = append(got, fmt.Sprintf("%s+%d: %s", r.Name, r.Length, describe(r.Fields)))
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%q: got\n%s\nwant\n%s", tt.input, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		file string
+		want string // part of the error, empty if the record fits
+	}{
+		{"F IFILE FIXED=60", ""},
+		{"F IFILE FIXED=80", "LIST CUST is 60 bytes long, IFILE F at"},
+		{"F FILE VAR=80", ""},
+		{"F FILE VAR=50", "has VAR=50"},
+		{"F IFILE FIXED=80", "has FIXED=80"},
+		{"F FILE", ""},
+	}
+	for _, tt := range tests {
+		records, prog := layouts(t, tt.file+"\n"+strings.SplitN(customer, "\n", 2)[1])
+		record, ok := Find(records, "cust")
+		if !ok {
+			t.Fatal("CUST not found")
+		}
+		err := Check(record, prog.Statements[0].(*ast.FileDeclaration))
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: got %v, want no error", tt.file, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: got %v, want an error with %q", tt.file, err, tt.want)
+		}
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	records, _ := layouts(t, customer)
+	var out bytes.Buffer
+	if err := WriteText(&out, records[:1]); err != nil {
+		t.Fatal(err)
+	}
+	want := `LIST CUST, 60 bytes
+OFFSET LENGTH  TYPE        FIELD
+     0      5  FORM 5      ID
+     5     30  DIM 30      NAME
+    35     15  RECORD      ADDR
+    35     10  DIM 10        STREET
+    45      5  FORM 5        ZIP
+    50     10  FORM 7.2    BAL
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestWriteCopybook(t *testing.T) {
+	records, _ := layouts(t, customer+"my_rec LIST\n    DIM 2\nX FORM 1\nY FORM 0.3\n    LISTEND\n")
+	var out bytes.Buffer
+	if err := WriteCopybook(&out, []*Field{records[0], records[2]}); err != nil {
+		t.Fatal(err)
+	}
+	want := `      * LIST CUST, 60 BYTES
+       01  CUST.
+           05  ID                       PIC -(4)9.
+           05  NAME                     PIC X(30).
+           05  ADDR.
+               10  STREET               PIC X(10).
+               10  ZIP                  PIC -(4)9.
+           05  BAL                      PIC -(6)9.9(2).
+      * LIST MY-REC, 7 BYTES
+       01  MY-REC.
+           05  FILLER                   PIC X(2).
+           05  X                        PIC 9.
+           05  Y                        PIC .9(3).
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	records, _ := layouts(t, "R RECORD\nA DIM 2\nN FORM 3.1\n    RECORDEND\n")
+	var out bytes.Buffer
+	if err := WriteJSON(&out, records); err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "version": 1,
+  "records": [
+    {
+      "name": "R",
+      "verb": "RECORD",
+      "declared": {
+        "file": "",
+        "line": 1,
+        "col": 1
+      },
+      "length": 7,
+      "fields": [
+        {
+          "name": "A",
+          "verb": "DIM",
+          "type": "character",
+          "offset": 0,
+          "length": 2
+        },
+        {
+          "name": "N",
+          "verb": "FORM",
+          "type": "numeric",
+          "offset": 2,
+          "length": 5,
+          "digits": 3,
+          "decimals": 1
+        }
+      ]
+    }
+  ]
+}
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
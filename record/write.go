@@ -0,0 +1,183 @@
+package record
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/storage"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JSONVersion is the version of the JSON encoding written by WriteJSON
+const JSONVersion = 1
+
+// WriteText writes the layout of the records as a table for each, giving the offset, length and declaration
+// of every field. The fields of a group are indented below it.
+func WriteText(w io.Writer, records []*Field) error {
+	out := bufio.NewWriter(w)
+	for i, record := range records {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "%s %s, %d bytes\n", record.Decl.Kind, displayName(record.Name), record.Length)
+		fmt.Fprintf(out, "%6s %6s  %-10s  %s\n", "OFFSET", "LENGTH", "TYPE", "FIELD")
+		var rows func(fields []*Field, depth int)
+		rows = func(fields []*Field, depth int) {
+			for _, f := range fields {
+				fmt.Fprintln(out, strings.TrimRight(fmt.Sprintf("%6d %6d  %-10s  %s%s", f.Offset, f.Length, declared(f),
+					strings.Repeat("  ", depth), displayName(f.Name)), " "))
+				rows(f.Fields, depth+1)
+			}
+		}
+		rows(record.Fields, 0)
+	}
+	return out.Flush()
+}
+
+// displayName returns the name of a field, or a dash if it has none
+func displayName(name string) string {
+	if name == "" {
+		return "-"
+	}
+	return name
+}
+
+// declared returns the declaring verb of a field with its size, e.g. DIM 20 or FORM 5.2
+func declared(f *Field) string {
+	switch f.Decl.Kind {
+	case "FORM":
+		digits, decimals, _ := storage.Digits(f.Decl)
+		if decimals > 0 {
+			return fmt.Sprintf("FORM %d.%d", digits, decimals)
+		}
+		return fmt.Sprintf("FORM %d", digits)
+	case "DIM", "INIT":
+		return fmt.Sprintf("%s %d", f.Decl.Kind, f.Length)
+	}
+	return f.Decl.Kind
+}
+
+// The JSON encoding of the layouts is a document
+//
+//	{"version": 1, "records": [{"name": "CUSTOMER", "verb": "LIST", "declared": {...}, "length": 52,
+//	 "fields": [{"name": "ID", "verb": "FORM", "type": "numeric", "offset": 0, "length": 5, "digits": 5, "decimals": 0}]}]}
+//
+// A group has the type group and its fields in "fields", digits and decimals are given for numeric fields only.
+type jsonDocument struct {
+	Version int          `json:"version"`
+	Records []*jsonField `json:"records"`
+}
+
+type jsonField struct {
+	Name     string        `json:"name"`
+	Verb     string        `json:"verb"`
+	Type     string        `json:"type,omitempty"`
+	Declared *ast.Position `json:"declared,omitempty"`
+	Offset   *int          `json:"offset,omitempty"`
+	Length   int           `json:"length"`
+	Digits   *int          `json:"digits,omitempty"`
+	Decimals *int          `json:"decimals,omitempty"`
+	Fields   []*jsonField  `json:"fields,omitempty"`
+}
+
+// WriteJSON writes the layouts of the records as an indented JSON document
+func WriteJSON(w io.Writer, records []*Field) error {
+	doc := jsonDocument{Version: JSONVersion, Records: []*jsonField{}}
+	for _, record := range records {
+		pos := record.Decl.Pos()
+		doc.Records = append(doc.Records, &jsonField{Name: record.Name, Verb: record.Decl.Kind, Declared: &pos,
+			Length: record.Length, Fields: encodeFields(record.Fields)})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func encodeFields(fields []*Field) []*jsonField {
+	encoded := []*jsonField{}
+	for _, f := range fields {
+		offset := f.Offset
+		field := &jsonField{Name: f.Name, Verb: f.Decl.Kind, Type: f.Type(), Offset: &offset, Length: f.Length}
+		if f.IsGroup() {
+			field.Fields = encodeFields(f.Fields)
+		}
+		if digits, decimals, ok := storage.Digits(f.Decl); ok {
+			field.Digits, field.Decimals = &digits, &decimals
+		}
+		encoded = append(encoded, field)
+	}
+	return encoded
+}
+
+// WriteCopybook writes the records as COBOL data descriptions in fixed format, a level 01 item for every record.
+// A DIM or INIT field is PIC X, a FORM field numeric edited with a floating minus sign, so that every item is
+// as long as the field, e.g. FORM 5.2 is PIC -(4)9.9(2). Names are upper-cased with underscores turned into
+// hyphens, fields without a name are FILLER.
+func WriteCopybook(w io.Writer, records []*Field) error {
+	out := bufio.NewWriter(w)
+	for _, record := range records {
+		fmt.Fprintf(out, "      * %s %s, %d BYTES\n", record.Decl.Kind, cobolName(record.Name), record.Length)
+		fmt.Fprintf(out, "       01  %s.\n", cobolName(record.Name))
+		var items func(fields []*Field, level int)
+		items = func(fields []*Field, level int) {
+			for _, f := range fields {
+				item := fmt.Sprintf("%s%02d  %s", strings.Repeat(" ", 11+4*(level/5-1)), level, cobolName(f.Name))
+				if f.IsGroup() {
+					fmt.Fprintln(out, item+".")
+					items(f.Fields, level+5)
+					continue
+				}
+				fmt.Fprintf(out, "%-39s PIC %s.\n", item, picture(f))
+			}
+		}
+		items(record.Fields, 5)
+	}
+	return out.Flush()
+}
+
+// cobolName returns a name as a COBOL data name, FILLER if it is empty
+func cobolName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		}
+		return '-'
+	}, name)
+	name = strings.Trim(name, "-")
+	if name == "" {
+		return "FILLER"
+	}
+	return name
+}
+
+// picture returns the PIC clause of a field that is not a group
+func picture(f *Field) string {
+	digits, decimals, ok := storage.Digits(f.Decl)
+	if !ok {
+		return repeat('X', f.Length)
+	}
+	var pic string
+	switch {
+	case digits == 1:
+		pic = "9"
+	case digits > 1:
+		pic = repeat('-', digits-1) + "9"
+	}
+	if decimals > 0 {
+		pic += "." + repeat('9', decimals)
+	}
+	return pic
+}
+
+// repeat returns a picture symbol repeated n times, e.g. X(20)
+func repeat(symbol rune, n int) string {
+	if n == 1 {
+		return string(symbol)
+	}
+	return fmt.Sprintf("%c(%d)", symbol, n)
+}
@@ -0,0 +1,42 @@
+package resolver
+
+import (
+	"PLB-Interpreter/symbols"
+)
+
+// verbAccesses gives the use of the data operands of a verb in order, separators not counted:
+// r for read, w for write, u for update and a for address. The last use repeats for further operands.
+// The operands of a verb not listed are read.
+var verbAccesses = map[string]string{
+	"MOVE":     "rw",
+	"APPEND":   "ru",
+	"CLEAR":    "w",
+	"RESET":    "ur",
+	"BUMP":     "ur",
+	"LENSET":   "u",
+	"ENDSET":   "u",
+	"ADD":      "ru",
+	"SUBTRACT": "ru",
+	"SUB":      "ru",
+	"MULTIPLY": "ru",
+	"MULT":     "ru",
+	"DIVIDE":   "ru",
+	"DIV":      "ru",
+	"MOVEADR":  "aw",
+	"MOVEPTR":  "rw",
+	"READ":     "rrw",
+}
+
+var accessCodes = map[byte]symbols.Access{'r': symbols.Read, 'w': symbols.Write, 'u': symbols.Update, 'a': symbols.Address}
+
+// accessAt returns how the i-th operand of a verb is used
+func accessAt(verb string, i int) symbols.Access {
+	accesses, ok := verbAccesses[verb]
+	if !ok {
+		return symbols.Read
+	}
+	if i >= len(accesses) {
+		i = len(accesses) - 1
+	}
+	return accessCodes[accesses[i]]
+}
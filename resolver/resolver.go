@@ -0,0 +1,302 @@
+// Package resolver builds the symbol table of a parsed program and binds every identifier to its declaration.
+//
+// PL/B keeps data labels and execution labels in separate spaces. Data is declared in the global scope,
+// in the scope of a LIST for its members, which are visible in the enclosing scope as well, or in the local scope of an LROUTINE.
+// Execution labels, including the names of routines, are always global.
+package resolver
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/parser"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/symbols"
+	"PLB-Interpreter/tokens"
+	"fmt"
+)
+
+// Resolver declares the labels of a program in scopes and binds the identifiers referring to them.
+// It reports duplicate declarations, names declared in both label spaces and references to undeclared names.
+type Resolver struct {
+	errors []error
+	diag   plbErrors.Diagnostics
+	env    *symbols.Environment
+	table  *symbols.Table
+}
+
+// Option configures a Resolver, see New
+type Option func(*Resolver)
+
+// WithDiagnostics reports every error found by the resolver to d, in addition to Errors
+func WithDiagnostics(d plbErrors.Diagnostics) Option {
+	return func(r *Resolver) {
+		r.diag = d
+	}
+}
+
+// WithEnvironment looks up names not declared in the program in env, e.g. defines given on the command line
+func WithEnvironment(env *symbols.Environment) Option {
+	return func(r *Resolver) {
+		r.env = env
+	}
+}
+
+// New creates a resolver, the program is resolved by Resolve
+func New(opts ...Option) *Resolver {
+	r := &Resolver{diag: plbErrors.Discard}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Errors returns true if there are any errors indicated in the resolver
+// If the boolean is true, the slice of errors will be non-empty
+// If the boolean is false, the slice of errors will be empty
+func (r *Resolver) Errors() (bool, []error) {
+	return len(r.errors) > 0, r.errors
+}
+
+func (r *Resolver) addError(tok tokens.Token, code, msg string) {
+	newErr := plbErrors.NewPLBError(code, msg, tok.FileName, tok.Line, tok.Col, tok.LineTxt)
+	r.errors = append(r.errors, newErr)
+	r.diag.Error(newErr)
+}
+
+// Resolve builds the symbol table of prog. Every name is declared before any reference is bound,
+// since a label may be referred to before it is declared, e.g. by a GOTO.
+func (r *Resolver) Resolve(prog *ast.Program) *symbols.Table {
+	r.table = symbols.NewTable()
+	r.declareStatements(prog.Statements, r.table.Global)
+	r.bindStatements(prog.Statements, r.table.Global)
+	return r.table
+}
+
+// declareStatements declares the labels of stmts, data in scope and execution labels in the global scope
+func (r *Resolver) declareStatements(stmts []ast.Statement, scope *symbols.Scope) {
+	var label *ast.LabelStatement // a label on a line of its own, naming the next statement
+	var list *symbols.Symbol      // the LIST whose members are being declared
+	for _, stmt := range stmts {
+		if l, ok := stmt.(*ast.LabelStatement); ok {
+			if label != nil {
+				r.declare(r.table.Global, label.Name, symbols.Label, label, nil)
+			}
+			label = l
+			continue
+		}
+		var name *ast.Identifier // the label on the line of its own, if it names a declaration
+		if label != nil {
+			name = label.Name
+		}
+
+		switch s := stmt.(type) {
+		case *ast.DataDeclaration:
+			switch s.Kind {
+			case "LIST", "RECORD":
+				list = r.declare(scope, ast.DeclaredName(s.Name, name), symbols.List, s, nil)
+				if list != nil {
+					r.table.Scopes[s] = symbols.NewScope(symbols.ListScope, scope, s)
+				}
+			case "LISTEND", "RECORDEND":
+				list = nil
+			default:
+				r.declare(scope, ast.DeclaredName(s.Name, name), symbols.Data, s, list)
+			}
+		case *ast.FileDeclaration:
+			r.declare(scope, ast.DeclaredName(s.Name, name), symbols.File, s, list)
+		case *ast.PointerDeclaration:
+			r.declare(scope, ast.DeclaredName(s.Name, name), symbols.Pointer, s, list)
+		case *ast.RoutineStatement:
+			if label != nil {
+				r.declare(r.table.Global, label.Name, symbols.Label, label, nil)
+			}
+			r.declare(r.table.Global, s.Name, symbols.Routine, s, nil)
+			bodyScope := scope
+			if s.IsLocal() {
+				bodyScope = symbols.NewScope(symbols.LocalScope, scope, s)
+				r.table.Scopes[s] = bodyScope
+			}
+			r.declareStatements(s.Body.Statements, bodyScope)
+		default:
+			if label != nil {
+				r.declare(r.table.Global, label.Name, symbols.Label, label, nil)
+			}
+			if l := ast.Label(stmt); l != nil {
+				r.declare(r.table.Global, l, symbols.Label, stmt, nil)
+			}
+			ast.Blocks(stmt, func(block *ast.BlockStatement) {
+				r.declareStatements(block.Statements, scope)
+			})
+		}
+		label = nil
+	}
+	if label != nil {
+		r.declare(r.table.Global, label.Name, symbols.Label, label, nil)
+	}
+}
+
+// declare binds ident to a new symbol and adds it to scope, and to the scope of list for a member of a LIST.
+// It returns the symbol, or nil if there is no name to declare.
+func (r *Resolver) declare(scope *symbols.Scope, ident *ast.Identifier, kind symbols.Kind, node ast.Node, list *symbols.Symbol) *symbols.Symbol {
+	if ident == nil {
+		return nil
+	}
+	sym := &symbols.Symbol{Name: ident.Value, Kind: kind, Token: ident.Token, Node: node, List: list}
+	r.table.Defs[ident] = sym
+	if list != nil {
+		r.table.Scopes[list.Node].Insert(sym)
+	}
+	if prev, ok := scope.Insert(sym); !ok {
+		r.addError(ident.Token, plbErrors.ErrDuplicateDeclaration, fmt.Sprintf("%s is already declared at %s",
+			ident.Value, at(prev.Token)))
+		return sym
+	}
+	if other, ok := scope.LookupLocal(otherSpace(kind.Space()), ident.Value); ok {
+		r.addError(ident.Token, plbErrors.ErrLabelSpace, fmt.Sprintf("%s is declared as %s here and as %s at %s",
+			ident.Value, withArticle(kind.Space()), withArticle(other.Kind.Space()), at(other.Token)))
+	}
+	return sym
+}
+
+// bindStatements binds the identifiers referring to labels in stmts, data is looked up starting in scope
+func (r *Resolver) bindStatements(stmts []ast.Statement, scope *symbols.Scope) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.DataDeclaration:
+			r.bindExpressions(s.Operands, symbols.Read, scope)
+		case *ast.RoutineStatement:
+			bodyScope := scope
+			if s.IsLocal() {
+				bodyScope = r.table.Scopes[s]
+			}
+			// the parameters are assigned by the caller
+			for _, param := range s.Params {
+				r.bind(param, symbols.DataSpace, symbols.Write, bodyScope)
+			}
+			r.bindStatements(s.Body.Statements, bodyScope)
+			continue
+		case *ast.VerbStatement:
+			spec, known := parser.LookupVerb(s.Verb)
+			for i, op := range s.Operands {
+				if ident, ok := op.(*ast.Identifier); ok && known && isExecutionLabel(spec.OperandAt(i)) {
+					r.bind(ident, symbols.ExecutionSpace, symbols.Branch, scope)
+					continue
+				}
+				r.bindExpression(op, accessAt(s.Verb, i), scope)
+			}
+			r.bindExpression(s.Condition, symbols.Read, scope)
+		case *ast.CallStatement:
+			if s.Target != nil {
+				r.bind(s.Target, symbols.ExecutionSpace, symbols.Branch, scope)
+			}
+			r.bindExpressions(s.Args, symbols.Address, scope)
+			r.bindExpression(s.Condition, symbols.Read, scope)
+		case *ast.ListStatement:
+			for _, item := range s.Items {
+				if item == nil {
+					continue
+				}
+				access := symbols.Read
+				if s.Verb == "KEYIN" && item.Kind == ast.VariableItem {
+					access = symbols.Write
+				}
+				r.bindExpression(item.Value, access, scope)
+				r.bindExpressions(item.Args, symbols.Read, scope)
+			}
+		case *ast.IfStatement:
+			r.bindExpression(s.Condition, symbols.Read, scope)
+			for _, clause := range s.ElseIfs {
+				r.bindExpression(clause.Condition, symbols.Read, scope)
+			}
+		case *ast.LoopConditionStatement:
+			r.bindExpression(s.Condition, symbols.Read, scope)
+		case *ast.ForStatement:
+			r.bindExpression(s.Variable, symbols.Write, scope)
+			r.bindExpressions([]ast.Expression{s.From, s.To, s.By}, symbols.Read, scope)
+		case *ast.SwitchStatement:
+			r.bindExpression(s.Subject, symbols.Read, scope)
+			for _, clause := range s.Cases {
+				r.bindExpressions(clause.Values, symbols.Read, scope)
+			}
+		}
+		ast.Blocks(stmt, func(block *ast.BlockStatement) {
+			r.bindStatements(block.Statements, scope)
+		})
+	}
+}
+
+func (r *Resolver) bindExpressions(exprs []ast.Expression, access symbols.Access, scope *symbols.Scope) {
+	for _, expr := range exprs {
+		r.bindExpression(expr, access, scope)
+	}
+}
+
+// bindExpression binds every identifier in expr as a data label. The variable an operand names is used as given
+// by access, the index of an array element, the pointer of a dereference and the names in any other expression are read.
+func (r *Resolver) bindExpression(expr ast.Expression, access symbols.Access, scope *symbols.Scope) {
+	switch e := expr.(type) {
+	case nil:
+	case *ast.Identifier:
+		r.bind(e, symbols.DataSpace, access, scope)
+	case *ast.IndexExpression:
+		r.bindExpression(e.Left, access, scope)
+		r.bindExpression(e.Index, symbols.Read, scope)
+	default:
+		ast.Inspect(expr, func(node ast.Node) bool {
+			if ident, ok := node.(*ast.Identifier); ok {
+				r.bind(ident, symbols.DataSpace, symbols.Read, scope)
+			}
+			return true
+		})
+	}
+}
+
+// bind looks up the name of ident in a label space and records the symbol it refers to and how it is used.
+// A name only declared in the other space or not at all is reported.
+func (r *Resolver) bind(ident *ast.Identifier, space symbols.Space, access symbols.Access, scope *symbols.Scope) {
+	if sym, ok := scope.Lookup(space, ident.Value); ok {
+		r.table.Uses[ident] = sym
+		r.table.Accesses[ident] = access
+		return
+	}
+	if r.env != nil {
+		if sym, ok := r.env.Lookup(ident.Value); ok && sym.Kind.Space() == space {
+			r.table.Uses[ident] = sym
+			r.table.Accesses[ident] = access
+			return
+		}
+	}
+	if other, ok := scope.Lookup(otherSpace(space), ident.Value); ok {
+		r.addError(ident.Token, plbErrors.ErrLabelSpace, fmt.Sprintf("%s is used as %s but declared as %s at %s",
+			ident.Value, withArticle(space), withArticle(other.Kind.Space()), at(other.Token)))
+		return
+	}
+	r.addError(ident.Token, plbErrors.ErrUndeclared, fmt.Sprintf("%s %s is not declared", space, ident.Value))
+}
+
+func isExecutionLabel(op parser.Operand) bool {
+	for _, class := range op {
+		if class == tokens.EXECUTIONLABEL {
+			return true
+		}
+	}
+	return false
+}
+
+func otherSpace(space symbols.Space) symbols.Space {
+	if space == symbols.DataSpace {
+		return symbols.ExecutionSpace
+	}
+	return symbols.DataSpace
+}
+
+func withArticle(space symbols.Space) string {
+	if space == symbols.ExecutionSpace {
+		return "an " + space.String()
+	}
+	return "a " + space.String()
+}
+
+// at formats the location of a token for messages referring to a second place in the source
+func at(tok tokens.Token) string {
+	return fmt.Sprintf("%s %d:%d", tok.FileName, tok.Line, tok.Col)
+}
@@ -0,0 +1,275 @@
+package resolver
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/parser"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/symbols"
+	"strings"
+	"testing"
+)
+
+// resolve parses and resolves the given input, parser errors fail the test
+func resolve(t *testing.T, input string, opts ...Option) (*ast.Program, *symbols.Table, []error) {
+	t.Helper()
+	prog, diag := parser.ParseReader(strings.NewReader(input), "test", parser.Options{})
+	if len(diag.Errors) > 0 {
+		t.Fatalf("parser errors: %v", diag.Errors)
+	}
+	r := New(opts...)
+	table := r.Resolve(prog)
+	_, errs := r.Errors()
+	return prog, table, errs
+}
+
+func TestResolver_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		codes []string
+		other string // a location of the earlier declaration the first message has to mention
+	}{
+		{
+			name:  "declared data and labels",
+			input: "A DIM 10\nB FORM 2\nTOP\n    MOVE A TO B\n    GOTO TOP IF EQUAL\n    CALL SUB USING A\nSUB ROUTINE A\n    RETURN\n",
+		},
+		{
+			name:  "reference before declaration",
+			input: "    GOTO DONE\n    MOVE \"X\" TO A\nA DIM 1\nDONE\n    STOP\n",
+		},
+		{
+			name:  "label on a line of its own names a declaration",
+			input: "A\n    DIM 10\n    MOVE \"X\" TO A\n",
+		},
+		{
+			name:  "undeclared data",
+			input: "    MOVE \"X\" TO A\n",
+			codes: []string{plbErrors.ErrUndeclared},
+		},
+		{
+			name:  "undeclared label",
+			input: "    GOTO NOWHERE\n    CALL NOBODY\n    TRAP NONE IF RANGE\n",
+			codes: []string{plbErrors.ErrUndeclared, plbErrors.ErrUndeclared, plbErrors.ErrUndeclared},
+		},
+		{
+			name:  "undeclared routine parameter",
+			input: "SUB ROUTINE P\n    RETURN\n",
+			codes: []string{plbErrors.ErrUndeclared},
+		},
+		{
+			name:  "duplicate data",
+			input: "A DIM 10\nA FORM 2\n",
+			codes: []string{plbErrors.ErrDuplicateDeclaration},
+			other: "test 1:1",
+		},
+		{
+			name:  "duplicate label",
+			input: "TOP\n    STOP\nTOP STOP\n",
+			codes: []string{plbErrors.ErrDuplicateDeclaration},
+			other: "test 1:1",
+		},
+		{
+			name:  "duplicate LIST member",
+			input: "A DIM 10\nL LIST\nA DIM 5\n    LISTEND\n",
+			codes: []string{plbErrors.ErrDuplicateDeclaration},
+			other: "test 1:1",
+		},
+		{
+			name:  "ROUTINE data is global",
+			input: "A DIM 10\nSUB ROUTINE\nA DIM 5\n    RETURN\n",
+			codes: []string{plbErrors.ErrDuplicateDeclaration},
+			other: "test 1:1",
+		},
+		{
+			name:  "duplicate local",
+			input: "SUB LROUTINE\nA DIM 5\nA DIM 5\n    RETURN\n",
+			codes: []string{plbErrors.ErrDuplicateDeclaration},
+			other: "test 2:1",
+		},
+		{
+			name:  "duplicate file and pointer",
+			input: "F FILE\nF IFILE\nP DIM ^\nP FORM 2\n",
+			codes: []string{plbErrors.ErrDuplicateDeclaration, plbErrors.ErrDuplicateDeclaration},
+			other: "test 1:1",
+		},
+		{
+			name:  "data label conflicts with execution label",
+			input: "TOP\n    STOP\nTOP DIM 10\n",
+			codes: []string{plbErrors.ErrLabelSpace},
+			other: "test 1:1",
+		},
+		{
+			name:  "routine conflicts with data label",
+			input: "SUB DIM 10\nSUB ROUTINE\n    RETURN\n",
+			codes: []string{plbErrors.ErrLabelSpace},
+			other: "test 1:1",
+		},
+		{
+			name:  "execution label used as data",
+			input: "TOP\n    MOVE TOP TO TOP\n",
+			codes: []string{plbErrors.ErrLabelSpace, plbErrors.ErrLabelSpace},
+			other: "test 1:1",
+		},
+		{
+			name:  "data used as execution label",
+			input: "A DIM 10\n    GOTO A\n",
+			codes: []string{plbErrors.ErrLabelSpace},
+			other: "test 1:1",
+		},
+		{
+			name:  "LROUTINE locals are not global",
+			input: "    MOVE \"Y\" TO L\nSUB LROUTINE\nL DIM 10\n    MOVE \"X\" TO L\n    RETURN\n",
+			codes: []string{plbErrors.ErrUndeclared},
+		},
+		{
+			name:  "local shadows global",
+			input: "A DIM 10\nSUB LROUTINE\nA DIM 5\n    RETURN\n",
+		},
+		{
+			name:  "same local in two routines",
+			input: "ONE LROUTINE\nA DIM 5\n    RETURN\nTWO LROUTINE\nA FORM 2\n    RETURN\n",
+		},
+		{
+			name:  "labels inside an LROUTINE are global",
+			input: "    GOTO INNER\nSUB LROUTINE\nINNER STOP\n    RETURN\n",
+		},
+		{
+			name:  "identifiers in conditions and lists",
+			input: "A FORM 2\nP DIM ^\n    IF (A > B)\n    DISPLAY *P=A:A,P\n    ENDIF\n",
+			codes: []string{plbErrors.ErrUndeclared},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, errs := resolve(t, tt.input)
+			assertCodes(t, errs, tt.codes)
+			if tt.other != "" && !strings.Contains(errs[0].(*plbErrors.PLBError).Message, tt.other) {
+				t.Errorf("message %q does not mention %s", errs[0].(*plbErrors.PLBError).Message, tt.other)
+			}
+		})
+	}
+}
+
+func TestResolver_Scopes(t *testing.T) {
+	input := "G DIM 10\nL LIST\nM1 DIM 5\nM2 FORM 2\n    LISTEND\nTOP\n    GOTO TOP\nSUB LROUTINE G\nG FORM 3\n    MOVE M1 TO G\n    RETURN\n"
+	prog, table, errs := resolve(t, input)
+	if len(errs) > 0 {
+		t.Fatalf("errors: %v", errs)
+	}
+
+	global := table.Global
+	list, ok := global.LookupLocal(symbols.DataSpace, "l")
+	if !ok || list.Kind != symbols.List {
+		t.Fatalf("L is %v, want a global list", list)
+	}
+	listScope := table.Scopes[list.Node]
+	if listScope == nil || listScope.Kind != symbols.ListScope || listScope.Parent != global {
+		t.Fatalf("scope of the list is %+v, want a list scope in the global scope", listScope)
+	}
+	for _, name := range []string{"M1", "M2"} {
+		member, ok := global.Lookup(symbols.DataSpace, name)
+		if !ok || member.List != list || member.Scope != listScope {
+			t.Errorf("%s is %+v, want a member of L visible globally", name, member)
+		}
+		if _, ok := listScope.LookupLocal(symbols.DataSpace, name); !ok {
+			t.Errorf("%s is not in the list scope", name)
+		}
+	}
+
+	routine := prog.Statements[7].(*ast.RoutineStatement)
+	local := table.Scopes[routine]
+	if local == nil || local.Kind != symbols.LocalScope {
+		t.Fatalf("scope of the LROUTINE is %+v, want a local scope", local)
+	}
+	localG, _ := local.LookupLocal(symbols.DataSpace, "G")
+	globalG, _ := global.LookupLocal(symbols.DataSpace, "G")
+	if localG == nil || localG == globalG {
+		t.Fatalf("the local G %+v does not shadow the global one", localG)
+	}
+	if sym := table.Uses[routine.Params[0]]; sym != localG {
+		t.Errorf("the parameter is bound to %+v, want the local G", sym)
+	}
+	move := routine.Body.Statements[1].(*ast.VerbStatement)
+	if sym := table.Uses[move.Operands[0].(*ast.Identifier)]; sym == nil || sym.Name != "M1" {
+		t.Errorf("M1 is bound to %+v", sym)
+	}
+	if sym := table.Uses[move.Operands[1].(*ast.Identifier)]; sym != localG {
+		t.Errorf("G in the routine is bound to %+v, want the local G", sym)
+	}
+
+	if _, ok := global.LookupLocal(symbols.ExecutionSpace, "SUB"); !ok {
+		t.Error("SUB is not a global execution label")
+	}
+	gotoTop := prog.Statements[6].(*ast.VerbStatement)
+	top, _ := global.LookupLocal(symbols.ExecutionSpace, "TOP")
+	if sym := table.Uses[gotoTop.Operands[0].(*ast.Identifier)]; sym == nil || sym != top {
+		t.Errorf("TOP is bound to %+v, want %+v", sym, top)
+	}
+}
+
+func TestResolver_Environment(t *testing.T) {
+	env := symbols.NewEnvironment()
+	env.Define("LIMIT", "10")
+	_, _, errs := resolve(t, "A FORM 2\n    MOVE LIMIT TO A\n", WithEnvironment(env))
+	assertCodes(t, errs, nil)
+}
+
+// TestResolver_BindsEveryIdentifier resolves an example using every construct and checks that no identifier is left unbound
+func TestResolver_BindsEveryIdentifier(t *testing.T) {
+	prog, diag := parser.ParseFile("../examples/constructs.plb", parser.Options{})
+	if len(diag.Errors) > 0 {
+		t.Fatalf("parser errors: %v", diag.Errors)
+	}
+	r := New()
+	table := r.Resolve(prog)
+	if has, errs := r.Errors(); has {
+		t.Fatalf("errors: %v", errs)
+	}
+	ast.Inspect(prog, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			if _, ok := table.SymbolOf(ident); !ok {
+				t.Errorf("%s at %s is not bound", ident.Value, ident.Pos())
+			}
+		}
+		return true
+	})
+}
+
+func TestResolver_Accesses(t *testing.T) {
+	input := "A DIM 10\nN FORM 2\nI FORM 1\nP DIM ^\nTOP\n    MOVE A TO A\n    ADD N TO N\n    KEYIN A\n    DISPLAY A(I)\n" +
+		"    CLEAR A(I)\n    MOVEADR A TO P\n    MOVE P TO A\n    FOR I FROM N TO 3\n    REPEAT\n    CALL SUB USING A\n    GOTO TOP\nSUB ROUTINE A\n    RETURN\n"
+	prog, table, errs := resolve(t, input)
+	if len(errs) > 0 {
+		t.Fatalf("errors: %v", errs)
+	}
+	var got []string
+	ast.Inspect(prog, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			if _, ok := table.Uses[ident]; ok {
+				got = append(got, ident.Value+" "+table.Accesses[ident].String())
+			}
+		}
+		return true
+	})
+	want := []string{
+		"A read", "A write", "N read", "N update", "A write", "A read", "I read", "A write", "I read",
+		"A address", "P write", "P read", "A write", "I write", "N read", "SUB branch", "A address", "TOP branch", "A write",
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got accesses\n%v\nwant\n%v", got, want)
+	}
+}
+
+// assertCodes compares the codes of the given errors with the expected ones
+func assertCodes(t *testing.T, errs []error, codes []string) {
+	t.Helper()
+	if len(errs) != len(codes) {
+		t.Fatalf("got %d errors %v, want %d", len(errs), errs, len(codes))
+	}
+	for i, err := range errs {
+		if got := err.(*plbErrors.PLBError).ErrorCode; got != codes[i] {
+			t.Errorf("error %d: got %s (%s), want %s", i, got, err, codes[i])
+		}
+	}
+}
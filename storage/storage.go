@@ -0,0 +1,153 @@
+// Package storage lays out the variables of a program in its data area. Every DIM, INIT and FORM takes the next bytes
+// in the order the variables are declared, including those in included files and routines.
+// EQU and LIST declare no storage of their own, the members of a LIST follow each other like any other variables.
+package storage
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/tokens"
+	"strconv"
+	"strings"
+)
+
+// DimHeader is the number of bytes in front of the characters of a DIM or INIT:
+// the form pointer, the logical length and the physical length
+const DimHeader = 3
+
+// Item is a variable in the data area
+type Item struct {
+	Decl   *ast.DataDeclaration
+	Name   string // the name of the variable, empty if it has none
+	Offset int    // the first byte of the variable, counted from the start of the data area
+	Size   int    // the bytes taken by the variable, including its header
+}
+
+// Map is the layout of the data area of a program
+type Map struct {
+	Items []*Item // in the order of their offsets
+	Total int     // the size of the data area in bytes
+	items map[*ast.DataDeclaration]*Item
+}
+
+// Layout returns the data area of prog
+func Layout(prog *ast.Program) *Map {
+	m := &Map{items: map[*ast.DataDeclaration]*Item{}}
+	var label *ast.Identifier // a label on a line of its own, naming the next declaration
+	ast.Inspect(prog, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.LabelStatement:
+			label = n.Name
+			return false
+		case *ast.DataDeclaration:
+			name := n.Name
+			if name == nil {
+				name = label
+			}
+			if size, ok := Size(n); ok {
+				item := &Item{Decl: n, Offset: m.Total, Size: size}
+				if name != nil {
+					item.Name = name.Value
+				}
+				m.Items = append(m.Items, item)
+				m.items[n] = item
+				m.Total += size
+			}
+		}
+		if _, ok := node.(ast.Statement); ok {
+			label = nil
+		}
+		return true
+	})
+	return m
+}
+
+// Lookup returns the item of a declaration, false if it declares no storage
+func (m *Map) Lookup(decl *ast.DataDeclaration) (*Item, bool) {
+	item, ok := m.items[decl]
+	return item, ok
+}
+
+// Size returns the bytes taken by a variable in the data area, false for a declaration without storage.
+// A DIM or INIT takes its width and the header, a FORM its width.
+func Size(decl *ast.DataDeclaration) (int, bool) {
+	width, ok := Width(decl)
+	if !ok {
+		return 0, false
+	}
+	if decl.Kind == "FORM" {
+		return width, true
+	}
+	return DimHeader + width, true
+}
+
+// Width returns the characters of the value of a variable, the bytes it takes in a record, false for a declaration
+// without storage. A DIM n is n characters wide, an INIT as wide as its value.
+// A FORM takes one character for every digit, and one for the decimal point if it has decimals, see Digits.
+func Width(decl *ast.DataDeclaration) (int, bool) {
+	switch decl.Kind {
+	case "DIM":
+		if len(decl.Operands) == 0 {
+			return 0, false
+		}
+		n, err := strconv.Atoi(decl.Operands[0].String())
+		if err != nil {
+			return 0, false
+		}
+		return n, true
+	case "INIT":
+		n := 0
+		for _, op := range decl.Operands {
+			n += len(LiteralValue(op))
+		}
+		return n, true
+	case "FORM":
+		digits, decimals, ok := Digits(decl)
+		if !ok {
+			return 0, false
+		}
+		if decimals > 0 {
+			return digits + 1 + decimals, true
+		}
+		return digits, true
+	}
+	return 0, false
+}
+
+// Digits returns the digits before and after the decimal point of a FORM, given by its size, e.g. FORM 5.2,
+// or by its initial value. A minus sign in the initial value takes the place of a digit, so FORM "-10.5"
+// has 3 digits and 1 decimal like FORM 3.1.
+func Digits(decl *ast.DataDeclaration) (int, int, bool) {
+	if decl.Kind != "FORM" || len(decl.Operands) == 0 {
+		return 0, 0, false
+	}
+	size := LiteralValue(decl.Operands[0])
+	if lit, ok := decl.Operands[0].(*ast.NumberLiteral); ok && lit.Token.Type == tokens.NUMERICLITERAL {
+		if dot := strings.Index(size, "."); dot >= 0 {
+			return dot, len(size) - dot - 1, true
+		}
+		return len(size), 0, true
+	}
+	digits, decimals, _ := strings.Cut(size, ".")
+	d, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0, 0, false
+	}
+	m := 0
+	if decimals != "" {
+		if m, err = strconv.Atoi(decimals); err != nil {
+			return 0, 0, false
+		}
+	}
+	return d, m, true
+}
+
+// LiteralValue returns the unquoted value of a literal operand, e.g. the initial value of an INIT or FORM
+func LiteralValue(expr ast.Expression) string {
+	switch e := expr.(type) {
+	case *ast.StringLiteral:
+		return e.Value
+	case *ast.NumberLiteral:
+		return e.Value
+	}
+	return expr.String()
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"PLB-Interpreter/parser"
+	"fmt"
+	"testing"
+)
+
+func TestLayout(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string // NAME@OFFSET+SIZE in order
+		total int
+	}{
+		{"A DIM 10\n", []string{"A@0+13"}, 13},
+		{"N FORM 5\nM FORM 3.2\nP FORM \"-12.50\"\n", []string{"N@0+5", "M@5+6", "P@11+6"}, 17},
+		{"N FORM \"-10\"\nM FORM \"10\"\n", []string{"N@0+3", "M@3+2"}, 5},
+		{"Q INIT \"ABC\",\"DE\"\nR INIT \"\"\n", []string{"Q@0+8", "R@8+3"}, 11},
+		{"X EQU 5\nP DIM ^\nF FILE\nA DIM X\nB DIM 2\n", []string{"B@0+5"}, 5},
+		{"L LIST\nA DIM 2\nN FORM 2\n LISTEND\n", []string{"A@0+5", "N@5+2"}, 7},
+		{"NAME\n DIM 4\n DIM 1\n", []string{"NAME@0+7", "@7+4"}, 11},
+		{"A DIM 1\nS ROUTINE\nB FORM 1\n RETURN\nC FORM 2\n", []string{"A@0+4", "B@4+1", "C@5+2"}, 7},
+	}
+	for _, tt := range tests {
+		prog, diag := parser.ParseString(tt.input, parser.Options{})
+		if len(diag.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, diag.Errors)
+		}
+		m := Layout(prog)
+		var got []string
+		for _, item := range m.Items {
+			got = append(got, fmt.Sprintf("%s@%d+%d", item.Name, item.Offset, item.Size))
+			if found, ok := m.Lookup(item.Decl); !ok || found != item {
+				t.Errorf("%q: Lookup of %s = %v, %v", tt.input, item.Name, found, ok)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.input, got, tt.want)
+		}
+		if m.Total != tt.total {
+			t.Errorf("%q: total %d, want %d", tt.input, m.Total, tt.total)
+		}
+	}
+}
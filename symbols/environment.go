@@ -0,0 +1,117 @@
+package symbols
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/tokens"
+	"sort"
+	"strings"
+)
+
+// Kind tells what a symbol names
+type Kind int
+
+const (
+	Data    Kind = iota // a variable defined by DIM, FORM, INIT or similar
+	File                // a file variable defined by FILE, IFILE, AFILE or PFILE
+	Pointer             // a pointer variable defined by DIM ^, FORM ^ or VAR @
+	Routine             // a ROUTINE or LROUTINE entry point
+	Label               // any other execution label
+	Define              // a constant defined outside of the source, see Environment.Define
+	List                // a LIST or RECORD of data labels, its members are declared up to LISTEND or RECORDEND
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Data:
+		return "data"
+	case File:
+		return "file"
+	case Pointer:
+		return "pointer"
+	case Routine:
+		return "routine"
+	case Label:
+		return "label"
+	case Define:
+		return "define"
+	case List:
+		return "list"
+	}
+	return "unknown"
+}
+
+// Symbol is a name declared in one of the files sharing an Environment
+type Symbol struct {
+	Name  string       // the name as written at its declaration
+	Kind  Kind         // what the name refers to
+	Token tokens.Token // where the name is declared, empty for a define
+	Node  ast.Node     // the declaring node, nil for a define
+	Value string       // the value of a define
+	Scope *Scope       // the scope the name is declared in, nil for a symbol only known to an Environment
+	List  *Symbol      // the LIST a member belongs to, nil for any other symbol
+}
+
+// Verb returns the verb declaring the symbol, e.g. DIM, IFILE, DIM ^ or LROUTINE, LABEL for any other execution label.
+// A symbol without a declaration is described by its kind, e.g. define.
+func (s *Symbol) Verb() string {
+	switch decl := s.Node.(type) {
+	case *ast.DataDeclaration:
+		return decl.Kind
+	case *ast.FileDeclaration:
+		return decl.Kind
+	case *ast.PointerDeclaration:
+		return decl.Kind + " " + decl.Marker()
+	case *ast.RoutineStatement:
+		return decl.Kind
+	}
+	if s.Kind == Label {
+		return "LABEL"
+	}
+	return s.Kind.String()
+}
+
+// Environment collects the global symbols of every file parsed with it, so a file can refer to
+// names declared in another one, e.g. a pointer declared in an included file.
+// Names are case-insensitive.
+type Environment struct {
+	symbols map[string]*Symbol
+}
+
+// NewEnvironment creates an empty environment
+func NewEnvironment() *Environment {
+	return &Environment{symbols: map[string]*Symbol{}}
+}
+
+// Define adds a constant that is not declared in any source file, e.g. given on the command line
+func (e *Environment) Define(name, value string) {
+	e.symbols[strings.ToUpper(name)] = &Symbol{Name: name, Kind: Define, Value: value}
+}
+
+// Declare adds sym to the environment.
+// If the name is already declared, the earlier symbol is kept and returned with false.
+func (e *Environment) Declare(sym *Symbol) (*Symbol, bool) {
+	key := strings.ToUpper(sym.Name)
+	if prev, ok := e.symbols[key]; ok {
+		return prev, false
+	}
+	e.symbols[key] = sym
+	return sym, true
+}
+
+// Lookup returns the symbol with the given name
+func (e *Environment) Lookup(name string) (*Symbol, bool) {
+	sym, ok := e.symbols[strings.ToUpper(name)]
+	return sym, ok
+}
+
+// Symbols returns every symbol of the environment sorted by name
+func (e *Environment) Symbols() []*Symbol {
+	syms := make([]*Symbol, 0, len(e.symbols))
+	for _, sym := range e.symbols {
+		syms = append(syms, sym)
+	}
+	sort.Slice(syms, func(i, j int) bool {
+		return strings.ToUpper(syms[i].Name) < strings.ToUpper(syms[j].Name)
+	})
+	return syms
+}
@@ -0,0 +1,185 @@
+package symbols
+
+import (
+	"PLB-Interpreter/ast"
+	"sort"
+	"strings"
+)
+
+// Space is the label space a name is declared in. A data label and an execution label
+// are looked up in different spaces, e.g. MOVE A TO B refers to data and GOTO A to an execution label.
+type Space int
+
+const (
+	DataSpace      Space = iota // data, file and pointer variables, LISTs and defines
+	ExecutionSpace              // routines and execution labels
+)
+
+func (s Space) String() string {
+	if s == ExecutionSpace {
+		return "execution label"
+	}
+	return "data label"
+}
+
+// Space returns the label space of a kind of symbol
+func (k Kind) Space() Space {
+	switch k {
+	case Routine, Label:
+		return ExecutionSpace
+	}
+	return DataSpace
+}
+
+// ScopeKind tells what opened a scope
+type ScopeKind int
+
+const (
+	GlobalScope ScopeKind = iota // the program and every file it includes
+	ListScope                    // the members of a LIST, up to its LISTEND
+	LocalScope                   // the data declared in an LROUTINE
+)
+
+func (k ScopeKind) String() string {
+	switch k {
+	case GlobalScope:
+		return "global"
+	case ListScope:
+		return "list"
+	case LocalScope:
+		return "local"
+	}
+	return "unknown"
+}
+
+// Scope holds the names declared at one level of a program, separately for each label space.
+// The program forms the global scope, a LIST or an LROUTINE opens a scope enclosed by the one it is declared in.
+// Names are case-insensitive.
+type Scope struct {
+	Kind   ScopeKind
+	Parent *Scope   // the enclosing scope, nil for the global scope
+	Node   ast.Node // the LIST declaration or LROUTINE opening the scope, nil for the global scope
+	spaces [2]map[string]*Symbol
+}
+
+// NewScope creates an empty scope enclosed by parent
+func NewScope(kind ScopeKind, parent *Scope, node ast.Node) *Scope {
+	return &Scope{Kind: kind, Parent: parent, Node: node, spaces: [2]map[string]*Symbol{{}, {}}}
+}
+
+// Insert adds sym to the label space of its kind. The first Insert sets the scope of sym,
+// so a LIST member inserted into the enclosing scope as well keeps the list scope.
+// If the name is already declared in that space of this scope, the earlier symbol is kept and returned with false.
+// Declarations in enclosing scopes are shadowed, not conflicting.
+func (s *Scope) Insert(sym *Symbol) (*Symbol, bool) {
+	names := s.spaces[sym.Kind.Space()]
+	key := strings.ToUpper(sym.Name)
+	if prev, ok := names[key]; ok {
+		return prev, false
+	}
+	names[key] = sym
+	if sym.Scope == nil {
+		sym.Scope = s
+	}
+	return sym, true
+}
+
+// LookupLocal returns the symbol declared with the given name in a label space of this scope only
+func (s *Scope) LookupLocal(space Space, name string) (*Symbol, bool) {
+	sym, ok := s.spaces[space][strings.ToUpper(name)]
+	return sym, ok
+}
+
+// Lookup finds the symbol with the given name in a label space of this scope or the enclosing ones
+func (s *Scope) Lookup(space Space, name string) (*Symbol, bool) {
+	for scope := s; scope != nil; scope = scope.Parent {
+		if sym, ok := scope.LookupLocal(space, name); ok {
+			return sym, true
+		}
+	}
+	return nil, false
+}
+
+// Symbols returns the symbols of both label spaces of this scope sorted by name, data labels first for equal names
+func (s *Scope) Symbols() []*Symbol {
+	var syms []*Symbol
+	for _, names := range s.spaces {
+		for _, sym := range names {
+			syms = append(syms, sym)
+		}
+	}
+	sort.SliceStable(syms, func(i, j int) bool {
+		a, b := strings.ToUpper(syms[i].Name), strings.ToUpper(syms[j].Name)
+		if a != b {
+			return a < b
+		}
+		return syms[i].Kind.Space() < syms[j].Kind.Space()
+	})
+	return syms
+}
+
+// Access tells how a statement uses the symbol an identifier refers to
+type Access int
+
+const (
+	Read    Access = iota
+	Write          // assigned without being read first, e.g. the destination of MOVE or a variable of KEYIN
+	Update         // read and assigned, e.g. the destination of ADD
+	Address        // passed by reference, e.g. a CALL argument, so it may be read and assigned elsewhere
+	Branch         // the target of a GOTO, BRANCH, TRAP or CALL
+)
+
+func (a Access) String() string {
+	switch a {
+	case Read:
+		return "read"
+	case Write:
+		return "write"
+	case Update:
+		return "update"
+	case Address:
+		return "address"
+	case Branch:
+		return "branch"
+	}
+	return "unknown"
+}
+
+// Reads returns true if the value of the symbol may be read, i.e. for Read, Update and Address
+func (a Access) Reads() bool {
+	return a == Read || a == Update || a == Address
+}
+
+// Writes returns true if the symbol may be assigned, i.e. for Write, Update and Address
+func (a Access) Writes() bool {
+	return a == Write || a == Update || a == Address
+}
+
+// Table is the result of resolving a program: its scopes and the symbol every identifier is bound to
+type Table struct {
+	Global   *Scope
+	Scopes   map[ast.Node]*Scope         // the scopes opened by LIST declarations and LROUTINEs
+	Defs     map[*ast.Identifier]*Symbol // identifiers declaring a symbol
+	Uses     map[*ast.Identifier]*Symbol // identifiers referring to a symbol
+	Accesses map[*ast.Identifier]Access  // how the identifiers in Uses are used
+}
+
+// NewTable creates a table with an empty global scope
+func NewTable() *Table {
+	return &Table{
+		Global:   NewScope(GlobalScope, nil, nil),
+		Scopes:   map[ast.Node]*Scope{},
+		Defs:     map[*ast.Identifier]*Symbol{},
+		Uses:     map[*ast.Identifier]*Symbol{},
+		Accesses: map[*ast.Identifier]Access{},
+	}
+}
+
+// SymbolOf returns the symbol an identifier declares or refers to
+func (t *Table) SymbolOf(ident *ast.Identifier) (*Symbol, bool) {
+	if sym, ok := t.Defs[ident]; ok {
+		return sym, true
+	}
+	sym, ok := t.Uses[ident]
+	return sym, ok
+}
@@ -24,6 +24,7 @@ const (
 	FORCING     = "FORCING"     // # or £
 	COMMA       = "COMMA"       // , or :
 	SEMICOLON   = "SEMICOLON"   // ;
+	EXCLAMATION = "EXCLAMATION" // !
 	PREP        = "PREP"        // either COMMA or a PREPOSITION enclosed by WHITESPACE
 	PREPOSITION = "PREPOSITION" // a word that is a preposition (see prepList)
 
@@ -31,6 +32,10 @@ const (
 	LPAREN = "LPAREN" // (
 	RPAREN = "RPAREN" // )
 
+	// Pointers
+	CARET = "CARET" // ^, declares a pointer to a DIM or FORM
+	AT    = "AT"    // @, declares a pointer to a variable of any type
+
 	// Arithmetic Operators
 	PLUS     = "PLUS"     // +
 	MINUS    = "MINUS"    // -
@@ -82,6 +87,7 @@ const (
 	CVAR       = "CVAR"       // character variable, is a VARLABEL, can have an array reference
 	SIMPLENVAR = "SIMPLENVAR" // numeric variable, is a VARLABEL
 	NVAR       = "NVAR"       // numeric variable, is a VARLABEL, can have an array reference
+	POINTERVAR = "POINTERVAR" // pointer variable itself rather than the variable it points to, is a VARLABEL
 
 	// Array Variables
 	VARARRAY  = "VARARRAY"  // either CVARARRAY or NVARARRAY
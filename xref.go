@@ -0,0 +1,51 @@
+package main
+
+import (
+	"PLB-Interpreter/parser"
+	"PLB-Interpreter/plbErrors"
+	"PLB-Interpreter/resolver"
+	"PLB-Interpreter/xref"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// xrefCommand prints the cross-reference of a file and the files it includes, or its JSON encoding with --json.
+// The report is printed even if there are errors, names that cannot be resolved are left out.
+func xrefCommand(args []string) int {
+	flags := flag.NewFlagSet("xref", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the cross-reference as JSON instead of a listing")
+	dialect := flags.String("dialect", "", "PL/B dialect, SUNBELT or ANSI")
+	defs := defineFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	opts, env := resolveOptions(*dialect, defs)
+	prog, diag := parser.ParseFile(sourcePath(flags.Args()), opts)
+	for _, err := range diag.Errors {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if unreadable(diag) {
+		return 1
+	}
+	var resolveErrors plbErrors.Collector
+	table := resolver.New(resolver.WithEnvironment(env), resolver.WithDiagnostics(&resolveErrors)).Resolve(prog)
+	for _, err := range resolveErrors.Errors {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	report := xref.Build(prog, table)
+	write := report.WriteText
+	if *asJSON {
+		write = report.WriteJSON
+	}
+	if err := write(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(diag.Errors) > 0 || len(resolveErrors.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,225 @@
+// Package xref builds the cross-reference of a program: every data and execution label with the place it is declared
+// and every place referring to it, including those in INCLUDEd files. Each reference tells how the label is used,
+// e.g. read or assigned by a statement or the target of a GOTO.
+package xref
+
+import (
+	"PLB-Interpreter/ast"
+	"PLB-Interpreter/symbols"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// JSONVersion is the version of the JSON encoding written by WriteJSON
+const JSONVersion = 1
+
+// Reference is a place referring to a label
+type Reference struct {
+	Pos    ast.Position
+	Access symbols.Access
+}
+
+// Entry is a label of the cross-reference
+type Entry struct {
+	Symbol     *symbols.Symbol
+	References []Reference // in source order
+}
+
+// Report is the cross-reference of a program
+type Report struct {
+	Files   []string // the file of the program followed by the files it includes, in the order they are included
+	Entries []*Entry // sorted by name, data labels first for equal names, then by the place of the declaration
+}
+
+// Build returns the cross-reference of prog resolved to table. Names declared outside of the program,
+// e.g. defines, are listed if the program refers to them.
+func Build(prog *ast.Program, table *symbols.Table) *Report {
+	r := &Report{}
+	order := map[string]int{}
+	ast.Inspect(prog, func(node ast.Node) bool {
+		if node == nil {
+			return false
+		}
+		if file := node.Pos().File; node.Pos().IsValid() {
+			if _, ok := order[file]; !ok {
+				order[file] = len(r.Files)
+				r.Files = append(r.Files, file)
+			}
+		}
+		return true
+	})
+
+	entries := map[*symbols.Symbol]*Entry{}
+	for _, sym := range table.Defs {
+		entries[sym] = &Entry{Symbol: sym}
+	}
+	for ident, sym := range table.Uses {
+		entry, ok := entries[sym]
+		if !ok {
+			entry = &Entry{Symbol: sym}
+			entries[sym] = entry
+		}
+		entry.References = append(entry.References, Reference{Pos: ident.Pos(), Access: table.Accesses[ident]})
+	}
+
+	before := func(a, b ast.Position) bool {
+		if a.File != b.File {
+			return order[a.File] < order[b.File]
+		}
+		return a.Before(b)
+	}
+	for _, entry := range entries {
+		refs := entry.References
+		sort.Slice(refs, func(i, j int) bool {
+			return before(refs[i].Pos, refs[j].Pos)
+		})
+		r.Entries = append(r.Entries, entry)
+	}
+	sort.Slice(r.Entries, func(i, j int) bool {
+		a, b := r.Entries[i].Symbol, r.Entries[j].Symbol
+		if x, y := strings.ToUpper(a.Name), strings.ToUpper(b.Name); x != y {
+			return x < y
+		}
+		if a.Kind.Space() != b.Kind.Space() {
+			return a.Kind.Space() < b.Kind.Space()
+		}
+		return before(declared(a), declared(b))
+	})
+	return r
+}
+
+// declared returns the position of the declaration of a symbol, not valid for a symbol without one
+func declared(sym *symbols.Symbol) ast.Position {
+	return ast.Position{File: sym.Token.FileName, Line: sym.Token.Line, Col: sym.Token.Col}
+}
+
+// marks are the letters the accesses of references are marked with in the text listing, a read is not marked
+var marks = map[symbols.Access]string{
+	symbols.Read:    "",
+	symbols.Write:   "W",
+	symbols.Update:  "M",
+	symbols.Address: "A",
+	symbols.Branch:  "B",
+}
+
+// refsPerLine is the number of references on a line of the text listing
+const refsPerLine = 8
+
+// WriteText writes the report as a listing with a line for every label, giving its name, its declaring verb,
+// the line of its declaration and its references. Lines in an included file are given with the name of the file,
+// relative to the directory of the program if it is in or below it.
+func (r *Report) WriteText(w io.Writer) error {
+	main := ""
+	if len(r.Files) > 0 {
+		main = r.Files[0]
+	}
+	line := func(pos ast.Position) string {
+		if !pos.IsValid() {
+			return "-"
+		}
+		if pos.File != main {
+			file := pos.File
+			if rel, err := filepath.Rel(filepath.Dir(main), file); err == nil && !strings.HasPrefix(rel, "..") {
+				file = rel
+			}
+			return fmt.Sprintf("%s:%d", file, pos.Line)
+		}
+		return fmt.Sprint(pos.Line)
+	}
+
+	rows := [][3]string{{"LABEL", "VERB", "DECLARED"}}
+	for _, entry := range r.Entries {
+		rows = append(rows, [3]string{entry.Symbol.Name, entry.Symbol.Verb(), line(declared(entry.Symbol))})
+	}
+	var widths [3]int
+	for _, row := range rows {
+		for i, col := range row {
+			if len(col) > widths[i] {
+				widths[i] = len(col)
+			}
+		}
+	}
+	indent := widths[0] + widths[1] + widths[2] + 6
+
+	out := bufio.NewWriter(w)
+	for i, row := range rows {
+		var text strings.Builder
+		fmt.Fprintf(&text, "%-*s  %-*s  %-*s  ", widths[0], row[0], widths[1], row[1], widths[2], row[2])
+		if i == 0 {
+			text.WriteString("REFERENCES")
+		} else {
+			for j, ref := range r.Entries[i-1].References {
+				switch {
+				case j == 0:
+				case j%refsPerLine == 0:
+					fmt.Fprintf(&text, "\n%*s", indent, "")
+				default:
+					text.WriteString(" ")
+				}
+				text.WriteString(line(ref.Pos) + marks[ref.Access])
+			}
+		}
+		fmt.Fprintln(out, strings.TrimRight(text.String(), " "))
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "W written, M read and written, A passed by reference, B branch target, other references are read")
+	return out.Flush()
+}
+
+// The JSON encoding of a report is a document
+//
+//	{"version": 1, "files": [...], "labels": [{"name": "COUNT", "space": "data label", "verb": "FORM",
+//	 "scope": "global", "declared": {...}, "references": [{"file": ..., "line": ..., "col": ..., "access": "read"}]}]}
+//
+// The scope is global, list or local. A member of a LIST has the name of the list in "list".
+// A label declared outside of the program has no scope and no declaration.
+type jsonReport struct {
+	Version int          `json:"version"`
+	Files   []string     `json:"files"`
+	Labels  []*jsonLabel `json:"labels"`
+}
+
+type jsonLabel struct {
+	Name       string           `json:"name"`
+	Space      string           `json:"space"`
+	Verb       string           `json:"verb"`
+	Scope      string           `json:"scope,omitempty"`
+	List       string           `json:"list,omitempty"`
+	Declared   *ast.Position    `json:"declared,omitempty"`
+	References []*jsonReference `json:"references"`
+}
+
+type jsonReference struct {
+	ast.Position
+	Access string `json:"access"`
+}
+
+// WriteJSON writes the report as an indented JSON document
+func (r *Report) WriteJSON(w io.Writer) error {
+	doc := jsonReport{Version: JSONVersion, Files: r.Files, Labels: []*jsonLabel{}}
+	for _, entry := range r.Entries {
+		sym := entry.Symbol
+		label := &jsonLabel{Name: sym.Name, Space: sym.Kind.Space().String(), Verb: sym.Verb(), References: []*jsonReference{}}
+		if sym.Scope != nil {
+			label.Scope = sym.Scope.Kind.String()
+		}
+		if sym.List != nil {
+			label.List = sym.List.Name
+		}
+		if pos := declared(sym); pos.IsValid() {
+			label.Declared = &pos
+		}
+		for _, ref := range entry.References {
+			label.References = append(label.References, &jsonReference{Position: ref.Pos, Access: ref.Access.String()})
+		}
+		doc.Labels = append(doc.Labels, label)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,155 @@
+package xref
+
+import (
+	"PLB-Interpreter/parser"
+	"PLB-Interpreter/resolver"
+	"PLB-Interpreter/symbols"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// build parses the files, written to a temporary directory, and returns the report of the first one.
+// Parser and resolver errors fail the test.
+func build(t *testing.T, files map[string]string, opts ...resolver.Option) (*Report, string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prog, diag := parser.ParseFile(filepath.Join(dir, "main.pls"), parser.Options{})
+	if len(diag.Errors) > 0 {
+		t.Fatalf("parser errors: %v", diag.Errors)
+	}
+	r := resolver.New(opts...)
+	table := r.Resolve(prog)
+	if _, errs := r.Errors(); len(errs) > 0 {
+		t.Fatalf("resolver errors: %v", errs)
+	}
+	return Build(prog, table), dir
+}
+
+func TestReport_WriteText(t *testing.T) {
+	report, _ := build(t, map[string]string{"main.pls": `N       FORM    2
+A       DIM     10
+UNUSED  INIT    "X"
+L       LIST
+B       DIM     5
+        LISTEND
+TOP
+        MOVE    "1" TO N
+        ADD     N TO N
+        KEYIN   A
+        DISPLAY A,N,L
+        CALL    SHOW USING A
+        GOTO    TOP IF EQUAL
+        STOP
+SHOW    ROUTINE A
+        RETURN
+`})
+	var out bytes.Buffer
+	if err := report.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `LABEL   VERB     DECLARED  REFERENCES
+A       DIM      2         10W 11 12A 15W
+B       DIM      5
+L       LIST     4         11
+N       FORM     1         8W 9 9M 11
+SHOW    ROUTINE  15        12B
+TOP     LABEL    7         13B
+UNUSED  INIT     3
+
+W written, M read and written, A passed by reference, B branch target, other references are read
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestReport_Includes(t *testing.T) {
+	report, dir := build(t, map[string]string{
+		"main.pls":   "    INCLUDE common.pls\n    MOVE \"X\" TO NAME\n    CALL SHOW\n    STOP\n",
+		"common.pls": "NAME DIM 10\nSHOW ROUTINE\n    DISPLAY NAME\n    RETURN\n",
+	})
+	common := filepath.Join(dir, "common.pls")
+	if len(report.Files) != 2 || report.Files[1] != common {
+		t.Errorf("got files %v, want main.pls and common.pls", report.Files)
+	}
+	var out bytes.Buffer
+	if err := report.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	want := `LABEL  VERB     DECLARED      REFERENCES
+NAME   DIM      common.pls:1  2W common.pls:3
+SHOW   ROUTINE  common.pls:2  3B
+`
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestReport_Order(t *testing.T) {
+	env := symbols.NewEnvironment()
+	env.Define("MAX", "10")
+	report, _ := build(t, map[string]string{
+		"main.pls": "X LIST\nB DIM 5\n    LISTEND\na DIM 1\nY\n    MOVE MAX TO B\n    GOTO Y\n    CALL LOC\n    STOP\nLOC LROUTINE\nB DIM 2\n    DISPLAY B,a,X\n    RETURN\n",
+	}, resolver.WithEnvironment(env))
+	var got []string
+	for _, entry := range report.Entries {
+		got = append(got, entry.Symbol.Name+" "+entry.Symbol.Verb()+" "+entry.Symbol.Kind.Space().String())
+	}
+	want := []string{
+		"a DIM data label", "B DIM data label", "B DIM data label", "LOC LROUTINE execution label",
+		"MAX define data label", "X LIST data label", "Y LABEL execution label",
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got entries %v, want %v", got, want)
+	}
+	if refs := report.Entries[1].References; len(refs) != 1 || refs[0].Pos.Line != 6 {
+		t.Errorf("the global B has references %v, want line 6", refs)
+	}
+	if refs := report.Entries[2].References; len(refs) != 1 || refs[0].Pos.Line != 12 {
+		t.Errorf("the local B has references %v, want line 12", refs)
+	}
+}
+
+func TestReport_WriteJSON(t *testing.T) {
+	report, _ := build(t, map[string]string{
+		"main.pls": "L LIST\nN FORM 2\n    LISTEND\n    ADD \"1\" TO N\n    DISPLAY L\n",
+	})
+	var out bytes.Buffer
+	if err := report.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Version int
+		Labels  []struct {
+			Name, Space, Verb, Scope, List string
+			Declared                       *struct{ Line, Col int }
+			References                     []struct {
+				Line, Col int
+				Access    string
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != JSONVersion || len(doc.Labels) != 2 {
+		t.Fatalf("got version %d with %d labels, want %d with 2:\n%s", doc.Version, len(doc.Labels), JSONVersion, out.String())
+	}
+	n := doc.Labels[1]
+	if n.Name != "N" || n.Space != "data label" || n.Verb != "FORM" || n.Scope != "list" || n.List != "L" {
+		t.Errorf("got %+v, want N, a data label declared by FORM as a member of L", n)
+	}
+	if n.Declared == nil || n.Declared.Line != 2 || len(n.References) != 1 || n.References[0].Access != "update" {
+		t.Errorf("got declaration %v and references %+v, want line 2 and one update", n.Declared, n.References)
+	}
+}